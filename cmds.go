@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 
@@ -29,7 +30,11 @@ var (
 				err = errParameter
 				return
 			}
-			gid, err := rpcc.AddTorrent(s[0])
+			data, err := ioutil.ReadFile(s[0])
+			if err != nil {
+				return
+			}
+			gid, err := rpcc.AddTorrentData(data, s[1:])
 			if err != nil {
 				return
 			}
@@ -364,6 +369,7 @@ var (
 			renderCmdList(os.Stdout, msg...)
 			return
 		},
+		"exporter": runExporter,
 	}
 )
 
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zyxar/argo/rpc"
+)
+
+// exporterKeys are the keys of the downloads queried at every scrape; bitfield and peers are left out.
+var exporterKeys = []string{
+	"gid", "status", "totalLength", "completedLength", "uploadLength", "downloadSpeed", "uploadSpeed",
+	"connections", "numSeeders", "errorCode", "dir", "files", "bittorrent",
+}
+
+type daemonList []string
+
+func (d *daemonList) String() string     { return strings.Join(*d, ",") }
+func (d *daemonList) Set(s string) error { *d = append(*d, s); return nil }
+
+// runExporter serves the metrics of aria2 daemons at /metrics in the Prometheus text format,
+// collecting them from every daemon at every scrape.
+func runExporter(s ...string) error {
+	var daemons daemonList
+	fs := flag.NewFlagSet("exporter", flag.ContinueOnError)
+	listen := fs.String("listen", ":9578", "address to serve /metrics on")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout of collecting the metrics of a scrape")
+	limit := fs.Int("limit", 1000, "max number of waiting and stopped downloads queried per daemon")
+	fs.Var(&daemons, "daemon", "rpc address of aria2 daemon, as [SECRET@]URI; repeatable, -uri and -secret by default")
+	if err := fs.Parse(s); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errParameter
+	}
+	if len(daemons) == 0 {
+		daemons = daemonList{rpcURI}
+		if rpcSecret != "" {
+			daemons[0] = rpcSecret + "@" + rpcURI
+		}
+	}
+	e := newExporter(*timeout, *limit)
+	for _, d := range daemons {
+		uri, secret := parseDaemon(d)
+		if err := e.addDaemon(uri, secret); err != nil {
+			e.Close()
+			return err
+		}
+	}
+	defer e.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	fmt.Printf("serving metrics of %d daemon(s) at %s/metrics\n", len(daemons), *listen)
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: exporterHeaderTimeout,
+		WriteTimeout:      *timeout + exporterHeaderTimeout, // a scrape lasts up to timeout, then its metrics are written
+	}
+	return srv.ListenAndServe()
+}
+
+// exporterHeaderTimeout bounds the time a scraper may take to send its request headers, and to read the metrics.
+const exporterHeaderTimeout = 5 * time.Second
+
+// parseDaemon splits "SECRET@URI" into its uri and secret; an "@" after the scheme belongs to the uri.
+func parseDaemon(s string) (uri, secret string) {
+	if i := strings.Index(s, "@"); i >= 0 && i < strings.Index(s, "://") {
+		return s[i+1:], s[:i]
+	}
+	return s, ""
+}
+
+// exporter is the http.Handler of /metrics.
+type exporter struct {
+	timeout time.Duration
+	limit   int
+	daemons []*daemon
+}
+
+type daemon struct {
+	uri    string
+	client rpc.Client
+	calls  *callStats
+}
+
+func newExporter(timeout time.Duration, limit int) *exporter {
+	return &exporter{timeout: timeout, limit: limit}
+}
+
+func (e *exporter) addDaemon(uri, secret string) error {
+	calls := &callStats{methods: map[string]*callStat{}}
+	client, err := rpc.NewWithOptions(context.Background(), uri,
+		rpc.WithToken(secret), rpc.WithTimeout(e.timeout), rpc.WithLogger(calls))
+	if err != nil {
+		return fmt.Errorf("%s: %w", uri, err)
+	}
+	e.daemons = append(e.daemons, &daemon{uri: uri, client: client, calls: calls})
+	return nil
+}
+
+func (e *exporter) Close() {
+	for _, d := range e.daemons {
+		d.client.Close()
+	}
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), e.timeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	e.collect(ctx).write(bw)
+	bw.Flush()
+}
+
+// collect scrapes every daemon concurrently.
+func (e *exporter) collect(ctx context.Context) *metrics {
+	scrapes := make([]scrape, len(e.daemons))
+	var wg sync.WaitGroup
+	for i, d := range e.daemons {
+		wg.Add(1)
+		go func(i int, d *daemon) {
+			defer wg.Done()
+			scrapes[i] = d.scrape(ctx, e.limit)
+		}(i, d)
+	}
+	wg.Wait()
+	m := &metrics{families: map[string]*family{}}
+	for i, d := range e.daemons {
+		scrapes[i].add(m, d.uri)
+		d.calls.add(m, d.uri)
+	}
+	return m
+}
+
+// scrape is what is collected from a daemon at a scrape.
+type scrape struct {
+	err       error
+	duration  time.Duration
+	stat      rpc.GlobalStat
+	version   string
+	downloads []rpc.DownloadStatus
+}
+
+// scrape collects the metrics of d in a single batch.
+func (d *daemon) scrape(ctx context.Context, limit int) (s scrape) {
+	start := time.Now()
+	defer func() { s.duration = time.Since(start) }()
+	b := d.client.Batch()
+	stat := b.GetGlobalStat()
+	version := b.GetVersion()
+	queues := []*rpc.StatusesCall{
+		b.TellActive(exporterKeys...),
+		b.TellWaiting(0, limit, exporterKeys...),
+		b.TellStopped(0, limit, exporterKeys...),
+	}
+	if s.err = b.Do(ctx); s.err != nil {
+		return
+	}
+	if s.err = firstError(stat.Err, version.Err, queues[0].Err, queues[1].Err, queues[2].Err); s.err != nil {
+		return
+	}
+	if s.stat, s.err = stat.Result.Typed(); s.err != nil {
+		return
+	}
+	s.version = version.Result.Version
+	for _, q := range queues {
+		for _, info := range q.Result {
+			status, err := info.Typed()
+			if err != nil {
+				s.err = err
+				return
+			}
+			s.downloads = append(s.downloads, status)
+		}
+	}
+	return
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scrape) add(m *metrics, uri string) {
+	m.add("aria2_scrape_duration_seconds", "gauge", "Time taken to collect the metrics of aria2 daemon.",
+		s.duration.Seconds(), "daemon", uri)
+	if s.err != nil {
+		m.add("aria2_up", "gauge", "Whether the metrics of aria2 daemon were collected.", 0, "daemon", uri)
+		return
+	}
+	m.add("aria2_up", "gauge", "Whether the metrics of aria2 daemon were collected.", 1, "daemon", uri)
+	m.add("aria2_version_info", "gauge", "Version of aria2 daemon.", 1, "daemon", uri, "version", s.version)
+	m.add("aria2_download_speed_bytes", "gauge", "Overall download speed in bytes per second.",
+		float64(s.stat.DownloadSpeed), "daemon", uri)
+	m.add("aria2_upload_speed_bytes", "gauge", "Overall upload speed in bytes per second.",
+		float64(s.stat.UploadSpeed), "daemon", uri)
+	for _, q := range []struct {
+		queue string
+		n     int
+	}{{"active", s.stat.NumActive}, {"waiting", s.stat.NumWaiting}, {"stopped", s.stat.NumStopped}} {
+		m.add("aria2_queue_downloads", "gauge", "Number of downloads in a queue of aria2 daemon.",
+			float64(q.n), "daemon", uri, "queue", q.queue)
+	}
+	m.add("aria2_stopped_downloads_total", "counter", "Number of stopped downloads of the session, not capped by --max-download-result.",
+		float64(s.stat.NumStoppedTotal), "daemon", uri)
+
+	failed := map[rpc.DownloadErrorCode]int{}
+	for _, d := range s.downloads {
+		// status is a gauge of its own, so that the series of a download go on as it changes
+		m.add("aria2_download_status", "gauge", "Status of a download, e.g. active, waiting, paused, error, complete or removed.",
+			1, "daemon", uri, "gid", d.Gid, "status", d.Status.String())
+		labels := []string{"daemon", uri, "gid", d.Gid, "name", d.Name()}
+		m.add("aria2_download_total_bytes", "gauge", "Total length of a download in bytes.", float64(d.TotalLength), labels...)
+		m.add("aria2_download_completed_bytes", "gauge", "Completed length of a download in bytes.", float64(d.CompletedLength), labels...)
+		m.add("aria2_download_uploaded_bytes", "gauge", "Uploaded length of a download in bytes.", float64(d.UploadLength), labels...)
+		m.add("aria2_download_download_speed_bytes", "gauge", "Download speed of a download in bytes per second.", float64(d.DownloadSpeed), labels...)
+		m.add("aria2_download_upload_speed_bytes", "gauge", "Upload speed of a download in bytes per second.", float64(d.UploadSpeed), labels...)
+		m.add("aria2_download_connections", "gauge", "Number of peers/servers a download is connected to.", float64(d.Connections), labels...)
+		if d.BitTorrent != nil {
+			m.add("aria2_download_seeders", "gauge", "Number of seeders a BitTorrent download is connected to.", float64(d.NumSeeders), labels...)
+		}
+		if d.Status == rpc.StatusError {
+			failed[d.ErrorCode]++
+		}
+	}
+	codes := make([]int, 0, len(failed))
+	for code := range failed {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		c := rpc.DownloadErrorCode(code)
+		m.add("aria2_download_errors", "gauge", "Number of stopped downloads in error, by errorCode.",
+			float64(failed[c]), "daemon", uri, "code", strconv.Itoa(code), "description", c.Description())
+	}
+}
+
+// callStats counts the calls of a client, as an rpc.Logger of the entries of its calls.
+type callStats struct {
+	mu      sync.Mutex
+	methods map[string]*callStat
+}
+
+type callStat struct {
+	count, errors int
+	latency       time.Duration
+}
+
+func (c *callStats) Log(level rpc.Level, msg string, fields ...rpc.Field) {
+	if msg != "call" && msg != "call failed" {
+		return
+	}
+	var method string
+	var latency time.Duration
+	for _, f := range fields {
+		switch f.Key {
+		case "method":
+			method, _ = f.Value.(string)
+		case "latency":
+			latency, _ = f.Value.(time.Duration)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stat, ok := c.methods[method]
+	if !ok {
+		stat = &callStat{}
+		c.methods[method] = stat
+	}
+	stat.count++
+	stat.latency += latency
+	if msg == "call failed" {
+		stat.errors++
+	}
+}
+
+func (c *callStats) add(m *metrics, uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	methods := make([]string, 0, len(c.methods))
+	for method := range c.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	const help = "Latency of the rpc calls to aria2 daemon."
+	for _, method := range methods {
+		stat := c.methods[method]
+		m.addSample("aria2_rpc_call_duration_seconds", "summary", help, "_sum", stat.latency.Seconds(), "daemon", uri, "method", method)
+		m.addSample("aria2_rpc_call_duration_seconds", "summary", help, "_count", float64(stat.count), "daemon", uri, "method", method)
+		m.add("aria2_rpc_call_errors_total", "counter", "Number of failed rpc calls to aria2 daemon.",
+			float64(stat.errors), "daemon", uri, "method", method)
+	}
+}
+
+// metrics are metric families in the order they are first added, their samples grouped as the text format requires.
+type metrics struct {
+	families map[string]*family
+	order    []string
+}
+
+type family struct {
+	name, typ, help string
+	samples         []sample
+}
+
+type sample struct {
+	suffix string
+	labels []string // name and value pairs
+	value  float64
+}
+
+func (m *metrics) add(name, typ, help string, value float64, labels ...string) {
+	m.addSample(name, typ, help, "", value, labels...)
+}
+
+func (m *metrics) addSample(name, typ, help, suffix string, value float64, labels ...string) {
+	f, ok := m.families[name]
+	if !ok {
+		f = &family{name: name, typ: typ, help: help}
+		m.families[name] = f
+		m.order = append(m.order, name)
+	}
+	f.samples = append(f.samples, sample{suffix: suffix, labels: labels, value: value})
+}
+
+// write writes m in the Prometheus text exposition format, version 0.0.4.
+func (m *metrics) write(w io.Writer) {
+	for _, name := range m.order {
+		f := m.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+		for _, s := range f.samples {
+			io.WriteString(w, f.name+s.suffix)
+			if len(s.labels) > 0 {
+				io.WriteString(w, "{")
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						io.WriteString(w, ",")
+					}
+					io.WriteString(w, s.labels[i]+`="`+escapeLabel(s.labels[i+1])+`"`)
+				}
+				io.WriteString(w, "}")
+			}
+			io.WriteString(w, " "+formatValue(s.value)+"\n")
+		}
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc"
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestExporter(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	e := newExporter(time.Second, 1000)
+	defer e.Close()
+	uri, secret := parseDaemon("secret@" + srv.URL)
+	if uri != srv.URL || secret != "secret" {
+		t.Fatalf("parseDaemon: %q %q", uri, secret)
+	}
+	if err := e.addDaemon(uri, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.addDaemon("http://127.0.0.1:1/jsonrpc", ""); err != nil { // down
+		t.Fatal(err)
+	}
+	gid, err := e.daemons[0].client.AddURI([]string{"http://example.com/a\"b.iso"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := e.daemons[0].client.AddURI([]string{"http://example.com/c.iso"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Fail(failed, int(rpc.ErrDiskFull), "disk full"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	b, _ := ioutil.ReadAll(w.Body)
+	text := string(b)
+	for _, line := range []string{
+		`aria2_up{daemon="` + srv.URL + `"} 1`,
+		`aria2_up{daemon="http://127.0.0.1:1/jsonrpc"} 0`,
+		`# TYPE aria2_queue_downloads gauge`,
+		`aria2_queue_downloads{daemon="` + srv.URL + `",queue="active"} 1`,
+		`aria2_download_status{daemon="` + srv.URL + `",gid="` + gid + `",status="active"} 1`,
+		`aria2_download_total_bytes{daemon="` + srv.URL + `",gid="` + gid + `",name="a\"b.iso"} 0`,
+		`aria2_download_errors{daemon="` + srv.URL + `",code="9",description="there was not enough disk space available"} 1`,
+		`aria2_rpc_call_duration_seconds_count{daemon="` + srv.URL + `",method="aria2.addUri"} 2`,
+		`aria2_rpc_call_errors_total{daemon="http://127.0.0.1:1/jsonrpc",method="system.multicall"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing %s in:\n%s", line, text)
+		}
+	}
+	if n := strings.Count(text, "# TYPE aria2_up "); n != 1 {
+		t.Errorf("aria2_up declared %d times", n)
+	}
+}
@@ -0,0 +1,120 @@
+// Package bencode decodes the bencoding of BitTorrent metainfo, for the rpc and torrent packages.
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrInvalid is wrapped by the errors of data which is not valid bencode.
+var ErrInvalid = errors.New("invalid bencode")
+
+// MaxDepth bounds the nesting of lists and dictionaries, against stack exhaustion by malicious data.
+const MaxDepth = 64
+
+// Decode decodes data, which must be a single bencoded value: integers as int64, strings as string,
+// lists as []interface{} and dictionaries as map[string]interface{}.
+// info is the raw bencoded value of the "info" key of a top-level dictionary, e.g. to compute the info hash; nil if absent.
+func Decode(data []byte) (v interface{}, info []byte, err error) {
+	d := &decoder{data: data}
+	if v, err = d.value(0); err != nil {
+		return nil, nil, err
+	}
+	if d.pos != len(data) {
+		return nil, nil, d.errorf("trailing data")
+	}
+	return v, d.info, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	info []byte
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrInvalid, d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.errorf("unexpected end of data")
+	}
+	if depth > MaxDepth {
+		return nil, d.errorf("nested too deep")
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		end := bytes.IndexByte(d.data[d.pos:], 'e')
+		if end < 0 {
+			return nil, d.errorf("unterminated integer")
+		}
+		s := string(d.data[d.pos+1 : d.pos+end])
+		if s == "" || s == "-0" || (len(s) > 1 && s[0] == '0') || (len(s) > 2 && s[:2] == "-0") {
+			return nil, d.errorf("invalid integer %q", s)
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, d.errorf("invalid integer %q", s)
+		}
+		d.pos += end + 1
+		return n, nil
+	case c >= '0' && c <= '9':
+		return d.string()
+	case c == 'l':
+		d.pos++
+		list := []interface{}{}
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated list")
+		}
+		d.pos++
+		return list, nil
+	case c == 'd':
+		d.pos++
+		dict := map[string]interface{}{}
+		for d.pos < len(d.data) && d.data[d.pos] != 'e' {
+			key, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			start := d.pos
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if key == "info" && depth == 0 {
+				d.info = d.data[start:d.pos]
+			}
+			dict[key] = v
+		}
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated dictionary")
+		}
+		d.pos++
+		return dict, nil
+	}
+	return nil, d.errorf("unexpected %q", d.data[d.pos])
+}
+
+func (d *decoder) string() (string, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", d.errorf("invalid string")
+	}
+	n, err := strconv.Atoi(string(d.data[d.pos : d.pos+colon]))
+	if err != nil || n < 0 || n > len(d.data)-d.pos-colon-1 {
+		return "", d.errorf("invalid string length")
+	}
+	start := d.pos + colon + 1
+	d.pos = start + n
+	return string(d.data[start:d.pos]), nil
+}
@@ -0,0 +1,31 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	v, info, err := Decode([]byte("d4:infod4:name1:ae4:listli-1e0:ee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"info": map[string]interface{}{"name": "a"}, "list": []interface{}{int64(-1), ""}}
+	if !reflect.DeepEqual(v, want) || string(info) != "d4:name1:ae" {
+		t.Errorf("Decode() = %v, %q", v, info)
+	}
+	if _, info, err = Decode([]byte("l4:infoe")); err != nil || info != nil {
+		t.Errorf("Decode() of a list = %q, %v", info, err)
+	}
+	nested := strings.Repeat("l", MaxDepth+1) + strings.Repeat("e", MaxDepth+1)
+	if _, _, err = Decode([]byte(nested)); err != nil {
+		t.Errorf("Decode() nested %d deep = %v", MaxDepth, err)
+	}
+	for _, data := range []string{"", "i01e", "i-0e", "ie", "i1", "1:", "-1:", "d", "l", "di1ei1ee", "i1ei2e", "x", "l" + nested + "e"} {
+		if _, _, err := Decode([]byte(data)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Decode(%q) = %v, want %v", data, err, ErrInvalid)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package magnet parses and builds BitTorrent magnet URIs of BEP 9, with the v2 info hash of BEP 52
+// and the select-only parameter of BEP 53, e.g. to show what a magnet link is before adding it to aria2 with AddURI.
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotMagnet is returned for URIs which are not magnet URIs.
+	ErrNotMagnet = errors.New("magnet: not a magnet uri")
+	// ErrNoInfoHash is returned for magnet URIs with neither a btih nor a btmh exact topic.
+	ErrNoInfoHash = errors.New("magnet: no info hash")
+	// ErrInvalid is returned for magnet URIs with an invalid parameter.
+	ErrInvalid = errors.New("magnet: invalid parameter")
+)
+
+const (
+	btih = "urn:btih:"
+	btmh = "urn:btmh:"
+	// sha256Multihash is the multihash prefix of a SHA-256 digest, as v2 info hashes are in btmh.
+	sha256Multihash = "1220"
+)
+
+// Magnet is a BitTorrent magnet URI.
+type Magnet struct {
+	InfoHash    string   // hex-encoded v1 info hash, of xt=urn:btih; "" for v2-only torrents
+	InfoHashV2  string   // hex-encoded v2 info hash, the SHA-256 of xt=urn:btmh; "" for v1-only torrents
+	DisplayName string   // dn
+	Length      int64    // xl, the total length in bytes; 0 if absent
+	Trackers    []string // tr
+	WebSeeds    []string // ws
+	SelectOnly  string   // so, the 0-based indexes and ranges of the files to download, e.g. "0,2,4-6"
+}
+
+// IsMagnet reports whether uri is a magnet URI, as aria2 tells it: by its scheme.
+func IsMagnet(uri string) bool {
+	return len(uri) >= 7 && strings.EqualFold(uri[:7], "magnet:")
+}
+
+var selectOnly = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// Parse parses a magnet URI; the info hash of btih may be hex or base32 encoded, and is returned hex-encoded.
+func Parse(uri string) (*Magnet, error) {
+	if !IsMagnet(uri) {
+		return nil, ErrNotMagnet
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	m := &Magnet{DisplayName: q.Get("dn"), Trackers: q["tr"], WebSeeds: q["ws"], SelectOnly: q.Get("so")}
+	for _, xt := range q["xt"] {
+		var err error
+		switch lower := strings.ToLower(xt); {
+		case strings.HasPrefix(lower, btih):
+			err = m.setInfoHash(xt[len(btih):])
+		case strings.HasPrefix(lower, btmh):
+			err = m.setInfoHashV2(xt[len(btmh):])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.InfoHash == "" && m.InfoHashV2 == "" {
+		return nil, ErrNoInfoHash
+	}
+	if xl := q.Get("xl"); xl != "" {
+		if m.Length, err = strconv.ParseInt(xl, 10, 64); err != nil || m.Length < 0 {
+			return nil, fmt.Errorf("%w: xl=%s", ErrInvalid, xl)
+		}
+	}
+	if m.SelectOnly != "" && !selectOnly.MatchString(m.SelectOnly) {
+		return nil, fmt.Errorf("%w: so=%s", ErrInvalid, m.SelectOnly)
+	}
+	return m, nil
+}
+
+func (m *Magnet) setInfoHash(h string) error {
+	var hash string
+	switch len(h) {
+	case 40:
+		if _, err := hex.DecodeString(h); err == nil {
+			hash = strings.ToLower(h)
+		}
+	case 32:
+		if b, err := base32.StdEncoding.DecodeString(strings.ToUpper(h)); err == nil {
+			hash = hex.EncodeToString(b)
+		}
+	}
+	if hash == "" || (m.InfoHash != "" && m.InfoHash != hash) {
+		return fmt.Errorf("%w: xt=%s%s", ErrInvalid, btih, h)
+	}
+	m.InfoHash = hash
+	return nil
+}
+
+func (m *Magnet) setInfoHashV2(h string) error {
+	hash := strings.ToLower(h)
+	if len(hash) != len(sha256Multihash)+64 || !strings.HasPrefix(hash, sha256Multihash) {
+		return fmt.Errorf("%w: xt=%s%s", ErrInvalid, btmh, h)
+	}
+	if _, err := hex.DecodeString(hash); err != nil || (m.InfoHashV2 != "" && m.InfoHashV2 != hash[len(sha256Multihash):]) {
+		return fmt.Errorf("%w: xt=%s%s", ErrInvalid, btmh, h)
+	}
+	m.InfoHashV2 = hash[len(sha256Multihash):]
+	return nil
+}
+
+// String returns m as a magnet URI, with the parameters set.
+func (m *Magnet) String() string {
+	var params []string
+	if m.InfoHash != "" {
+		params = append(params, "xt="+btih+m.InfoHash)
+	}
+	if m.InfoHashV2 != "" {
+		params = append(params, "xt="+btmh+sha256Multihash+m.InfoHashV2)
+	}
+	if m.DisplayName != "" {
+		params = append(params, "dn="+url.QueryEscape(m.DisplayName))
+	}
+	if m.Length > 0 {
+		params = append(params, "xl="+strconv.FormatInt(m.Length, 10))
+	}
+	for _, tr := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tr))
+	}
+	for _, ws := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(ws))
+	}
+	if m.SelectOnly != "" {
+		params = append(params, "so="+m.SelectOnly)
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// Validate reports whether m has an info hash, and valid parameters, as String would need to build a magnet URI
+// which Parse accepts.
+func (m *Magnet) Validate() error {
+	_, err := Parse(m.String())
+	return err
+}
+
+// SelectFile returns the select-file option of aria2 selecting the files of SelectOnly,
+// i.e. its indexes made 1-based as aria2 has them; "" if SelectOnly is.
+func (m *Magnet) SelectFile() string {
+	if m.SelectOnly == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i, part := range strings.Split(m.SelectOnly, ",") {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		for j, index := range strings.SplitN(part, "-", 2) {
+			if j > 0 {
+				b.WriteByte('-')
+			}
+			n, _ := strconv.Atoi(index)
+			b.WriteString(strconv.Itoa(n + 1))
+		}
+	}
+	return b.String()
+}
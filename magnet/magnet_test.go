@@ -0,0 +1,76 @@
+package magnet
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	hash   = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	hashV2 = "1f8a4ee3c3f23b6f4a8b4a7c2b0ac6c5e9e27a5a8cbbc8a4d1ad4b7e8b76c9d2"
+)
+
+func TestParse(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:" + strings.ToUpper(hash) + "&xt=urn:btmh:1220" + hashV2 +
+		"&dn=a+b%26c&xl=1024&tr=udp%3A%2F%2Ft1%3A80&tr=http://t2/announce&ws=http%3A%2F%2Fseed%2F&so=0,2,4-6"
+	m, err := Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Magnet{
+		InfoHash:    hash,
+		InfoHashV2:  hashV2,
+		DisplayName: "a b&c",
+		Length:      1024,
+		Trackers:    []string{"udp://t1:80", "http://t2/announce"},
+		WebSeeds:    []string{"http://seed/"},
+		SelectOnly:  "0,2,4-6",
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Parse() = %+v\nwant %+v", m, want)
+	}
+	if again, err := Parse(m.String()); err != nil || !reflect.DeepEqual(again, m) {
+		t.Errorf("Parse(String()) = %+v, %v\n%s", again, err, m.String())
+	}
+	if s := m.SelectFile(); s != "1,3,5-7" {
+		t.Errorf("SelectFile() = %q, want %q", s, "1,3,5-7")
+	}
+	// base32 of the same info hash
+	if m, err = Parse("magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK"); err != nil || m.InfoHash != hash {
+		t.Errorf("Parse(base32) = %+v, %v", m, err)
+	}
+	if m, err = Parse("magnet:?xt=urn:btmh:1220" + hashV2); err != nil || m.InfoHash != "" || m.InfoHashV2 != hashV2 {
+		t.Errorf("Parse(btmh) = %+v, %v", m, err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for uri, want := range map[string]error{
+		"http://example.org/a":                                        ErrNotMagnet,
+		"magnet:?dn=a":                                                ErrNoInfoHash,
+		"magnet:?xt=urn:sha1:" + hash:                                 ErrNoInfoHash,
+		"magnet:?xt=urn:btih:" + hash[:39]:                            ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash[:39] + "z":                      ErrInvalid,
+		"magnet:?xt=urn:btih:" + strings.Repeat("1", 32):              ErrInvalid,
+		"magnet:?xt=urn:btmh:1220" + hashV2[:62]:                      ErrInvalid,
+		"magnet:?xt=urn:btmh:1114" + hashV2[:40]:                      ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&xt=urn:btih:" + hashV2[:40]: ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&xl=-1":                      ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&xl=1k":                      ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&so=1,":                      ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&so=a-b":                     ErrInvalid,
+		"magnet:?xt=urn:btih:" + hash + "&dn=%zz":                     ErrInvalid,
+	} {
+		if _, err := Parse(uri); !errors.Is(err, want) {
+			t.Errorf("Parse(%s) = %v, want %v", uri, err, want)
+		}
+	}
+	if err := (&Magnet{DisplayName: "a"}).Validate(); err != ErrNoInfoHash {
+		t.Errorf("Validate() = %v, want %v", err, ErrNoInfoHash)
+	}
+	if err := (&Magnet{InfoHash: hash, SelectOnly: "1-"}).Validate(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Validate() = %v, want %v", err, ErrInvalid)
+	}
+}
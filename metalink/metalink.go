@@ -0,0 +1,200 @@
+// Package metalink parses Metalink documents, i.e. version 4 of RFC 5854 and the legacy version 3,
+// and generates them from mirrors and checksums, to be submitted to aria2 with AddMetalinkData or Add.
+package metalink
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zyxar/argo/rpc"
+)
+
+const (
+	// NamespaceV4 is the XML namespace of Metalink version 4.
+	NamespaceV4 = "urn:ietf:params:xml:ns:metalink"
+	// NamespaceV3 is the XML namespace of Metalink version 3.
+	NamespaceV3 = "http://www.metalinker.org/"
+)
+
+var (
+	// ErrNotMetalink is returned for XML documents which are not Metalink of version 3 or 4.
+	ErrNotMetalink = errors.New("metalink: not a metalink document")
+	// ErrNoFile is returned for metalinks without a file.
+	ErrNoFile = errors.New("metalink: no file")
+	// ErrUnsafeName is returned for files whose names are absolute or escape the download directory.
+	ErrUnsafeName = errors.New("metalink: unsafe file name")
+)
+
+// Metalink is a Metalink document.
+type Metalink struct {
+	Generator string
+	Origin    string    // URL of the document itself
+	Dynamic   bool      // whether the document should be fetched again from Origin for updates
+	Published time.Time // zero if absent
+	Files     []File
+}
+
+// File is a file of a metalink, with the resources to download it from.
+type File struct {
+	Name        string // relative path of the file, "/" separated
+	Size        int64  // bytes; 0 if unknown
+	Identity    string
+	Version     string
+	Description string
+	Language    []string
+	OS          []string
+	Hashes      []Hash  // of the whole file
+	Pieces      *Pieces // nil if absent
+	URLs        []URL
+	MetaURLs    []MetaURL
+}
+
+// Hash is a checksum; Type is named as in the IANA registry used by Metalink 4, e.g. "sha-256" or "md5".
+type Hash struct {
+	Type  string
+	Value string // hex-encoded
+}
+
+// Pieces are the checksums of consecutive pieces of a file.
+type Pieces struct {
+	Length int64  // bytes of a piece
+	Type   string // as Hash.Type
+	Hashes []string
+}
+
+// URL is a mirror of a file.
+type URL struct {
+	URL      string
+	Priority int    // 1 is the most preferred; 0 if unspecified
+	Location string // ISO 3166-1 alpha-2 country code of the mirror, e.g. "de"
+}
+
+// MetaURL is a metainfo describing a file, e.g. a ".torrent".
+type MetaURL struct {
+	URL       string
+	MediaType string // e.g. "torrent"
+	Priority  int
+	Name      string // name of the file in the metainfo, for multi-file ones
+}
+
+// NewFile returns a File of name and size downloaded from mirrors, the first one preferred, and checked by hashes,
+// e.g. NewFile("app.tar.gz", 1024, mirrors, Hash{"sha-256", sum}).
+func NewFile(name string, size int64, mirrors []string, hashes ...Hash) File {
+	f := File{Name: name, Size: size, Hashes: hashes}
+	for i, m := range mirrors {
+		f.URLs = append(f.URLs, URL{URL: m, Priority: i + 1})
+	}
+	return f
+}
+
+// Parse parses a Metalink document of version 4 or 3.
+func Parse(data []byte) (*Metalink, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "metalink" {
+		return nil, ErrNotMetalink
+	}
+	var m *Metalink
+	switch root.XMLName.Space {
+	case NamespaceV4:
+		var doc metalinkV4
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		m = doc.metalink()
+	case NamespaceV3:
+		var doc metalinkV3
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		m = doc.metalink()
+	default:
+		return nil, ErrNotMetalink
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Read parses the Metalink document read from r until EOF.
+func Read(r io.Reader) (*Metalink, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Load parses the Metalink document of filename, e.g. a ".meta4" or ".metalink" file.
+func Load(filename string) (*Metalink, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// check reports whether m has files, all of safe names, as RFC 5854 requires of both producers and consumers.
+func (m *Metalink) check() error {
+	if len(m.Files) == 0 {
+		return ErrNoFile
+	}
+	for _, f := range m.Files {
+		name := f.Name
+		if name == "" || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%w: %q", ErrUnsafeName, name)
+		}
+	}
+	return nil
+}
+
+// Marshal returns m as a Metalink version 4 document, i.e. a ".meta4" file.
+func (m *Metalink) Marshal() ([]byte, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return marshal(newMetalinkV4(m))
+}
+
+// MarshalV3 returns m as a Metalink version 3 document, i.e. a ".metalink" file, for older clients;
+// published and pieces are kept, identity, MetaURL names and dynamic are not.
+func (m *Metalink) MarshalV3() ([]byte, error) {
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return marshal(newMetalinkV3(m))
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	e := xml.NewEncoder(&buf)
+	e.Indent("", "  ")
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Add submits m to aria2 with c, as a Metalink version 4 document; options are those of AddMetalinkData.
+func (m *Metalink) Add(ctx context.Context, c rpc.ContextProtocol, options ...interface{}) (gids []string, err error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return
+	}
+	return c.AddMetalinkDataContext(ctx, data, options...)
+}
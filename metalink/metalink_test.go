@@ -0,0 +1,193 @@
+package metalink
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc"
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// v4 is the example of RFC 5854, with pieces.
+const v4 = `<?xml version="1.0" encoding="UTF-8"?>
+<metalink xmlns="urn:ietf:params:xml:ns:metalink">
+  <generator>MirrorBrain/2.15.0</generator>
+  <origin dynamic="true">http://example.com/example.ext.meta4</origin>
+  <published>2009-05-15T12:23:23Z</published>
+  <file name="example.ext">
+    <size>14471447</size>
+    <identity>Example</identity>
+    <version>1.0</version>
+    <language>en</language>
+    <description>A description of the example file for download.</description>
+    <hash type="sha-256">f0ad929cd259957e160ea442eb80986b5f01</hash>
+    <pieces length="262144" type="sha-1">
+      <hash>5a7c4d3b8f1a</hash>
+      <hash>7e8c1f2a9b3d</hash>
+    </pieces>
+    <url location="de" priority="1">ftp://ftp.example.com/example.ext</url>
+    <url location="fr" priority="1">http://example.com/example.ext</url>
+    <metaurl mediatype="torrent" priority="2">http://example.com/example.ext.torrent</metaurl>
+  </file>
+</metalink>`
+
+const v3 = `<?xml version="1.0" encoding="UTF-8"?>
+<metalink version="3.0" xmlns="http://www.metalinker.org/" generator="Metalink Editor" pubdate="Fri, 15 May 2009 12:23:23 +0000" type="static">
+  <files>
+    <file name="dir/example.ext">
+      <size>14471447</size>
+      <verification>
+        <hash type="md5">9b3bd1c4d3fb4d5dec8b6f5b8f04bcd0</hash>
+        <hash type="sha256">f0ad929cd259957e160ea442eb80986b5f01</hash>
+        <pieces length="262144" type="sha1">
+          <hash piece="1">7e8c1f2a9b3d</hash>
+          <hash piece="0">5a7c4d3b8f1a</hash>
+        </pieces>
+      </verification>
+      <resources>
+        <url type="ftp" location="de" preference="100">ftp://ftp.example.com/example.ext</url>
+        <url type="http" preference="90">http://example.com/example.ext</url>
+        <url type="bittorrent" preference="99">http://example.com/example.ext.torrent</url>
+      </resources>
+    </file>
+  </files>
+</metalink>`
+
+func TestParseV4(t *testing.T) {
+	m, err := Parse([]byte(v4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metalink{
+		Generator: "MirrorBrain/2.15.0",
+		Origin:    "http://example.com/example.ext.meta4",
+		Dynamic:   true,
+		Published: time.Date(2009, 5, 15, 12, 23, 23, 0, time.UTC),
+		Files: []File{{
+			Name:        "example.ext",
+			Size:        14471447,
+			Identity:    "Example",
+			Version:     "1.0",
+			Description: "A description of the example file for download.",
+			Language:    []string{"en"},
+			Hashes:      []Hash{{"sha-256", "f0ad929cd259957e160ea442eb80986b5f01"}},
+			Pieces:      &Pieces{Length: 262144, Type: "sha-1", Hashes: []string{"5a7c4d3b8f1a", "7e8c1f2a9b3d"}},
+			URLs: []URL{
+				{URL: "ftp://ftp.example.com/example.ext", Priority: 1, Location: "de"},
+				{URL: "http://example.com/example.ext", Priority: 1, Location: "fr"},
+			},
+			MetaURLs: []MetaURL{{URL: "http://example.com/example.ext.torrent", MediaType: "torrent", Priority: 2}},
+		}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Parse() = %+v\nwant %+v", m, want)
+	}
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := Parse(data); err != nil || !reflect.DeepEqual(again, m) {
+		t.Errorf("Parse(Marshal()) = %+v, %v\n%s", again, err, data)
+	}
+}
+
+func TestParseV3(t *testing.T) {
+	m, err := Parse([]byte(v3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metalink{
+		Generator: "Metalink Editor",
+		Published: time.Date(2009, 5, 15, 12, 23, 23, 0, time.FixedZone("", 0)),
+		Files: []File{{
+			Name:   "dir/example.ext",
+			Size:   14471447,
+			Hashes: []Hash{{"md5", "9b3bd1c4d3fb4d5dec8b6f5b8f04bcd0"}, {"sha-256", "f0ad929cd259957e160ea442eb80986b5f01"}},
+			Pieces: &Pieces{Length: 262144, Type: "sha-1", Hashes: []string{"5a7c4d3b8f1a", "7e8c1f2a9b3d"}},
+			URLs: []URL{
+				{URL: "ftp://ftp.example.com/example.ext", Priority: 1, Location: "de"},
+				{URL: "http://example.com/example.ext", Priority: 11},
+			},
+			MetaURLs: []MetaURL{{URL: "http://example.com/example.ext.torrent", MediaType: "torrent", Priority: 2}},
+		}},
+	}
+	if !m.Published.Equal(want.Published) {
+		t.Errorf("Published = %v, want %v", m.Published, want.Published)
+	}
+	m.Published = want.Published
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Parse() = %+v\nwant %+v", m, want)
+	}
+	data, err := m.MarshalV3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<hash type="sha256">`) || !strings.Contains(string(data), `type="bittorrent" preference="99"`) {
+		t.Errorf("MarshalV3() = %s", data)
+	}
+	again, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Published.Equal(m.Published) {
+		t.Errorf("Published = %v, want %v", again.Published, m.Published)
+	}
+	again.Published = m.Published
+	if !reflect.DeepEqual(again, m) {
+		t.Errorf("Parse(MarshalV3()) = %+v\n%s", again, data)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for data, want := range map[string]error{
+		`<metalink xmlns="urn:example"/>`:                                  ErrNotMetalink,
+		`<feed xmlns="urn:ietf:params:xml:ns:metalink"/>`:                  ErrNotMetalink,
+		`<metalink xmlns="urn:ietf:params:xml:ns:metalink"/>`:              ErrNoFile,
+		`<metalink xmlns="http://www.metalinker.org/"><files/></metalink>`: ErrNoFile,
+	} {
+		if _, err := Parse([]byte(data)); err != want {
+			t.Errorf("Parse(%s) = %v, want %v", data, err, want)
+		}
+	}
+	for _, name := range []string{"", "/etc/passwd", "../a", "a/../../b", "a/./b", ".."} {
+		data := `<metalink xmlns="urn:ietf:params:xml:ns:metalink"><file name="` + name + `"/></metalink>`
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrUnsafeName) {
+			t.Errorf("Parse(%q) = %v, want %v", name, err, ErrUnsafeName)
+		}
+		m := &Metalink{Files: []File{NewFile(name, 1, nil)}}
+		if _, err := m.Marshal(); !errors.Is(err, ErrUnsafeName) {
+			t.Errorf("Marshal(%q) = %v, want %v", name, err, ErrUnsafeName)
+		}
+	}
+	if _, err := Parse([]byte("<metalink")); err == nil {
+		t.Error("Parse() of malformed XML succeeded")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := rpc.NewWithOptions(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m := &Metalink{Generator: "argo", Files: []File{
+		NewFile("a.tar.gz", 1024, []string{"http://mirror1/a.tar.gz", "http://mirror2/a.tar.gz"}, Hash{"sha-256", strings.Repeat("0", 64)}),
+		NewFile("b.tar.gz", 2048, []string{"http://mirror1/b.tar.gz"}),
+	}}
+	if u := m.Files[0].URLs[1]; u.Priority != 2 {
+		t.Errorf("NewFile() = %+v", m.Files[0])
+	}
+	gids, err := m.Add(context.Background(), c, rpc.Option{}.Pause(true))
+	if err != nil || len(gids) != 2 {
+		t.Fatalf("Add() = %v, %v", gids, err)
+	}
+	if uris, err := c.GetURIs(gids[0]); err != nil || len(uris) != 2 || uris[0].URI != "http://mirror1/a.tar.gz" {
+		t.Errorf("GetURIs() = %v, %v", uris, err)
+	}
+}
@@ -0,0 +1,299 @@
+package metalink
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// metalinkV4 is the XML of RFC 5854.
+type metalinkV4 struct {
+	XMLName   xml.Name  `xml:"urn:ietf:params:xml:ns:metalink metalink"`
+	Generator string    `xml:"generator,omitempty"`
+	Origin    *originV4 `xml:"origin"`
+	Published string    `xml:"published,omitempty"`
+	Files     []fileV4  `xml:"file"`
+}
+
+type originV4 struct {
+	Dynamic bool   `xml:"dynamic,attr,omitempty"`
+	URL     string `xml:",chardata"`
+}
+
+type fileV4 struct {
+	Name        string      `xml:"name,attr"`
+	Size        int64       `xml:"size,omitempty"`
+	Identity    string      `xml:"identity,omitempty"`
+	Version     string      `xml:"version,omitempty"`
+	Description string      `xml:"description,omitempty"`
+	Language    []string    `xml:"language"`
+	OS          []string    `xml:"os"`
+	Hashes      []hashXML   `xml:"hash"`
+	Pieces      *piecesV4   `xml:"pieces"`
+	URLs        []urlV4     `xml:"url"`
+	MetaURLs    []metaURLV4 `xml:"metaurl"`
+}
+
+type hashXML struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type piecesV4 struct {
+	Length int64    `xml:"length,attr"`
+	Type   string   `xml:"type,attr"`
+	Hashes []string `xml:"hash"`
+}
+
+type urlV4 struct {
+	Location string `xml:"location,attr,omitempty"`
+	Priority int    `xml:"priority,attr,omitempty"`
+	URL      string `xml:",chardata"`
+}
+
+type metaURLV4 struct {
+	MediaType string `xml:"mediatype,attr"`
+	Priority  int    `xml:"priority,attr,omitempty"`
+	Name      string `xml:"name,attr,omitempty"`
+	URL       string `xml:",chardata"`
+}
+
+func newMetalinkV4(m *Metalink) *metalinkV4 {
+	doc := &metalinkV4{Generator: m.Generator}
+	if m.Origin != "" {
+		doc.Origin = &originV4{Dynamic: m.Dynamic, URL: m.Origin}
+	}
+	if !m.Published.IsZero() {
+		doc.Published = m.Published.UTC().Format(time.RFC3339)
+	}
+	for _, f := range m.Files {
+		x := fileV4{
+			Name:        f.Name,
+			Size:        f.Size,
+			Identity:    f.Identity,
+			Version:     f.Version,
+			Description: f.Description,
+			Language:    f.Language,
+			OS:          f.OS,
+		}
+		for _, h := range f.Hashes {
+			x.Hashes = append(x.Hashes, hashXML{Type: h.Type, Value: h.Value})
+		}
+		if f.Pieces != nil {
+			x.Pieces = &piecesV4{Length: f.Pieces.Length, Type: f.Pieces.Type, Hashes: f.Pieces.Hashes}
+		}
+		for _, u := range f.URLs {
+			x.URLs = append(x.URLs, urlV4{Location: u.Location, Priority: u.Priority, URL: u.URL})
+		}
+		for _, u := range f.MetaURLs {
+			x.MetaURLs = append(x.MetaURLs, metaURLV4{MediaType: u.MediaType, Priority: u.Priority, Name: u.Name, URL: u.URL})
+		}
+		doc.Files = append(doc.Files, x)
+	}
+	return doc
+}
+
+func (doc *metalinkV4) metalink() *Metalink {
+	m := &Metalink{Generator: strings.TrimSpace(doc.Generator)}
+	if doc.Origin != nil {
+		m.Origin, m.Dynamic = strings.TrimSpace(doc.Origin.URL), doc.Origin.Dynamic
+	}
+	m.Published, _ = time.Parse(time.RFC3339, strings.TrimSpace(doc.Published))
+	for _, x := range doc.Files {
+		f := File{
+			Name:        x.Name,
+			Size:        x.Size,
+			Identity:    strings.TrimSpace(x.Identity),
+			Version:     strings.TrimSpace(x.Version),
+			Description: strings.TrimSpace(x.Description),
+			Language:    x.Language,
+			OS:          x.OS,
+		}
+		for _, h := range x.Hashes {
+			f.Hashes = append(f.Hashes, Hash{Type: h.Type, Value: strings.TrimSpace(h.Value)})
+		}
+		if x.Pieces != nil {
+			f.Pieces = &Pieces{Length: x.Pieces.Length, Type: x.Pieces.Type, Hashes: trimAll(x.Pieces.Hashes)}
+		}
+		for _, u := range x.URLs {
+			f.URLs = append(f.URLs, URL{URL: strings.TrimSpace(u.URL), Priority: u.Priority, Location: u.Location})
+		}
+		for _, u := range x.MetaURLs {
+			f.MetaURLs = append(f.MetaURLs, MetaURL{URL: strings.TrimSpace(u.URL), MediaType: u.MediaType, Priority: u.Priority, Name: u.Name})
+		}
+		m.Files = append(m.Files, f)
+	}
+	return m
+}
+
+// metalinkV3 is the XML of Metalink 3.0.
+type metalinkV3 struct {
+	XMLName   xml.Name `xml:"http://www.metalinker.org/ metalink"`
+	Version   string   `xml:"version,attr"`
+	Generator string   `xml:"generator,attr,omitempty"`
+	Origin    string   `xml:"origin,attr,omitempty"`
+	Type      string   `xml:"type,attr,omitempty"` // "dynamic" or "static"
+	PubDate   string   `xml:"pubdate,attr,omitempty"`
+	Files     []fileV3 `xml:"files>file"`
+}
+
+type fileV3 struct {
+	Name        string    `xml:"name,attr"`
+	Size        int64     `xml:"size,omitempty"`
+	Version     string    `xml:"version,omitempty"`
+	Description string    `xml:"description,omitempty"`
+	Language    []string  `xml:"language"`
+	OS          []string  `xml:"os"`
+	Hashes      []hashXML `xml:"verification>hash"`
+	Pieces      *piecesV3 `xml:"verification>pieces"`
+	URLs        []urlV3   `xml:"resources>url"`
+}
+
+type piecesV3 struct {
+	Length int64         `xml:"length,attr"`
+	Type   string        `xml:"type,attr"`
+	Hashes []pieceHashV3 `xml:"hash"`
+}
+
+type pieceHashV3 struct {
+	Piece int    `xml:"piece,attr"`
+	Value string `xml:",chardata"`
+}
+
+type urlV3 struct {
+	Type       string `xml:"type,attr,omitempty"` // e.g. "http", "ftp" or "bittorrent"
+	Location   string `xml:"location,attr,omitempty"`
+	Preference int    `xml:"preference,attr,omitempty"` // 1 to 100, 100 being the most preferred
+	URL        string `xml:",chardata"`
+}
+
+func newMetalinkV3(m *Metalink) *metalinkV3 {
+	doc := &metalinkV3{Version: "3.0", Generator: m.Generator, Origin: m.Origin}
+	if m.Dynamic {
+		doc.Type = "dynamic"
+	}
+	if !m.Published.IsZero() {
+		doc.PubDate = m.Published.UTC().Format(time.RFC1123Z)
+	}
+	for _, f := range m.Files {
+		x := fileV3{
+			Name:        f.Name,
+			Size:        f.Size,
+			Version:     f.Version,
+			Description: f.Description,
+			Language:    f.Language,
+			OS:          f.OS,
+		}
+		for _, h := range f.Hashes {
+			x.Hashes = append(x.Hashes, hashXML{Type: hashTypeV3(h.Type), Value: h.Value})
+		}
+		if f.Pieces != nil {
+			x.Pieces = &piecesV3{Length: f.Pieces.Length, Type: hashTypeV3(f.Pieces.Type)}
+			for i, h := range f.Pieces.Hashes {
+				x.Pieces.Hashes = append(x.Pieces.Hashes, pieceHashV3{Piece: i, Value: h})
+			}
+		}
+		for _, u := range f.URLs {
+			x.URLs = append(x.URLs, urlV3{Type: urlType(u.URL), Location: u.Location, Preference: preference(u.Priority), URL: u.URL})
+		}
+		for _, u := range f.MetaURLs {
+			if u.MediaType == "torrent" {
+				x.URLs = append(x.URLs, urlV3{Type: "bittorrent", Preference: preference(u.Priority), URL: u.URL})
+			}
+		}
+		doc.Files = append(doc.Files, x)
+	}
+	return doc
+}
+
+func (doc *metalinkV3) metalink() *Metalink {
+	m := &Metalink{Generator: doc.Generator, Origin: doc.Origin, Dynamic: doc.Type == "dynamic"}
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822} {
+		if t, err := time.Parse(layout, strings.TrimSpace(doc.PubDate)); err == nil {
+			m.Published = t
+			break
+		}
+	}
+	for _, x := range doc.Files {
+		f := File{
+			Name:        x.Name,
+			Size:        x.Size,
+			Version:     strings.TrimSpace(x.Version),
+			Description: strings.TrimSpace(x.Description),
+			Language:    x.Language,
+			OS:          x.OS,
+		}
+		for _, h := range x.Hashes {
+			f.Hashes = append(f.Hashes, Hash{Type: hashTypeV4(h.Type), Value: strings.TrimSpace(h.Value)})
+		}
+		if x.Pieces != nil {
+			f.Pieces = &Pieces{Length: x.Pieces.Length, Type: hashTypeV4(x.Pieces.Type), Hashes: make([]string, len(x.Pieces.Hashes))}
+			for i, h := range x.Pieces.Hashes {
+				j := h.Piece
+				if j < 0 || j >= len(x.Pieces.Hashes) {
+					j = i
+				}
+				f.Pieces.Hashes[j] = strings.TrimSpace(h.Value)
+			}
+		}
+		for _, u := range x.URLs {
+			if u.Type == "bittorrent" {
+				f.MetaURLs = append(f.MetaURLs, MetaURL{URL: strings.TrimSpace(u.URL), MediaType: "torrent", Priority: priority(u.Preference)})
+				continue
+			}
+			f.URLs = append(f.URLs, URL{URL: strings.TrimSpace(u.URL), Priority: priority(u.Preference), Location: u.Location})
+		}
+		m.Files = append(m.Files, f)
+	}
+	return m
+}
+
+// preference converts a priority of Metalink 4 to a preference of Metalink 3, and priority the other way around.
+func preference(priority int) int {
+	if priority <= 0 {
+		return 0
+	}
+	if priority >= 100 {
+		return 1
+	}
+	return 101 - priority
+}
+
+func priority(preference int) int {
+	if preference <= 0 {
+		return 0
+	}
+	if preference > 100 {
+		return 1
+	}
+	return 101 - preference
+}
+
+var shaV3 = regexp.MustCompile(`^sha(\d+)$`)
+
+// hashTypeV4 names hash types of Metalink 3, e.g. "sha256", as Metalink 4 does, e.g. "sha-256".
+func hashTypeV4(t string) string {
+	t = strings.ToLower(t)
+	return shaV3.ReplaceAllString(t, "sha-$1")
+}
+
+func hashTypeV3(t string) string {
+	return strings.Replace(strings.ToLower(t), "sha-", "sha", 1)
+}
+
+// urlType returns the type of a url of Metalink 3, i.e. its scheme.
+func urlType(u string) string {
+	if i := strings.Index(u, "://"); i > 0 {
+		return strings.ToLower(u[:i])
+	}
+	return ""
+}
+
+func trimAll(s []string) []string {
+	t := make([]string, len(s))
+	for i := range s {
+		t[i] = strings.TrimSpace(s[i])
+	}
+	return t
+}
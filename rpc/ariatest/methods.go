@@ -0,0 +1,673 @@
+package ariatest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type method struct {
+	secure bool // requires the secret token
+	fn     func(q *queue, a args) (interface{}, *Error)
+}
+
+var methods = map[string]method{
+	"aria2.addUri":               {true, addURI},
+	"aria2.addTorrent":           {true, addTorrent},
+	"aria2.addMetalink":          {true, addMetalink},
+	"aria2.remove":               {true, removeDownload},
+	"aria2.forceRemove":          {true, removeDownload},
+	"aria2.pause":                {true, pause},
+	"aria2.pauseAll":             {true, pauseAll},
+	"aria2.forcePause":           {true, pause},
+	"aria2.forcePauseAll":        {true, pauseAll},
+	"aria2.unpause":              {true, unpause},
+	"aria2.unpauseAll":           {true, unpauseAll},
+	"aria2.tellStatus":           {true, tellStatus},
+	"aria2.getUris":              {true, getURIs},
+	"aria2.getFiles":             {true, getFiles},
+	"aria2.getPeers":             {true, getPeers},
+	"aria2.getServers":           {true, getServers},
+	"aria2.tellActive":           {true, tellActive},
+	"aria2.tellWaiting":          {true, tellWaiting},
+	"aria2.tellStopped":          {true, tellStopped},
+	"aria2.changePosition":       {true, changePosition},
+	"aria2.changeUri":            {true, changeURI},
+	"aria2.getOption":            {true, getOption},
+	"aria2.changeOption":         {true, changeOption},
+	"aria2.getGlobalOption":      {true, getGlobalOption},
+	"aria2.changeGlobalOption":   {true, changeGlobalOption},
+	"aria2.getGlobalStat":        {true, getGlobalStat},
+	"aria2.purgeDownloadResult":  {true, purgeDownloadResult},
+	"aria2.removeDownloadResult": {true, removeDownloadResult},
+	"aria2.getVersion":           {true, getVersion},
+	"aria2.getSessionInfo":       {true, getSessionInfo},
+	"aria2.shutdown":             {true, ok},
+	"aria2.forceShutdown":        {true, ok},
+	"aria2.saveSession":          {true, ok},
+	"system.multicall":           {false, nil},
+	"system.listMethods":         {false, nil},
+	"system.listNotifications":   {false, nil},
+}
+
+var notifications = []string{
+	"aria2.onDownloadStart",
+	"aria2.onDownloadPause",
+	"aria2.onDownloadStop",
+	"aria2.onDownloadComplete",
+	"aria2.onDownloadError",
+	"aria2.onBtDownloadComplete",
+}
+
+func (s *Server) callSystem(method string, params []json.RawMessage) (interface{}, *Error) {
+	switch method {
+	case "system.listMethods":
+		names := make([]string, 0, len(methods))
+		for name := range methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	case "system.listNotifications":
+		return notifications, nil
+	}
+	var calls []struct {
+		Name   string            `json:"methodName"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := args(params).decode(0, &calls); err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, 0, len(calls))
+	for _, c := range calls {
+		if c.Name == "system.multicall" {
+			results = append(results, &Error{Code: 1, Message: "Recursive system.multicall forbidden."})
+			continue
+		}
+		result, err := s.call(c.Name, c.Params)
+		if err != nil {
+			results = append(results, err)
+			continue
+		}
+		results = append(results, []interface{}{result})
+	}
+	return results, nil
+}
+
+type args []json.RawMessage
+
+var errBadParam = &Error{Code: 1, Message: "Bad parameter"}
+
+func (a args) has(i int) bool { return i < len(a) }
+
+func (a args) decode(i int, v interface{}) *Error {
+	if !a.has(i) || json.Unmarshal(a[i], v) != nil {
+		return errBadParam
+	}
+	return nil
+}
+
+func (a args) gid(i int) (gid string, err *Error) {
+	err = a.decode(i, &gid)
+	return
+}
+
+func (a args) int(i int) (n int, err *Error) {
+	err = a.decode(i, &n)
+	return
+}
+
+func (a args) keys(i int) (keys []string, err *Error) {
+	if a.has(i) {
+		err = a.decode(i, &keys)
+	}
+	return
+}
+
+// options decodes an optional struct of options, stringifying every value as aria2 does.
+func (a args) options(i int) (map[string]string, *Error) {
+	options := map[string]string{}
+	if !a.has(i) {
+		return options, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := a.decode(i, &raw); err != nil {
+		return nil, err
+	}
+	for k, v := range raw {
+		var s string
+		var list []string
+		switch {
+		case json.Unmarshal(v, &s) == nil:
+			options[k] = s
+		case json.Unmarshal(v, &list) == nil:
+			options[k] = strings.Join(list, "\n")
+		default:
+			options[k] = string(v)
+		}
+	}
+	return options, nil
+}
+
+func (a args) position(i int) (int, *Error) {
+	if !a.has(i) {
+		return -1, nil
+	}
+	n, err := a.int(i)
+	if err == nil && n < 0 {
+		err = errBadParam
+	}
+	return n, err
+}
+
+func (a args) data(i int) ([]byte, *Error) {
+	var s string
+	if err := a.decode(i, &s); err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, &Error{Code: 1, Message: "Bad base64 data"}
+	}
+	return b, nil
+}
+
+func addURI(q *queue, a args) (interface{}, *Error) {
+	var uris []string
+	if err := a.decode(0, &uris); err != nil || len(uris) == 0 {
+		return nil, &Error{Code: 1, Message: "No URI to download."}
+	}
+	options, err := a.options(1)
+	if err != nil {
+		return nil, err
+	}
+	position, err := a.position(2)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.newDownload(options, []file{{path: baseName(uris[0]), uris: uris}})
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(uris[0], "magnet:") {
+		if i := strings.Index(uris[0], "urn:btih:"); i >= 0 && len(uris[0]) >= i+49 {
+			d.InfoHash = strings.ToLower(uris[0][i+9 : i+49])
+		}
+	}
+	q.enqueue(d, position)
+	return d.gid, nil
+}
+
+func addTorrent(q *queue, a args) (interface{}, *Error) {
+	data, err := a.data(0)
+	if err != nil {
+		return nil, err
+	}
+	var webSeeds []string
+	if a.has(1) {
+		if err = a.decode(1, &webSeeds); err != nil {
+			return nil, err
+		}
+	}
+	options, err := a.options(2)
+	if err != nil {
+		return nil, err
+	}
+	position, err := a.position(3)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(data)
+	hash := hex.EncodeToString(sum[:])
+	name := "torrent-" + hash[:8]
+	d, err := q.newDownload(options, []file{{path: name, uris: webSeeds}})
+	if err != nil {
+		return nil, err
+	}
+	d.InfoHash, d.torrent = hash, name
+	q.enqueue(d, position)
+	return d.gid, nil
+}
+
+type metalinkFile struct {
+	Name string   `xml:"name,attr"`
+	Size int64    `xml:"size"`
+	URLs []string `xml:"url"`
+}
+
+// metalink covers files of both Metalink v4 (RFC 5854) and v3 documents.
+type metalink struct {
+	Files   []metalinkFile `xml:"file"`
+	V3Files []struct {
+		Name string   `xml:"name,attr"`
+		Size int64    `xml:"size"`
+		URLs []string `xml:"resources>url"`
+	} `xml:"files>file"`
+}
+
+func addMetalink(q *queue, a args) (interface{}, *Error) {
+	data, err := a.data(0)
+	if err != nil {
+		return nil, err
+	}
+	options, err := a.options(1)
+	if err != nil {
+		return nil, err
+	}
+	position, err := a.position(2)
+	if err != nil {
+		return nil, err
+	}
+	var doc metalink
+	if xml.Unmarshal(data, &doc) != nil {
+		return nil, &Error{Code: 1, Message: "Failed to parse metalink"}
+	}
+	for _, f := range doc.V3Files {
+		doc.Files = append(doc.Files, metalinkFile{Name: f.Name, Size: f.Size, URLs: f.URLs})
+	}
+	if len(doc.Files) == 0 {
+		return nil, &Error{Code: 1, Message: "No files to download."}
+	}
+	if _, ok := options["gid"]; ok && len(doc.Files) > 1 {
+		return nil, &Error{Code: 1, Message: "gid option cannot be used for multiple files"}
+	}
+	gids := make([]string, 0, len(doc.Files))
+	for _, f := range doc.Files {
+		d, err := q.newDownload(options, []file{{path: f.Name, length: f.Size, uris: f.URLs}})
+		if err != nil {
+			return nil, err
+		}
+		d.TotalLength = f.Size
+		q.enqueue(d, position)
+		if position >= 0 {
+			position++
+		}
+		gids = append(gids, d.gid)
+	}
+	return gids, nil
+}
+
+func removeDownload(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if d.stopped() {
+		return nil, &Error{Code: 1, Message: fmt.Sprintf("Active Download not found for GID#%s", gid)}
+	}
+	q.stop(d, "removed", "31", "")
+	return gid, nil
+}
+
+func pause(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if err = q.pause(d); err != nil {
+		return nil, err
+	}
+	q.schedule()
+	return gid, nil
+}
+
+func pauseAll(q *queue, a args) (interface{}, *Error) {
+	for _, d := range append(append([]*download(nil), q.active...), q.waiting...) {
+		if d.status != "paused" {
+			q.pause(d)
+		}
+	}
+	return "OK", nil
+}
+
+func unpause(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if err = q.unpause(d); err != nil {
+		return nil, err
+	}
+	q.schedule()
+	return gid, nil
+}
+
+func unpauseAll(q *queue, a args) (interface{}, *Error) {
+	for _, d := range q.waiting {
+		if d.status == "paused" {
+			q.unpause(d)
+		}
+	}
+	q.schedule()
+	return "OK", nil
+}
+
+func tellStatus(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := a.keys(1)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	return d.info(keys), nil
+}
+
+func getURIs(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	return d.uriInfos(d.files[0]), nil
+}
+
+func getFiles(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	return d.fileInfos(), nil
+}
+
+func getPeers(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = q.lookup(gid); err != nil {
+		return nil, err
+	}
+	return []interface{}{}, nil
+}
+
+func getServers(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if d.status != "active" {
+		return nil, &Error{Code: 1, Message: fmt.Sprintf("No active download for GID#%s", gid)}
+	}
+	infos := make([]map[string]interface{}, 0, len(d.files))
+	for i, f := range d.files {
+		servers := []map[string]string{}
+		if len(f.uris) > 0 {
+			servers = append(servers, map[string]string{
+				"uri":           f.uris[0],
+				"currentUri":    f.uris[0],
+				"downloadSpeed": strconv.FormatInt(d.DownloadSpeed, 10),
+			})
+		}
+		infos = append(infos, map[string]interface{}{"index": strconv.Itoa(i + 1), "servers": servers})
+	}
+	return infos, nil
+}
+
+func infos(list []*download, keys []string) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, d := range list {
+		out = append(out, d.info(keys))
+	}
+	return out
+}
+
+func tellActive(q *queue, a args) (interface{}, *Error) {
+	keys, err := a.keys(0)
+	if err != nil {
+		return nil, err
+	}
+	return infos(q.active, keys), nil
+}
+
+func tellList(list []*download, a args) (interface{}, *Error) {
+	offset, err := a.int(0)
+	if err != nil {
+		return nil, err
+	}
+	num, err := a.int(1)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := a.keys(2)
+	if err != nil {
+		return nil, err
+	}
+	return infos(window(list, offset, num), keys), nil
+}
+
+func tellWaiting(q *queue, a args) (interface{}, *Error) { return tellList(q.waiting, a) }
+
+func tellStopped(q *queue, a args) (interface{}, *Error) { return tellList(q.stopped, a) }
+
+func changePosition(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := a.int(1)
+	if err != nil {
+		return nil, err
+	}
+	var how string
+	if err = a.decode(2, &how); err != nil {
+		return nil, err
+	}
+	current := -1
+	for i, d := range q.waiting {
+		if d.gid == gid {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		return nil, &Error{Code: 1, Message: fmt.Sprintf("GID#%s not found in the waiting queue.", gid)}
+	}
+	switch how {
+	case "POS_SET":
+	case "POS_CUR":
+		pos += current
+	case "POS_END":
+		pos += len(q.waiting) - 1
+	default:
+		return nil, &Error{Code: 1, Message: "Illegal argument."}
+	}
+	if pos < 0 {
+		pos = 0
+	} else if pos >= len(q.waiting) {
+		pos = len(q.waiting) - 1
+	}
+	d := q.waiting[current]
+	q.waiting = append(q.waiting[:current], q.waiting[current+1:]...)
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[pos+1:], q.waiting[pos:])
+	q.waiting[pos] = d
+	return pos, nil
+}
+
+func changeURI(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	index, err := a.int(1)
+	if err != nil {
+		return nil, err
+	}
+	var delURIs, addURIs []string
+	if err = a.decode(2, &delURIs); err != nil {
+		return nil, err
+	}
+	if err = a.decode(3, &addURIs); err != nil {
+		return nil, err
+	}
+	position, err := a.position(4)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if index < 1 || index > len(d.files) {
+		return nil, &Error{Code: 1, Message: "fileIndex is out of range"}
+	}
+	f := &d.files[index-1]
+	deleted := 0
+	for _, uri := range delURIs {
+		for i := range f.uris {
+			if f.uris[i] == uri {
+				f.uris = append(f.uris[:i], f.uris[i+1:]...)
+				deleted++
+				break
+			}
+		}
+	}
+	if position < 0 || position > len(f.uris) {
+		position = len(f.uris)
+	}
+	uris := append(append(append([]string(nil), f.uris[:position]...), addURIs...), f.uris[position:]...)
+	f.uris = uris
+	return []int{deleted, len(addURIs)}, nil
+}
+
+func getOption(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	return d.options, nil
+}
+
+func changeOption(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	options, err := a.options(1)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if d.stopped() {
+		return nil, &Error{Code: 1, Message: fmt.Sprintf("Cannot change option for GID#%s", gid)}
+	}
+	for k, v := range options {
+		d.options[k] = v
+	}
+	return "OK", nil
+}
+
+func getGlobalOption(q *queue, a args) (interface{}, *Error) {
+	return q.global, nil
+}
+
+func changeGlobalOption(q *queue, a args) (interface{}, *Error) {
+	options, err := a.options(0)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range options {
+		q.global[k] = v
+	}
+	q.schedule()
+	return "OK", nil
+}
+
+func getGlobalStat(q *queue, a args) (interface{}, *Error) {
+	var down, up int64
+	for _, d := range q.active {
+		down += d.DownloadSpeed
+		up += d.UploadSpeed
+	}
+	return map[string]string{
+		"downloadSpeed":   strconv.FormatInt(down, 10),
+		"uploadSpeed":     strconv.FormatInt(up, 10),
+		"numActive":       strconv.Itoa(len(q.active)),
+		"numWaiting":      strconv.Itoa(len(q.waiting)),
+		"numStopped":      strconv.Itoa(len(q.stopped)),
+		"numStoppedTotal": strconv.Itoa(len(q.stopped)),
+	}, nil
+}
+
+func purgeDownloadResult(q *queue, a args) (interface{}, *Error) {
+	for _, d := range q.stopped {
+		delete(q.gids, d.gid)
+	}
+	q.stopped = nil
+	return "OK", nil
+}
+
+func removeDownloadResult(q *queue, a args) (interface{}, *Error) {
+	gid, err := a.gid(0)
+	if err != nil {
+		return nil, err
+	}
+	d, err := q.lookup(gid)
+	if err != nil {
+		return nil, err
+	}
+	if !d.stopped() {
+		return nil, &Error{Code: 1, Message: fmt.Sprintf("Could not remove download result of GID#%s", gid)}
+	}
+	q.stopped, _ = remove(q.stopped, d)
+	delete(q.gids, gid)
+	return "OK", nil
+}
+
+// Version is the aria2 version reported by aria2.getVersion.
+const Version = "1.36.0"
+
+func getVersion(q *queue, a args) (interface{}, *Error) {
+	return map[string]interface{}{
+		"version":         Version,
+		"enabledFeatures": []string{"Async DNS", "BitTorrent", "GZip", "HTTPS", "Message Digest", "Metalink", "XML-RPC"},
+	}, nil
+}
+
+func getSessionInfo(q *queue, a args) (interface{}, *Error) {
+	return map[string]string{"sessionId": q.session}, nil
+}
+
+func ok(q *queue, a args) (interface{}, *Error) { return "OK", nil }
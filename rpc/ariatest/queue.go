@@ -0,0 +1,429 @@
+package ariatest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Download holds the transfer statistics of a download, which tests may alter through Server.Update.
+type Download struct {
+	TotalLength     int64
+	CompletedLength int64
+	UploadLength    int64
+	DownloadSpeed   int64
+	UploadSpeed     int64
+	Connections     int
+	NumSeeders      int
+	Seeder          bool
+	InfoHash        string
+	FollowedBy      []string
+	BelongsTo       string
+}
+
+type file struct {
+	path     string
+	length   int64
+	selected bool
+	uris     []string
+}
+
+type download struct {
+	Download
+	gid          string
+	status       string // active, waiting, paused, error, complete or removed
+	errorCode    string
+	errorMessage string
+	options      map[string]string
+	files        []file
+	torrent      string // name in info dictionary; empty if not a BitTorrent download
+}
+
+func (d *download) stopped() bool {
+	switch d.status {
+	case "error", "complete", "removed":
+		return true
+	}
+	return false
+}
+
+const pieceLength = 1 << 20
+
+// info renders d as a struct of aria2.tellStatus, restricted to keys if any.
+func (d *download) info(keys []string) map[string]interface{} {
+	m := map[string]interface{}{
+		"gid":             d.gid,
+		"status":          d.status,
+		"totalLength":     strconv.FormatInt(d.TotalLength, 10),
+		"completedLength": strconv.FormatInt(d.CompletedLength, 10),
+		"uploadLength":    strconv.FormatInt(d.UploadLength, 10),
+		"downloadSpeed":   strconv.FormatInt(d.DownloadSpeed, 10),
+		"uploadSpeed":     strconv.FormatInt(d.UploadSpeed, 10),
+		"connections":     strconv.Itoa(d.Connections),
+		"pieceLength":     strconv.Itoa(pieceLength),
+		"numPieces":       strconv.FormatInt((d.TotalLength+pieceLength-1)/pieceLength, 10),
+		"dir":             d.options["dir"],
+		"files":           d.fileInfos(),
+	}
+	if d.stopped() {
+		m["errorCode"] = d.errorCode
+		if d.errorMessage != "" {
+			m["errorMessage"] = d.errorMessage
+		}
+	}
+	if d.InfoHash != "" {
+		m["infoHash"] = d.InfoHash
+	}
+	if d.torrent != "" {
+		m["numSeeders"] = strconv.Itoa(d.NumSeeders)
+		m["seeder"] = strconv.FormatBool(d.Seeder)
+		mode := "single"
+		if len(d.files) > 1 {
+			mode = "multi"
+		}
+		m["bittorrent"] = map[string]interface{}{
+			"announceList": [][]string{},
+			"mode":         mode,
+			"info":         map[string]string{"name": d.torrent},
+		}
+	}
+	if len(d.FollowedBy) > 0 {
+		m["followedBy"] = d.FollowedBy
+	}
+	if d.BelongsTo != "" {
+		m["belongsTo"] = d.BelongsTo
+	}
+	if len(keys) == 0 {
+		return m
+	}
+	projected := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := m[key]; ok {
+			projected[key] = v
+		}
+	}
+	return projected
+}
+
+func (d *download) uriInfos(f file) []map[string]string {
+	infos := make([]map[string]string, 0, len(f.uris))
+	for i, uri := range f.uris {
+		status := "waiting"
+		if i == 0 && d.status == "active" {
+			status = "used"
+		}
+		infos = append(infos, map[string]string{"uri": uri, "status": status})
+	}
+	return infos
+}
+
+func (d *download) fileInfos() []map[string]interface{} {
+	infos := make([]map[string]interface{}, 0, len(d.files))
+	for i, f := range d.files {
+		length, completed := f.length, int64(0)
+		if len(d.files) == 1 {
+			length, completed = d.TotalLength, d.CompletedLength
+		} else if d.status == "complete" {
+			completed = length
+		}
+		infos = append(infos, map[string]interface{}{
+			"index":           strconv.Itoa(i + 1),
+			"path":            f.path,
+			"length":          strconv.FormatInt(length, 10),
+			"completedLength": strconv.FormatInt(completed, 10),
+			"selected":        strconv.FormatBool(f.selected),
+			"uris":            d.uriInfos(f),
+		})
+	}
+	return infos
+}
+
+type queue struct {
+	active  []*download
+	waiting []*download // including paused downloads
+	stopped []*download
+	gids    map[string]*download
+	global  map[string]string
+	session string
+	seq     uint64
+	pending []notification
+}
+
+func newQueue() queue {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return queue{
+		gids: make(map[string]*download),
+		global: map[string]string{
+			"dir":                        "/downloads",
+			"max-concurrent-downloads":   "5",
+			"max-connection-per-server":  "1",
+			"max-download-limit":         "0",
+			"max-upload-limit":           "0",
+			"max-overall-download-limit": "0",
+			"max-overall-upload-limit":   "0",
+			"split":                      "5",
+		},
+		session: hex.EncodeToString(b),
+	}
+}
+
+func (q *queue) emit(method, gid string) {
+	q.pending = append(q.pending, notification{Version: "2.0", Method: method, Params: []event{{Gid: gid}}})
+}
+
+func errNotFound(gid string) *Error {
+	return &Error{Code: 1, Message: fmt.Sprintf("GID %s is not found", gid)}
+}
+
+func (q *queue) lookup(gid string) (*download, *Error) {
+	if d, ok := q.gids[gid]; ok {
+		return d, nil
+	}
+	return nil, errNotFound(gid)
+}
+
+func (q *queue) newGid(options map[string]string) (string, *Error) {
+	if gid, ok := options["gid"]; ok {
+		if _, err := hex.DecodeString(gid); err != nil || len(gid) != 16 {
+			return "", &Error{Code: 1, Message: fmt.Sprintf("%s is invalid for gid", gid)}
+		}
+		if _, ok := q.gids[gid]; ok {
+			return "", &Error{Code: 1, Message: fmt.Sprintf("GID %s is not unique.", gid)}
+		}
+		return gid, nil
+	}
+	for {
+		q.seq++
+		gid := fmt.Sprintf("%016x", q.seq*0x9e3779b97f4a7c15)
+		if _, ok := q.gids[gid]; !ok {
+			return gid, nil
+		}
+	}
+}
+
+// newDownload registers a download for files with the global options overridden by options.
+func (q *queue) newDownload(options map[string]string, files []file) (*download, *Error) {
+	gid, err := q.newGid(options)
+	if err != nil {
+		return nil, err
+	}
+	opts := make(map[string]string, len(q.global)+len(options))
+	for k, v := range q.global {
+		opts[k] = v
+	}
+	for k, v := range options {
+		opts[k] = v
+	}
+	delete(opts, "gid")
+	for i := range files {
+		files[i].path = path.Join(opts["dir"], files[i].path)
+		files[i].selected = true
+	}
+	if out, ok := opts["out"]; ok && len(files) == 1 {
+		files[0].path = path.Join(opts["dir"], out)
+	}
+	d := &download{gid: gid, status: "waiting", options: opts, files: files}
+	return d, nil
+}
+
+// enqueue inserts d into the waiting queue at position, or appends it if position is out of range.
+func (q *queue) enqueue(d *download, position int) {
+	if d.options["pause"] == "true" {
+		d.status = "paused"
+	}
+	q.gids[d.gid] = d
+	if position < 0 || position > len(q.waiting) {
+		position = len(q.waiting)
+	}
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[position+1:], q.waiting[position:])
+	q.waiting[position] = d
+	q.schedule()
+}
+
+// schedule starts waiting downloads as long as max-concurrent-downloads permits.
+func (q *queue) schedule() {
+	max, _ := strconv.Atoi(q.global["max-concurrent-downloads"])
+	for i := 0; i < len(q.waiting) && len(q.active) < max; {
+		d := q.waiting[i]
+		if d.status != "waiting" {
+			i++
+			continue
+		}
+		q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+		d.status = "active"
+		q.active = append(q.active, d)
+		q.emit("aria2.onDownloadStart", d.gid)
+	}
+}
+
+func remove(list []*download, d *download) ([]*download, bool) {
+	for i := range list {
+		if list[i] == d {
+			return append(list[:i], list[i+1:]...), true
+		}
+	}
+	return list, false
+}
+
+// stop moves an active or waiting download to the stopped list with status.
+func (q *queue) stop(d *download, status, code, message string) {
+	var ok bool
+	if q.active, ok = remove(q.active, d); !ok {
+		q.waiting, _ = remove(q.waiting, d)
+	}
+	d.status = status
+	d.errorCode = code
+	d.errorMessage = message
+	d.DownloadSpeed, d.UploadSpeed, d.Connections = 0, 0, 0
+	q.stopped = append(q.stopped, d)
+	switch status {
+	case "removed":
+		q.emit("aria2.onDownloadStop", d.gid)
+	case "complete":
+		q.emit("aria2.onDownloadComplete", d.gid)
+	case "error":
+		q.emit("aria2.onDownloadError", d.gid)
+	}
+	q.schedule()
+}
+
+func (q *queue) pause(d *download) *Error {
+	switch d.status {
+	case "active":
+		q.active, _ = remove(q.active, d)
+		q.waiting = append([]*download{d}, q.waiting...)
+	case "waiting":
+	default:
+		return &Error{Code: 1, Message: fmt.Sprintf("GID#%s cannot be paused now", d.gid)}
+	}
+	d.status = "paused"
+	d.DownloadSpeed, d.UploadSpeed, d.Connections = 0, 0, 0
+	q.emit("aria2.onDownloadPause", d.gid)
+	return nil
+}
+
+func (q *queue) unpause(d *download) *Error {
+	if d.status != "paused" {
+		return &Error{Code: 1, Message: fmt.Sprintf("GID#%s cannot be unpaused now", d.gid)}
+	}
+	d.status = "waiting"
+	return nil
+}
+
+// window returns num downloads of list starting at offset, with the semantics of aria2.tellWaiting.
+func window(list []*download, offset, num int) []*download {
+	if num <= 0 {
+		return nil
+	}
+	if offset >= 0 {
+		if offset >= len(list) {
+			return nil
+		}
+		end := offset + num
+		if end > len(list) {
+			end = len(list)
+		}
+		return list[offset:end]
+	}
+	var out []*download
+	for i := len(list) + offset; i >= 0 && len(out) < num; i-- {
+		out = append(out, list[i])
+	}
+	return out
+}
+
+// Complete finishes the active download denoted by gid and emits aria2.onDownloadComplete.
+func (s *Server) Complete(gid string) (err error) {
+	s.mutate(func(q *queue) {
+		d, e := q.lookup(gid)
+		if e != nil {
+			err = e
+			return
+		}
+		if d.stopped() {
+			err = fmt.Errorf("ariatest: %s is already %s", gid, d.status)
+			return
+		}
+		d.CompletedLength = d.TotalLength
+		q.stop(d, "complete", "0", "")
+	})
+	return
+}
+
+// Fail stops the download denoted by gid with aria2 exit status code and message, and emits aria2.onDownloadError.
+func (s *Server) Fail(gid string, code int, message string) (err error) {
+	s.mutate(func(q *queue) {
+		d, e := q.lookup(gid)
+		if e != nil {
+			err = e
+			return
+		}
+		if d.stopped() {
+			err = fmt.Errorf("ariatest: %s is already %s", gid, d.status)
+			return
+		}
+		q.stop(d, "error", strconv.Itoa(code), message)
+	})
+	return
+}
+
+// BtComplete emits aria2.onBtDownloadComplete for the download denoted by gid, which keeps seeding.
+func (s *Server) BtComplete(gid string) (err error) {
+	s.mutate(func(q *queue) {
+		d, e := q.lookup(gid)
+		if e != nil {
+			err = e
+			return
+		}
+		d.CompletedLength = d.TotalLength
+		d.Seeder = true
+		q.emit("aria2.onBtDownloadComplete", d.gid)
+	})
+	return
+}
+
+// Update calls fn with the statistics of the download denoted by gid, which fn may modify.
+// Status transitions are driven by rpc calls, Complete and Fail only.
+func (s *Server) Update(gid string, fn func(d *Download)) (err error) {
+	s.mutate(func(q *queue) {
+		d, e := q.lookup(gid)
+		if e != nil {
+			err = e
+			return
+		}
+		fn(&d.Download)
+	})
+	return
+}
+
+// Status returns the status of the download denoted by gid, e.g. "active", and whether it exists.
+func (s *Server) Status(gid string) (status string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, found := s.q.gids[gid]; found {
+		return d.status, true
+	}
+	return "", false
+}
+
+func baseName(uri string) string {
+	if strings.HasPrefix(uri, "magnet:") {
+		return "[METADATA]"
+	}
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+	if i := strings.Index(uri, "://"); i >= 0 {
+		uri = uri[i+3:]
+	}
+	name := path.Base(uri)
+	if !strings.Contains(uri, "/") || name == "/" || name == "." || strings.HasSuffix(uri, "/") {
+		return "index.html"
+	}
+	return name
+}
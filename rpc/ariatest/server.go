@@ -0,0 +1,366 @@
+// Package ariatest provides an in-process aria2 RPC server for tests.
+//
+// The server speaks the JSON-RPC dialect of aria2 over HTTP and websocket, and its XML-RPC at /rpc,
+// keeps an in-memory download queue and emits aria2.onDownload* notifications
+// to connected websocket clients, so that rpc.Client can be exercised
+// hermetically.
+package ariatest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server is an aria2 RPC server listening on a system-chosen port on the local loopback interface.
+type Server struct {
+	URL          string // http://ipaddr:port/jsonrpc
+	WebsocketURL string // ws://ipaddr:port/jsonrpc
+	XMLRPCURL    string // http://ipaddr:port/rpc
+
+	srv    *httptest.Server
+	secret string
+
+	mu         sync.Mutex
+	q          queue      // guarded by mu
+	batchFault BatchFault // guarded by mu
+
+	connMu sync.Mutex
+	conns  map[*wsConn]struct{}
+}
+
+// NewServer starts and returns a new Server.
+// When secret is not empty, every request must carry "token:"+secret as its first parameter.
+// The caller should call Close when finished, to shut it down.
+func NewServer(secret string) *Server {
+	s := &Server{
+		secret: secret,
+		q:      newQueue(),
+		conns:  make(map[*wsConn]struct{}),
+	}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL + "/jsonrpc"
+	s.WebsocketURL = "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/jsonrpc"
+	s.XMLRPCURL = s.srv.URL + "/rpc"
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests on this server have completed.
+func (s *Server) Close() {
+	s.CloseClientConnections()
+	s.srv.Close()
+}
+
+// CloseClientConnections closes any open HTTP and websocket connections to the server,
+// as if the aria2 daemon had been restarted. The download queue is kept.
+func (s *Server) CloseClientConnections() {
+	s.connMu.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.connMu.Unlock()
+	s.srv.CloseClientConnections()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/rpc" {
+		s.serveXML(w, r)
+		return
+	}
+	if r.URL.Path != "/jsonrpc" {
+		http.NotFound(w, r)
+		return
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebsocket(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.serveGet(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json-rpc")
+	w.Write(s.handleMessage(body))
+}
+
+// serveGet serves a request encoded in the query as aria2 does: method and id are JSON strings,
+// and params is a base64-encoded JSON array; without method and id, params is the whole request, or batch of them.
+// The response is wrapped in a call of jsoncallback, if set.
+func (s *Server) serveGet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, err := base64.StdEncoding.DecodeString(query.Get("params"))
+	if err != nil {
+		params = []byte("<invalid base64>") // answered by a parse error
+	}
+	body := params
+	if method, id := query.Get("method"), query.Get("id"); method != "" || id != "" {
+		req := map[string]json.RawMessage{}
+		req["method"], _ = json.Marshal(method)
+		if id != "" {
+			req["id"], _ = json.Marshal(id)
+		}
+		if len(params) != 0 {
+			req["params"] = params
+		}
+		if body, err = json.Marshal(req); err != nil { // params is not JSON
+			body = params
+		}
+	}
+	resp := s.handleMessage(body)
+	if callback := query.Get("jsoncallback"); callback != "" {
+		w.Header().Set("Content-Type", "text/javascript")
+		w.Write([]byte(callback + "("))
+		w.Write(resp)
+		w.Write([]byte(")"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json-rpc")
+	w.Write(resp)
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex // serializes writes
+}
+
+func (c *wsConn) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn}
+	s.connMu.Lock()
+	s.conns[c] = struct{}{}
+	s.connMu.Unlock()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, c)
+		s.connMu.Unlock()
+		conn.Close()
+	}()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err = c.write(s.handleMessage(data)); err != nil {
+			return
+		}
+	}
+}
+
+type request struct {
+	Version string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+	Id      json.RawMessage   `json:"id"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object as sent by aria2.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// handleMessage handles a request, or a batch array of them, which is answered by an array of responses in the same order.
+func (s *Server) handleMessage(data []byte) []byte {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		return s.handleBatch(data)
+	}
+	var req request
+	var resp response
+	if err := json.Unmarshal(data, &req); err != nil {
+		resp = response{Version: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: -32700, Message: "Parse error."}}
+	} else {
+		resp = s.handleRequest(&req)
+	}
+	b, _ := json.Marshal(resp)
+	return b
+}
+
+// BatchFault is a way of answering batch requests wrongly, to test clients against misbehaving servers.
+type BatchFault int
+
+const (
+	BatchOK     BatchFault = iota // answer batch requests as aria2 does
+	BatchNullID                   // answer with an array whose last response has a null id
+	BatchReject                   // answer with a single error object, as for an invalid batch
+)
+
+// FailBatches makes the server answer the batch requests it receives from now on as fault says.
+func (s *Server) FailBatches(fault BatchFault) {
+	s.mu.Lock()
+	s.batchFault = fault
+	s.mu.Unlock()
+}
+
+func (s *Server) handleBatch(data []byte) []byte {
+	s.mu.Lock()
+	fault := s.batchFault
+	s.mu.Unlock()
+	var reqs []json.RawMessage
+	var resp interface{}
+	if err := json.Unmarshal(data, &reqs); err != nil {
+		resp = response{Version: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: -32700, Message: "Parse error."}}
+	} else if len(reqs) == 0 || fault == BatchReject {
+		resp = response{Version: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: -32600, Message: "Invalid Request."}}
+	} else {
+		resps := make([]response, len(reqs))
+		for i, raw := range reqs {
+			var req request
+			if err := json.Unmarshal(raw, &req); err != nil {
+				resps[i] = response{Version: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: -32600, Message: "Invalid Request."}}
+				continue
+			}
+			resps[i] = s.handleRequest(&req)
+		}
+		if fault == BatchNullID {
+			resps[len(resps)-1].Id = json.RawMessage("null")
+		}
+		resp = resps
+	}
+	b, _ := json.Marshal(resp)
+	return b
+}
+
+func (s *Server) handleRequest(req *request) response {
+	resp := response{Version: "2.0", Id: req.Id}
+	if len(resp.Id) == 0 {
+		resp.Id = json.RawMessage("null")
+	}
+	if req.Method == "" {
+		resp.Error = &Error{Code: -32600, Message: "Invalid Request."}
+		return resp
+	}
+	result, err := s.call(req.Method, req.Params)
+	if err != nil {
+		resp.Error = err
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// call dispatches a single method call; system.multicall recurses into it.
+func (s *Server) call(method string, params []json.RawMessage) (interface{}, *Error) {
+	m, ok := methods[method]
+	if !ok {
+		return nil, &Error{Code: 1, Message: "No such method: " + method}
+	}
+	params, err := s.authorize(params, m.secure)
+	if err != nil {
+		return nil, err
+	}
+	if m.fn == nil { // system.* methods do not touch the queue
+		return s.callSystem(method, params)
+	}
+	var result interface{}
+	s.mutate(func(q *queue) {
+		result, err = m.fn(q, args(params))
+	})
+	return result, err
+}
+
+// authorize strips the leading "token:" parameter, checking it against the secret if secure.
+func (s *Server) authorize(params []json.RawMessage, secure bool) ([]json.RawMessage, *Error) {
+	var token string
+	if len(params) > 0 && json.Unmarshal(params[0], &token) == nil && strings.HasPrefix(token, "token:") {
+		params = params[1:]
+		token = strings.TrimPrefix(token, "token:")
+	} else {
+		token = ""
+	}
+	if secure && s.secret != "" && token != s.secret {
+		return nil, &Error{Code: 1, Message: "Unauthorized"}
+	}
+	return params, nil
+}
+
+type notification struct {
+	Version string  `json:"jsonrpc"`
+	Method  string  `json:"method"`
+	Params  []event `json:"params"`
+}
+
+type event struct {
+	Gid string `json:"gid"`
+}
+
+// Notify sends a notification of method, e.g. "aria2.onDownloadStart", for gids to every websocket client.
+func (s *Server) Notify(method string, gids ...string) {
+	ns := make([]notification, 0, len(gids))
+	for _, gid := range gids {
+		ns = append(ns, notification{Version: "2.0", Method: method, Params: []event{{Gid: gid}}})
+	}
+	s.broadcast(ns)
+}
+
+func (s *Server) broadcast(ns []notification) {
+	if len(ns) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	msgs := make([][]byte, 0, len(ns))
+	for i := range ns {
+		buf.Reset()
+		json.NewEncoder(&buf).Encode(&ns[i])
+		msgs = append(msgs, append([]byte(nil), buf.Bytes()...))
+	}
+	s.connMu.Lock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connMu.Unlock()
+	for _, c := range conns {
+		for _, msg := range msgs {
+			if c.write(msg) != nil {
+				break
+			}
+		}
+	}
+}
+
+// mutate runs fn on the queue and then sends the notifications it produced.
+func (s *Server) mutate(fn func(q *queue)) {
+	s.mu.Lock()
+	fn(&s.q)
+	pending := s.q.pending
+	s.q.pending = nil
+	s.mu.Unlock()
+	s.broadcast(pending)
+}
@@ -0,0 +1,246 @@
+package ariatest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func call(t *testing.T, s *Server, method string, params ...interface{}) (json.RawMessage, *Error) {
+	t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	b, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	r, err := http.Post(s.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Result, resp.Error
+}
+
+func mustCall(t *testing.T, s *Server, reply interface{}, method string, params ...interface{}) {
+	t.Helper()
+	result, err := call(t, s, method, params...)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	if reply != nil {
+		if err := json.Unmarshal(result, reply); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func gids(t *testing.T, s *Server, method string, params ...interface{}) []string {
+	t.Helper()
+	var infos []struct {
+		Gid string `json:"gid"`
+	}
+	mustCall(t, s, &infos, method, params...)
+	out := []string{}
+	for _, info := range infos {
+		out = append(out, info.Gid)
+	}
+	return out
+}
+
+func TestQueue(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+	mustCall(t, s, nil, "aria2.changeGlobalOption", map[string]string{"max-concurrent-downloads": "1"})
+	var a, b, c string
+	mustCall(t, s, &a, "aria2.addUri", []string{"http://example.org/a"})
+	mustCall(t, s, &b, "aria2.addUri", []string{"http://example.org/b"})
+	mustCall(t, s, &c, "aria2.addUri", []string{"http://example.org/c"}, map[string]string{}, 0)
+
+	if got := gids(t, s, "aria2.tellActive"); !reflect.DeepEqual(got, []string{a}) {
+		t.Errorf("tellActive = %v, want [%s]", got, a)
+	}
+	if got := gids(t, s, "aria2.tellWaiting", 0, 10); !reflect.DeepEqual(got, []string{c, b}) {
+		t.Errorf("tellWaiting(0, 10) = %v, want [%s %s]", got, c, b)
+	}
+	if got := gids(t, s, "aria2.tellWaiting", -1, 2); !reflect.DeepEqual(got, []string{b, c}) {
+		t.Errorf("tellWaiting(-1, 2) = %v, want [%s %s]", got, b, c)
+	}
+	var pos int
+	mustCall(t, s, &pos, "aria2.changePosition", b, 0, "POS_SET")
+	if pos != 0 {
+		t.Errorf("changePosition = %d, want 0", pos)
+	}
+
+	if err := s.Complete(a); err != nil {
+		t.Fatal(err)
+	}
+	if got := gids(t, s, "aria2.tellActive"); !reflect.DeepEqual(got, []string{b}) {
+		t.Errorf("tellActive after Complete = %v, want [%s]", got, b)
+	}
+	if err := s.Fail(b, 3, "Resource not found"); err != nil {
+		t.Fatal(err)
+	}
+	var info map[string]interface{}
+	mustCall(t, s, &info, "aria2.tellStatus", b, []string{"status", "errorCode"})
+	if want := map[string]interface{}{"status": "error", "errorCode": "3"}; !reflect.DeepEqual(info, want) {
+		t.Errorf("tellStatus = %v, want %v", info, want)
+	}
+	if got := gids(t, s, "aria2.tellStopped", 0, 10); !reflect.DeepEqual(got, []string{a, b}) {
+		t.Errorf("tellStopped = %v, want [%s %s]", got, a, b)
+	}
+	if _, err := call(t, s, "aria2.remove", a); err == nil {
+		t.Error("remove of a stopped download succeeded")
+	}
+	mustCall(t, s, nil, "aria2.purgeDownloadResult")
+	if _, err := call(t, s, "aria2.tellStatus", a); err == nil {
+		t.Error("tellStatus of a purged download succeeded")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	s := NewServer("secret")
+	defer s.Close()
+	if _, err := call(t, s, "aria2.getVersion"); err == nil || err.Message != "Unauthorized" {
+		t.Errorf("getVersion without token = %v, want Unauthorized", err)
+	}
+	mustCall(t, s, nil, "aria2.getVersion", "token:secret")
+	mustCall(t, s, nil, "system.listMethods")
+	var results []json.RawMessage
+	mustCall(t, s, &results, "system.multicall", []interface{}{
+		map[string]interface{}{"methodName": "aria2.getVersion", "params": []string{"token:secret"}},
+		map[string]interface{}{"methodName": "aria2.getVersion", "params": []string{}},
+	})
+	if len(results) != 2 || results[0][0] != '[' || results[1][0] != '{' {
+		t.Errorf("multicall = %s", results)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+	conn, _, err := websocket.DefaultDialer.Dial(s.WebsocketURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var gid string
+	mustCall(t, s, &gid, "aria2.addUri", []string{"http://example.org/a"})
+	mustCall(t, s, nil, "aria2.pause", gid)
+	s.Notify("aria2.onBtDownloadComplete", gid)
+	for _, want := range []string{"aria2.onDownloadStart", "aria2.onDownloadPause", "aria2.onBtDownloadComplete"} {
+		var n notification
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n.Method != want || len(n.Params) != 1 || n.Params[0].Gid != gid {
+			t.Errorf("notification = %+v, want %s for %s", n, want, gid)
+		}
+	}
+}
+
+func TestBatchArray(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+	post := func(body string) []byte {
+		r, err := http.Post(s.URL, "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		return buf.Bytes()
+	}
+	var resps []struct {
+		Id     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	body := post(`[{"jsonrpc":"2.0","id":7,"method":"aria2.getVersion","params":[]},` +
+		`{"jsonrpc":"2.0","id":8,"method":"aria2.tellStatus","params":["0000000000000001"]},3]`)
+	if err := json.Unmarshal(body, &resps); err != nil {
+		t.Fatalf("%s: %v", body, err)
+	}
+	if len(resps) != 3 || resps[0].Id != 7 || resps[0].Error != nil || len(resps[0].Result) == 0 ||
+		resps[1].Id != 8 || resps[1].Error == nil || resps[2].Error == nil || resps[2].Error.Code != -32600 {
+		t.Errorf("batch response = %s", body)
+	}
+	var resp response
+	if body = post(`[]`); json.Unmarshal(body, &resp) != nil || resp.Error == nil || resp.Error.Code != -32600 {
+		t.Errorf("empty batch response = %s", body)
+	}
+}
+
+func TestGet(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+	get := func(query url.Values) string {
+		r, err := http.Get(s.URL + "?" + query.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		return buf.String()
+	}
+	params := base64.StdEncoding.EncodeToString([]byte(`["0000000000000001"]`))
+	body := get(url.Values{"method": {"aria2.tellStatus"}, "id": {"foo"}, "params": {params}, "jsoncallback": {"cb"}})
+	if !strings.HasPrefix(body, `cb({"jsonrpc":"2.0","id":"foo","error":{"code":1,`) || !strings.HasSuffix(body, "})") {
+		t.Errorf("GET tellStatus = %s", body)
+	}
+	batch := base64.StdEncoding.EncodeToString([]byte(`[{"jsonrpc":"2.0","id":1,"method":"aria2.getVersion"},{"jsonrpc":"2.0","id":2,"method":"aria2.getGlobalStat"}]`))
+	var resps []response
+	if body = get(url.Values{"params": {batch}}); json.Unmarshal([]byte(body), &resps) != nil || len(resps) != 2 || resps[0].Error != nil || resps[1].Error != nil {
+		t.Errorf("GET batch = %s", body)
+	}
+	if body = get(url.Values{"params": {"!"}}); !strings.Contains(body, `"code":-32700`) {
+		t.Errorf("GET invalid params = %s", body)
+	}
+}
+
+func TestXMLRPC(t *testing.T) {
+	s := NewServer("")
+	defer s.Close()
+	post := func(body string) string {
+		r, err := http.Post(s.XMLRPCURL, "text/xml", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		return buf.String()
+	}
+	torrent := base64.StdEncoding.EncodeToString([]byte("d4:infod4:name1:aee"))
+	body := post(`<methodCall><methodName>aria2.addTorrent</methodName><params><param><value><base64>` + torrent + `</base64></value></param></params></methodCall>`)
+	if !strings.Contains(body, "<params><param><value><string>") {
+		t.Errorf("addTorrent = %s", body)
+	}
+	body = post(`<methodCall><methodName>system.multicall</methodName><params><param><value><array><data>` +
+		`<value><struct><member><name>methodName</name><value>aria2.tellStatus</value></member>` +
+		`<member><name>params</name><value><array><data><value>0000000000000001</value></data></array></value></member></struct></value>` +
+		`</data></array></value></param></params></methodCall>`)
+	if !strings.Contains(body, "<member><name>faultCode</name><value><int>1</int></value></member>") || strings.Contains(body, "<fault>") {
+		t.Errorf("multicall = %s", body)
+	}
+	if body = post(`<methodCall>`); !strings.Contains(body, "<fault>") {
+		t.Errorf("invalid request = %s", body)
+	}
+}
@@ -0,0 +1,204 @@
+package ariatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// serveXML serves an XML-RPC request as aria2 does at /rpc, by way of the JSON encoding of its params:
+// base64 values are passed on as base64-encoded strings, and integers as numbers.
+func (s *Server) serveXML(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var call struct {
+		Method string     `xml:"methodName"`
+		Params []xmlValue `xml:"params>param>value"`
+	}
+	var result interface{}
+	var rpcErr *Error
+	if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+		rpcErr = &Error{Code: 1, Message: "Failed to parse xml-rpc request."}
+	} else if params, err := xmlParams(call.Params); err != nil {
+		rpcErr = &Error{Code: 1, Message: err.Error()}
+	} else {
+		result, rpcErr = s.call(call.Method, params)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + "<methodResponse>")
+	if rpcErr != nil {
+		buf.WriteString("<fault>")
+		writeXMLValue(&buf, fault(rpcErr))
+		buf.WriteString("</fault>")
+	} else {
+		buf.WriteString("<params><param>")
+		writeXMLValue(&buf, xmlResult(result))
+		buf.WriteString("</param></params>")
+	}
+	buf.WriteString("</methodResponse>")
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write(buf.Bytes())
+}
+
+func fault(e *Error) map[string]interface{} {
+	return map[string]interface{}{"faultCode": json.Number(strconv.Itoa(e.Code)), "faultString": e.Message}
+}
+
+// xmlResult returns result as generic JSON values, faults in the results of system.multicall turned into fault structs.
+func xmlResult(result interface{}) interface{} {
+	switch result := result.(type) {
+	case *Error:
+		return fault(result)
+	case []interface{}:
+		values := make([]interface{}, len(result))
+		for i, v := range result {
+			values[i] = xmlResult(v)
+		}
+		return values
+	}
+	b, _ := json.Marshal(result)
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	d.Decode(&v)
+	return v
+}
+
+// writeXMLValue writes a value decoded from JSON as a value element.
+func writeXMLValue(buf *bytes.Buffer, v interface{}) {
+	buf.WriteString("<value>")
+	switch v := v.(type) {
+	case string:
+		buf.WriteString("<string>")
+		xml.EscapeText(buf, []byte(v))
+		buf.WriteString("</string>")
+	case bool:
+		if v {
+			buf.WriteString("<boolean>1</boolean>")
+		} else {
+			buf.WriteString("<boolean>0</boolean>")
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			buf.WriteString("<int>" + v.String() + "</int>")
+		} else {
+			buf.WriteString("<double>" + v.String() + "</double>")
+		}
+	case []interface{}:
+		buf.WriteString("<array><data>")
+		for _, e := range v {
+			writeXMLValue(buf, e)
+		}
+		buf.WriteString("</data></array>")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteString("<struct>")
+		for _, key := range keys {
+			buf.WriteString("<member><name>")
+			xml.EscapeText(buf, []byte(key))
+			buf.WriteString("</name>")
+			writeXMLValue(buf, v[key])
+			buf.WriteString("</member>")
+		}
+		buf.WriteString("</struct>")
+	default: // null
+		buf.WriteString("<string></string>")
+	}
+	buf.WriteString("</value>")
+}
+
+// xmlValue is a value element of XML-RPC; one of its fields is set, or else Text is a string.
+type xmlValue struct {
+	String  *string `xml:"string"`
+	Int     *string `xml:"int"`
+	I4      *string `xml:"i4"`
+	Boolean *string `xml:"boolean"`
+	Double  *string `xml:"double"`
+	Base64  *string `xml:"base64"`
+	Struct  *struct {
+		Members []struct {
+			Name  string   `xml:"name"`
+			Value xmlValue `xml:"value"`
+		} `xml:"member"`
+	} `xml:"struct"`
+	Array *struct {
+		Values []xmlValue `xml:"data>value"`
+	} `xml:"array"`
+	Text string `xml:",chardata"`
+}
+
+func xmlParams(values []xmlValue) ([]json.RawMessage, error) {
+	params := make([]json.RawMessage, len(values))
+	for i := range values {
+		v, err := values[i].value()
+		if err != nil {
+			return nil, err
+		}
+		if params[i], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+var errXMLValue = errors.New("Bad xml-rpc value.")
+
+func (v *xmlValue) value() (interface{}, error) {
+	switch {
+	case v.String != nil:
+		return *v.String, nil
+	case v.Int != nil, v.I4 != nil:
+		s := v.Int
+		if s == nil {
+			s = v.I4
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(*s), 10, 32)
+		if err != nil {
+			return nil, errXMLValue
+		}
+		return n, nil
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean) == "1", nil
+	case v.Double != nil:
+		f, err := strconv.ParseFloat(strings.TrimSpace(*v.Double), 64)
+		if err != nil {
+			return nil, errXMLValue
+		}
+		return f, nil
+	case v.Base64 != nil:
+		return strings.Join(strings.Fields(*v.Base64), ""), nil
+	case v.Struct != nil:
+		m := make(map[string]interface{}, len(v.Struct.Members))
+		for i := range v.Struct.Members {
+			member := &v.Struct.Members[i]
+			value, err := member.Value.value()
+			if err != nil {
+				return nil, err
+			}
+			m[member.Name] = value
+		}
+		return m, nil
+	case v.Array != nil:
+		a := make([]interface{}, len(v.Array.Values))
+		for i := range v.Array.Values {
+			value, err := v.Array.Values[i].value()
+			if err != nil {
+				return nil, err
+			}
+			a[i] = value
+		}
+		return a, nil
+	}
+	return v.Text, nil
+}
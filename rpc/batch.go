@@ -0,0 +1,452 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// Batch collects calls to aria2 and sends them in a single round trip with system.multicall, or as a JSON-RPC batch request.
+// Each builder method returns the call it adds; its Result and Err are set by Do or DoArray.
+// A fault of one call is reported as its Err, of type *Error, and does not fail the others.
+//
+//	b := c.Batch()
+//	st := b.TellStatus(gid)
+//	stat := b.GetGlobalStat()
+//	if err := b.Do(ctx); err != nil { ... }
+//	if st.Err == nil { ... st.Result.Status ... }
+type Batch struct {
+	c     *client
+	calls []batchCall
+}
+
+type batchCall struct {
+	method Method
+	reply  interface{} // pointer to the Result of the call
+	err    *error      // the Err of the call
+}
+
+// StringCall is a call of a Batch returning a string, e.g. a GID or "OK".
+type StringCall struct {
+	Result string
+	Err    error
+}
+
+// StringsCall is a call of a Batch returning strings, e.g. GIDs.
+type StringsCall struct {
+	Result []string
+	Err    error
+}
+
+// IntCall is a call of a Batch returning an integer.
+type IntCall struct {
+	Result int
+	Err    error
+}
+
+// IntsCall is a call of a Batch returning integers.
+type IntsCall struct {
+	Result []int
+	Err    error
+}
+
+// StatusCall is a call of a Batch returning the status of a download.
+type StatusCall struct {
+	Result StatusInfo
+	Err    error
+}
+
+// StatusesCall is a call of a Batch returning the status of downloads.
+type StatusesCall struct {
+	Result []StatusInfo
+	Err    error
+}
+
+// URIsCall is a call of a Batch returning the URIs of a download.
+type URIsCall struct {
+	Result []URIInfo
+	Err    error
+}
+
+// FilesCall is a call of a Batch returning the files of a download.
+type FilesCall struct {
+	Result []FileInfo
+	Err    error
+}
+
+// PeersCall is a call of a Batch returning the peers of a download.
+type PeersCall struct {
+	Result []PeerInfo
+	Err    error
+}
+
+// ServersCall is a call of a Batch returning the servers of a download.
+type ServersCall struct {
+	Result []ServerInfo
+	Err    error
+}
+
+// OptionCall is a call of a Batch returning options.
+type OptionCall struct {
+	Result Option
+	Err    error
+}
+
+// GlobalStatCall is a call of a Batch returning global statistics.
+type GlobalStatCall struct {
+	Result GlobalStatInfo
+	Err    error
+}
+
+// VersionCall is a call of a Batch returning the version of aria2.
+type VersionCall struct {
+	Result VersionInfo
+	Err    error
+}
+
+// SessionCall is a call of a Batch returning session information.
+type SessionCall struct {
+	Result SessionInfo
+	Err    error
+}
+
+func (c *client) Batch() *Batch {
+	return &Batch{c: c}
+}
+
+// add appends a call of method with params; the secret token is prepended to params.
+func (b *Batch) add(method string, reply interface{}, err *error, params ...interface{}) {
+	if b.c.token != "" {
+		params = append([]interface{}{"token:" + b.c.token}, params...)
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+	b.calls = append(b.calls, batchCall{method: Method{Name: method, Params: params}, reply: reply, err: err})
+}
+
+// Len returns the number of calls Do will send.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Do sends the calls added so far, then empties b so that it can be reused.
+// It returns an error only if the round trip as a whole fails, which is also set as Err of every call.
+func (b *Batch) Do(ctx context.Context) error {
+	return b.do(func(calls []batchCall) error {
+		methods := make([]Method, len(calls))
+		for i, call := range calls {
+			methods[i] = call.method
+		}
+		var results []json.RawMessage
+		err := b.c.Call(ctx, aria2Multicall, []interface{}{methods}, &results)
+		if err == nil && len(results) != len(calls) {
+			err = fmt.Errorf("system.multicall returned %d results for %d calls", len(results), len(calls))
+		}
+		if err != nil {
+			return err
+		}
+		for i, call := range calls {
+			*call.err = decodeMulticallResult(results[i], call.reply)
+		}
+		return nil
+	})
+}
+
+// DoArray is Do sending the calls as a JSON-RPC 2.0 batch request instead, i.e. an array of requests
+// each with an id and an error of its own, rather than a single call of system.multicall.
+func (b *Batch) DoArray(ctx context.Context) error {
+	return b.do(func(calls []batchCall) error {
+		return b.c.CallBatch(ctx, calls)
+	})
+}
+
+func (b *Batch) do(send func(calls []batchCall) error) error {
+	calls := b.calls
+	b.calls = nil
+	if len(calls) == 0 {
+		return nil
+	}
+	err := send(calls)
+	if err != nil {
+		for _, call := range calls {
+			*call.err = err
+		}
+	}
+	return err
+}
+
+// decodeMulticallResult decodes an element of the response of system.multicall:
+// either a one-item array holding the result, or a fault struct, of JSON-RPC or XML-RPC.
+func decodeMulticallResult(result json.RawMessage, reply interface{}) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(result, &values); err == nil {
+		if len(values) != 1 {
+			return fmt.Errorf("multicall result of %d items", len(values))
+		}
+		return json.Unmarshal(values[0], reply)
+	}
+	var fault struct {
+		Code       ErrorCode `json:"code"`
+		Message    string    `json:"message"`
+		XMLCode    ErrorCode `json:"faultCode"`   // XML-RPC
+		XMLMessage string    `json:"faultString"` // XML-RPC
+	}
+	if err := json.Unmarshal(result, &fault); err != nil {
+		return err
+	}
+	e := &Error{Code: fault.Code, Message: fault.Message}
+	if e.Message == "" && e.Code == 0 {
+		e.Code, e.Message = fault.XMLCode, fault.XMLMessage
+	}
+	if e.Message == "" && e.Code == 0 {
+		return errors.New("invalid multicall result: " + string(result))
+	}
+	return e
+}
+
+// AddURI adds a call of aria2.addUri; see Protocol.
+func (b *Batch) AddURI(uris []string, options ...interface{}) *StringCall {
+	call := &StringCall{}
+	if call.Err = checkURIs(uris); call.Err != nil {
+		return call
+	}
+	if call.Err = b.c.validate(ScopeInputFile, options...); call.Err != nil {
+		return call
+	}
+	b.add(aria2AddURI, &call.Result, &call.Err, append([]interface{}{uris}, options...)...)
+	return call
+}
+
+// AddTorrent adds a call of aria2.addTorrent, uploading the torrent read from filename; see Protocol.
+func (b *Batch) AddTorrent(filename string, options ...interface{}) *StringCall {
+	co, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return &StringCall{Err: err}
+	}
+	return b.AddTorrentData(co, nil, options...)
+}
+
+// AddTorrentData adds a call of aria2.addTorrent, uploading data with webSeeds; see Protocol.
+func (b *Batch) AddTorrentData(data []byte, webSeeds []string, options ...interface{}) *StringCall {
+	call := &StringCall{}
+	if call.Err = b.c.validate(ScopeInputFile, options...); call.Err != nil {
+		return call
+	}
+	if webSeeds == nil {
+		webSeeds = []string{}
+	}
+	b.add(aria2AddTorrent, &call.Result, &call.Err, append([]interface{}{base64Data(data), webSeeds}, options...)...)
+	return call
+}
+
+// AddMetalink adds a call of aria2.addMetalink, uploading the metalink read from filename; see Protocol.
+func (b *Batch) AddMetalink(filename string, options ...interface{}) *StringsCall {
+	co, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return &StringsCall{Err: err}
+	}
+	return b.AddMetalinkData(co, options...)
+}
+
+// AddMetalinkData adds a call of aria2.addMetalink, uploading data; see Protocol.
+func (b *Batch) AddMetalinkData(data []byte, options ...interface{}) *StringsCall {
+	call := &StringsCall{}
+	if call.Err = b.c.validate(ScopeInputFile, options...); call.Err != nil {
+		return call
+	}
+	b.add(aria2AddMetalink, &call.Result, &call.Err, append([]interface{}{base64Data(data)}, options...)...)
+	return call
+}
+
+func (b *Batch) stringCall(method string, params ...interface{}) *StringCall {
+	call := &StringCall{}
+	b.add(method, &call.Result, &call.Err, params...)
+	return call
+}
+
+// Remove adds a call of aria2.remove.
+func (b *Batch) Remove(gid string) *StringCall { return b.stringCall(aria2Remove, gid) }
+
+// ForceRemove adds a call of aria2.forceRemove.
+func (b *Batch) ForceRemove(gid string) *StringCall { return b.stringCall(aria2ForceRemove, gid) }
+
+// Pause adds a call of aria2.pause.
+func (b *Batch) Pause(gid string) *StringCall { return b.stringCall(aria2Pause, gid) }
+
+// PauseAll adds a call of aria2.pauseAll.
+func (b *Batch) PauseAll() *StringCall { return b.stringCall(aria2PauseAll) }
+
+// ForcePause adds a call of aria2.forcePause.
+func (b *Batch) ForcePause(gid string) *StringCall { return b.stringCall(aria2ForcePause, gid) }
+
+// ForcePauseAll adds a call of aria2.forcePauseAll.
+func (b *Batch) ForcePauseAll() *StringCall { return b.stringCall(aria2ForcePauseAll) }
+
+// Unpause adds a call of aria2.unpause.
+func (b *Batch) Unpause(gid string) *StringCall { return b.stringCall(aria2Unpause, gid) }
+
+// UnpauseAll adds a call of aria2.unpauseAll.
+func (b *Batch) UnpauseAll() *StringCall { return b.stringCall(aria2UnpauseAll) }
+
+// TellStatus adds a call of aria2.tellStatus.
+func (b *Batch) TellStatus(gid string, keys ...string) *StatusCall {
+	call := &StatusCall{}
+	params := []interface{}{gid}
+	if keys != nil {
+		params = append(params, keys)
+	}
+	b.add(aria2TellStatus, &call.Result, &call.Err, params...)
+	return call
+}
+
+// GetURIs adds a call of aria2.getUris.
+func (b *Batch) GetURIs(gid string) *URIsCall {
+	call := &URIsCall{}
+	b.add(aria2GetURIs, &call.Result, &call.Err, gid)
+	return call
+}
+
+// GetFiles adds a call of aria2.getFiles.
+func (b *Batch) GetFiles(gid string) *FilesCall {
+	call := &FilesCall{}
+	b.add(aria2GetFiles, &call.Result, &call.Err, gid)
+	return call
+}
+
+// GetPeers adds a call of aria2.getPeers.
+func (b *Batch) GetPeers(gid string) *PeersCall {
+	call := &PeersCall{}
+	b.add(aria2GetPeers, &call.Result, &call.Err, gid)
+	return call
+}
+
+// GetServers adds a call of aria2.getServers.
+func (b *Batch) GetServers(gid string) *ServersCall {
+	call := &ServersCall{}
+	b.add(aria2GetServers, &call.Result, &call.Err, gid)
+	return call
+}
+
+// TellActive adds a call of aria2.tellActive.
+func (b *Batch) TellActive(keys ...string) *StatusesCall {
+	call := &StatusesCall{}
+	var params []interface{}
+	if keys != nil {
+		params = append(params, keys)
+	}
+	b.add(aria2TellActive, &call.Result, &call.Err, params...)
+	return call
+}
+
+// TellWaiting adds a call of aria2.tellWaiting.
+func (b *Batch) TellWaiting(offset, num int, keys ...string) *StatusesCall {
+	return b.tellStatuses(aria2TellWaiting, offset, num, keys)
+}
+
+// TellStopped adds a call of aria2.tellStopped.
+func (b *Batch) TellStopped(offset, num int, keys ...string) *StatusesCall {
+	return b.tellStatuses(aria2TellStopped, offset, num, keys)
+}
+
+func (b *Batch) tellStatuses(method string, offset, num int, keys []string) *StatusesCall {
+	call := &StatusesCall{}
+	params := []interface{}{offset, num}
+	if keys != nil {
+		params = append(params, keys)
+	}
+	b.add(method, &call.Result, &call.Err, params...)
+	return call
+}
+
+// ChangePosition adds a call of aria2.changePosition.
+func (b *Batch) ChangePosition(gid string, pos int, how string) *IntCall {
+	call := &IntCall{}
+	b.add(aria2ChangePosition, &call.Result, &call.Err, gid, pos, how)
+	return call
+}
+
+// ChangeURI adds a call of aria2.changeUri.
+func (b *Batch) ChangeURI(gid string, fileindex int, delUris []string, addUris []string, position ...int) *IntsCall {
+	call := &IntsCall{}
+	params := []interface{}{gid, fileindex, delUris, addUris}
+	if position != nil {
+		params = append(params, position[0])
+	}
+	b.add(aria2ChangeURI, &call.Result, &call.Err, params...)
+	return call
+}
+
+// GetOption adds a call of aria2.getOption.
+func (b *Batch) GetOption(gid string) *OptionCall {
+	call := &OptionCall{}
+	b.add(aria2GetOption, &call.Result, &call.Err, gid)
+	return call
+}
+
+// ChangeOption adds a call of aria2.changeOption.
+func (b *Batch) ChangeOption(gid string, option Option) *StringCall {
+	if err := b.c.validate(ScopeDownload, option); err != nil {
+		return &StringCall{Err: err}
+	}
+	params := []interface{}{gid}
+	if option != nil {
+		params = append(params, option)
+	}
+	return b.stringCall(aria2ChangeOption, params...)
+}
+
+// GetGlobalOption adds a call of aria2.getGlobalOption.
+func (b *Batch) GetGlobalOption() *OptionCall {
+	call := &OptionCall{}
+	b.add(aria2GetGlobalOption, &call.Result, &call.Err)
+	return call
+}
+
+// ChangeGlobalOption adds a call of aria2.changeGlobalOption.
+func (b *Batch) ChangeGlobalOption(options Option) *StringCall {
+	if err := b.c.validate(ScopeGlobal, options); err != nil {
+		return &StringCall{Err: err}
+	}
+	return b.stringCall(aria2ChangeGlobalOption, options)
+}
+
+// GetGlobalStat adds a call of aria2.getGlobalStat.
+func (b *Batch) GetGlobalStat() *GlobalStatCall {
+	call := &GlobalStatCall{}
+	b.add(aria2GetGlobalStat, &call.Result, &call.Err)
+	return call
+}
+
+// PurgeDownloadResult adds a call of aria2.purgeDownloadResult.
+func (b *Batch) PurgeDownloadResult() *StringCall { return b.stringCall(aria2PurgeDownloadResult) }
+
+// RemoveDownloadResult adds a call of aria2.removeDownloadResult.
+func (b *Batch) RemoveDownloadResult(gid string) *StringCall {
+	return b.stringCall(aria2RemoveDownloadResult, gid)
+}
+
+// GetVersion adds a call of aria2.getVersion.
+func (b *Batch) GetVersion() *VersionCall {
+	call := &VersionCall{}
+	b.add(aria2GetVersion, &call.Result, &call.Err)
+	return call
+}
+
+// GetSessionInfo adds a call of aria2.getSessionInfo.
+func (b *Batch) GetSessionInfo() *SessionCall {
+	call := &SessionCall{}
+	b.add(aria2GetSessionInfo, &call.Result, &call.Err)
+	return call
+}
+
+// Shutdown adds a call of aria2.shutdown.
+func (b *Batch) Shutdown() *StringCall { return b.stringCall(aria2Shutdown) }
+
+// ForceShutdown adds a call of aria2.forceShutdown.
+func (b *Batch) ForceShutdown() *StringCall { return b.stringCall(aria2ForceShutdown) }
+
+// SaveSession adds a call of aria2.saveSession.
+func (b *Batch) SaveSession() *StringCall { return b.stringCall(aria2SaveSession) }
@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// batchModes are the ways a Batch is sent.
+var batchModes = []struct {
+	name string
+	do   func(b *Batch, ctx context.Context) error
+}{
+	{"Do", (*Batch).Do},
+	{"DoArray", (*Batch).DoArray},
+}
+
+func TestBatch(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	for _, mode := range batchModes {
+		for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+			testBatch(t, mode.name+" "+uri, uri, mode.do)
+		}
+	}
+}
+
+func testBatch(t *testing.T, name, uri string, do func(b *Batch, ctx context.Context) error) {
+	c, err := New(context.Background(), uri, "secret", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := c.Batch()
+	if err := do(b, context.Background()); err != nil {
+		t.Errorf("%s: empty batch = %v", name, err)
+	}
+	add := b.AddURI([]string{targetURL}, Option{}.Pause(true))
+	bad := b.AddURI([]string{targetURL}, Option{"max-downlaod-limit": "1M"})
+	if b.Len() != 1 || !errors.Is(bad.Err, ErrUnknownOption) {
+		t.Errorf("%s: Len() = %d, bad option error %v", name, b.Len(), bad.Err)
+	}
+	if err := do(b, context.Background()); err != nil || add.Err != nil {
+		t.Fatalf("%s: %v, %v", name, err, add.Err)
+	}
+
+	st := b.TellStatus(add.Result, "gid", "status")
+	missing := b.TellStatus("0000000000000000")
+	stat := b.GetGlobalStat()
+	option := b.GetOption(add.Result)
+	version := b.GetVersion()
+	if err := do(b, context.Background()); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if st.Err != nil || st.Result.Gid != add.Result || st.Result.Status != "paused" {
+		t.Errorf("%s: TellStatus = %+v, %v", name, st.Result, st.Err)
+	}
+	var e *Error
+	if !errors.As(missing.Err, &e) || e.Code != 1 {
+		t.Errorf("%s: TellStatus of missing GID = %v, want *Error", name, missing.Err)
+	}
+	if stat.Err != nil || stat.Result.NumWaiting != "1" {
+		t.Errorf("%s: GetGlobalStat = %+v, %v", name, stat.Result, stat.Err)
+	}
+	if option.Err != nil || option.Result["pause"] != "true" {
+		t.Errorf("%s: GetOption = %v, %v", name, option.Result, option.Err)
+	}
+	if version.Err != nil || version.Result.Version == "" {
+		t.Errorf("%s: GetVersion = %+v, %v", name, version.Result, version.Err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("%s: Len() after sending = %d", name, b.Len())
+	}
+
+	rm := b.Remove(add.Result)
+	purge := b.PurgeDownloadResult()
+	if err := do(b, context.Background()); err != nil || rm.Err != nil || rm.Result != add.Result || purge.Err != nil || purge.Result != "OK" {
+		t.Errorf("%s: %v; Remove = %q, %v; PurgeDownloadResult = %q, %v", name, err, rm.Result, rm.Err, purge.Result, purge.Err)
+	}
+	c.Close()
+}
+
+func TestBatchUnauthorized(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	c, err := New(context.Background(), srv.URL, "wrong", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b := c.Batch()
+	version := b.GetVersion()
+	active := b.TellActive()
+	if err := b.Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{version.Err, active.Err} {
+		if e, ok := err.(*Error); !ok || e.Message != "Unauthorized" {
+			t.Errorf("Err = %v, want Unauthorized", err)
+		}
+	}
+}
+
+func TestBatchRoundTripError(t *testing.T) {
+	srv := silentServer()
+	defer srv.Close()
+	c, err := New(context.Background(), "ws"+srv.URL[len("http"):], "", 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b := c.Batch()
+	st := b.TellStatus("0000000000000001")
+	if err := b.Do(context.Background()); err == nil || st.Err != err {
+		t.Errorf("Do() = %v, Err = %v", err, st.Err)
+	}
+}
+
+func TestBatchArray(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		c, err := New(context.Background(), uri, "", 10*time.Second, nil) // thousands of calls take a while under -race
+		if err != nil {
+			t.Fatal(err)
+		}
+		gid, err := c.AddURI([]string{targetURL}, Option{}.Pause(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := c.Batch()
+		sts := make([]*StatusCall, 2000)
+		for i := range sts {
+			sts[i] = b.TellStatus(gid, "gid")
+		}
+		missing := b.TellStatus("0000000000000000")
+		if err := b.DoArray(context.Background()); err != nil {
+			t.Fatalf("%s: DoArray() = %v", uri, err)
+		}
+		for i, st := range sts {
+			if st.Err != nil || st.Result.Gid != gid {
+				t.Fatalf("%s: TellStatus #%d = %+v, %v", uri, i, st.Result, st.Err)
+			}
+		}
+		if !errors.Is(missing.Err, ErrGIDNotFound) {
+			t.Errorf("%s: TellStatus of missing GID = %v, want %v", uri, missing.Err, ErrGIDNotFound)
+		}
+		c.Close()
+	}
+
+	silent := silentServer()
+	defer silent.Close()
+	c, err := New(context.Background(), "ws"+silent.URL[len("http"):], "", 50*time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b := c.Batch()
+	st := b.TellStatus("0000000000000001")
+	if err := b.DoArray(context.Background()); err == nil || st.Err != err {
+		t.Errorf("DoArray() = %v, Err = %v", err, st.Err)
+	}
+}
+
+func TestBatchArrayFault(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL} {
+		c, err := New(context.Background(), uri, "", 0, nil) // no call timeout: a lost response blocks for good
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		srv.FailBatches(ariatest.BatchNullID)
+		b := c.Batch()
+		version, stat := b.GetVersion(), b.GetGlobalStat()
+		if err = b.DoArray(ctx); err != nil || version.Err != nil || stat.Err != errNoBatchResponse {
+			t.Errorf("%s: DoArray() with a response lacking an id = %v, %v, %v; want %v", uri, err, version.Err, stat.Err, errNoBatchResponse)
+		}
+
+		srv.FailBatches(ariatest.BatchReject)
+		b = c.Batch()
+		version = b.GetVersion()
+		if err = b.DoArray(ctx); version.Err != err {
+			t.Errorf("%s: DoArray() = %v, Err = %v", uri, err, version.Err)
+		}
+		if e, ok := err.(*Error); !ok || e.Code != E_INVALID_REQ {
+			t.Errorf("%s: DoArray() of a rejected batch = %v, want the error of the server", uri, err)
+		}
+
+		srv.FailBatches(ariatest.BatchOK)
+		b = c.Batch()
+		version = b.GetVersion()
+		if err = b.DoArray(ctx); err != nil || version.Err != nil {
+			t.Errorf("%s: DoArray() after faults = %v, %v", uri, err, version.Err)
+		}
+		cancel()
+		c.Close()
+	}
+}
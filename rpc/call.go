@@ -1,12 +1,15 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"log"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"path"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,38 +18,71 @@ import (
 )
 
 type caller interface {
-	// Call sends a request of rpc to aria2 daemon
-	Call(method string, params, reply interface{}) (err error)
+	// Call sends a request of rpc to aria2 daemon; the round trip is abandoned once ctx is done
+	Call(ctx context.Context, method string, params, reply interface{}) (err error)
+	// CallBatch sends calls as a JSON-RPC batch request in a single message, setting the Err of each call;
+	// err is set if the round trip as a whole fails
+	CallBatch(ctx context.Context, calls []batchCall) (err error)
 	Close() error
 }
 
+// batchMethod stands for the method of a JSON-RPC batch request in logs.
+const batchMethod = "batch"
+
+var (
+	// ErrConnLost is returned by calls in flight when the websocket connection to aria2 daemon is lost,
+	// and by every call once the connection cannot be re-established.
+	ErrConnLost = errors.New("connection to aria2 daemon lost")
+	// ErrClosed is returned by calls pending on, or made after, a closed websocket client.
+	ErrClosed = errors.New("client closed")
+)
+
 type httpCaller struct {
-	uri    string
-	c      *http.Client
-	cancel context.CancelFunc
-	wg     *sync.WaitGroup
-	once   sync.Once
+	uri      string
+	c        *http.Client
+	cfg      *clientConfig
+	cancel   context.CancelFunc
+	wg       *sync.WaitGroup
+	once     sync.Once
+	notifies sync.Once
 }
 
-func newHTTPCaller(ctx context.Context, u *url.URL, timeout time.Duration, notifer Notifier) *httpCaller {
-	c := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: 1,
-			MaxConnsPerHost:     1,
-			// TLSClientConfig:     tlsConfig,
-			Dial: (&net.Dialer{
-				Timeout:   timeout,
+func newHTTPCaller(ctx context.Context, u *url.URL, cfg *clientConfig) *httpCaller {
+	c := cfg.httpClient
+	if c == nil {
+		dial := cfg.dial
+		if dial == nil {
+			dial = (&net.Dialer{
+				Timeout:   cfg.timeout,
 				KeepAlive: 60 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   3 * time.Second,
-			ResponseHeaderTimeout: timeout,
-		},
+			}).DialContext
+		}
+		c = &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 cfg.proxy,
+				MaxIdleConnsPerHost:   1,
+				MaxConnsPerHost:       1,
+				TLSClientConfig:       cfg.tlsConfig(),
+				DialContext:           dial,
+				TLSHandshakeTimeout:   3 * time.Second,
+				ResponseHeaderTimeout: cfg.timeout,
+			},
+		}
 	}
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(ctx)
-	h := &httpCaller{uri: u.String(), c: c, cancel: cancel, wg: &wg}
-	if notifer != nil {
-		h.setNotifier(ctx, *u, notifer)
+	h := &httpCaller{uri: u.String(), c: c, cfg: cfg, cancel: cancel, wg: &wg}
+	start := func() {
+		h.notifies.Do(func() {
+			if err := h.setNotifier(ctx, *u, cfg); err != nil {
+				cfg.logger.Log(LevelWarn, "notification stream unavailable", Field{"uri", u.String()}, Field{"err", err})
+			}
+		})
+	}
+	if cfg.notifier != nil {
+		start()
+	} else { // open the notification stream on the first subscription
+		cfg.events.start = start
 	}
 	return h
 }
@@ -59,213 +95,488 @@ func (h *httpCaller) Close() (err error) {
 	return
 }
 
-func (h *httpCaller) setNotifier(ctx context.Context, u url.URL, notifer Notifier) (err error) {
-	u.Scheme = "ws"
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
-	if err != nil {
-		return
+func (h *httpCaller) setNotifier(ctx context.Context, u url.URL, cfg *clientConfig) (err error) {
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
 	}
-	h.wg.Add(1)
-	go func() {
-		defer h.wg.Done()
-		defer conn.Close()
-		select {
-		case <-ctx.Done():
-			conn.SetWriteDeadline(time.Now().Add(time.Second))
-			if err := conn.WriteMessage(websocket.CloseMessage,
-				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
-				log.Printf("sending websocket close message: %v", err)
-			}
+	if cfg.xml { // aria2 sends notifications over JSON-RPC only
+		u.Path = path.Join(path.Dir(u.Path), "jsonrpc")
+	}
+	conn, _, err := cfg.dialer().Dial(u.String(), cfg.header)
+	if err != nil {
+		if !cfg.reconnect {
 			return
 		}
-	}()
+		// keep redialing in background, but tell about e.g. a wrong port or path, which will never succeed
+		cfg.logger.Log(LevelWarn, "websocket dial failed, redialing in background", Field{"uri", u.String()}, Field{"err", err})
+		conn, err = nil, nil
+	}
+	s := &wsSession{uri: u.String(), cfg: cfg}
 	h.wg.Add(1)
 	go func() {
 		defer h.wg.Done()
-		var request websocketResponse
-		var err error
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-			if err = conn.ReadJSON(&request); err != nil {
-				select {
-				case <-ctx.Done():
+		s.serve(ctx, conn, func(ctx context.Context, conn *websocket.Conn) {
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			connCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-connCtx.Done()
+				if ctx.Err() != nil {
+					closeWebsocket(conn, cfg.logger)
+				}
+				conn.Close()
+			}()
+			var request websocketResponse
+			for {
+				if err := conn.ReadJSON(&request); err != nil {
+					if ctx.Err() == nil {
+						cfg.logger.Log(LevelWarn, "websocket read failed", Field{"uri", u.String()}, Field{"err", err})
+					}
 					return
-				default:
 				}
-				log.Printf("conn.ReadJSON|err:%v", err.Error())
-				return
+				cfg.notify(request)
 			}
-			switch request.Method {
-			case "aria2.onDownloadStart":
-				notifer.OnDownloadStart(request.Params)
-			case "aria2.onDownloadPause":
-				notifer.OnDownloadPause(request.Params)
-			case "aria2.onDownloadStop":
-				notifer.OnDownloadStop(request.Params)
-			case "aria2.onDownloadComplete":
-				notifer.OnDownloadComplete(request.Params)
-			case "aria2.onDownloadError":
-				notifer.OnDownloadError(request.Params)
-			case "aria2.onBtDownloadComplete":
-				notifer.OnBtDownloadComplete(request.Params)
-			default:
-				log.Printf("unexpected notification: %s", request.Method)
-			}
-		}
+		})
 	}()
 	return
 }
 
-func (h httpCaller) Call(method string, params, reply interface{}) (err error) {
+func (h *httpCaller) Call(ctx context.Context, method string, params, reply interface{}) (err error) {
+	defer logCall(h.cfg.logger, method, 0, time.Now(), &err)
 	payload, err := EncodeClientRequest(method, params)
 	if err != nil {
 		return
 	}
-	r, err := h.c.Post(h.uri, "application/json", payload)
+	return h.roundTrip(ctx, payload, func(body io.Reader) error {
+		return DecodeClientResponse(body, &reply)
+	})
+}
+
+func (h *httpCaller) CallBatch(ctx context.Context, calls []batchCall) (err error) {
+	defer logCall(h.cfg.logger, batchMethod, 0, time.Now(), &err)
+	reqs := newBatchRequests(calls)
+	payload, err := encodeClientBatch(reqs)
 	if err != nil {
 		return
 	}
-	err = DecodeClientResponse(r.Body, &reply)
-	r.Body.Close()
+	return h.roundTrip(ctx, payload, func(body io.Reader) error {
+		return decodeClientBatch(body, reqs, calls)
+	})
+}
+
+// roundTrip sends payload to aria2 daemon, posted or encoded in a GET request (see WithHTTPGet), and decodes the response body with decode.
+func (h *httpCaller) roundTrip(ctx context.Context, payload *bytes.Buffer, decode func(body io.Reader) error) (err error) {
+	if h.cfg.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.cfg.callTimeout)
+		defer cancel()
+	}
+	var req *http.Request
+	get := h.cfg.get && !h.cfg.xml
+	if get {
+		req, err = newGetRequest(ctx, h.uri, payload, h.cfg.jsonp)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, h.uri, payload)
+	}
+	if err != nil {
+		return
+	}
+	for k, v := range h.cfg.header {
+		req.Header[k] = v
+	}
+	switch {
+	case h.cfg.xml:
+		req.Header.Set("Content-Type", "text/xml")
+	case !get:
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r, err := h.c.Do(req)
+	if err != nil {
+		return
+	}
+	defer r.Body.Close()
+	var body io.Reader = r.Body
+	if h.cfg.maxMsgSize > 0 {
+		body = &limitedReader{r: r.Body, n: h.cfg.maxMsgSize}
+	}
+	if get && h.cfg.jsonp != "" {
+		if body, err = unwrapJSONP(body, h.cfg.jsonp); err != nil {
+			return
+		}
+	}
+	return decode(body)
+}
+
+// errMessageTooLarge is returned by calls whose response exceeds the size set by WithMaxMessageSize.
+var errMessageTooLarge = errors.New("message from aria2 daemon exceeds max message size")
+
+// limitedReader reads from r, failing with errMessageTooLarge past n bytes.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (n int, err error) {
+	if l.n <= 0 {
+		return 0, errMessageTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err = l.r.Read(p)
+	l.n -= int64(n)
 	return
 }
 
+// wsSession keeps a websocket connection to aria2 daemon alive, redialing with backoff when it is lost.
+type wsSession struct {
+	uri string
+	cfg *clientConfig
+}
+
+func (s *wsSession) state(state ConnState) {
+	s.cfg.logger.Log(LevelInfo, "websocket "+state.String(), Field{"uri", s.uri})
+	if s.cfg.onState != nil {
+		s.cfg.onState(state)
+	}
+}
+
+// serve runs fn on conn, then on every re-established connection, until ctx is done or redialing gives up.
+// A nil conn starts by redialing. fn must return once conn is broken or ctx is done, and must close conn.
+func (s *wsSession) serve(ctx context.Context, conn *websocket.Conn, fn func(ctx context.Context, conn *websocket.Conn)) {
+	defer s.state(StateClosed)
+	for {
+		if conn == nil {
+			s.state(StateReconnecting)
+			if conn = s.redial(ctx); conn == nil {
+				return
+			}
+		}
+		if s.cfg.maxMsgSize > 0 {
+			conn.SetReadLimit(s.cfg.maxMsgSize)
+		}
+		s.state(StateConnected)
+		fn(ctx, conn)
+		if ctx.Err() != nil || !s.cfg.reconnect {
+			return
+		}
+		conn = nil
+	}
+}
+
+func (s *wsSession) redial(ctx context.Context) *websocket.Conn {
+	b := s.cfg.backoff
+	for attempt := 0; b.MaxAttempts <= 0 || attempt < b.MaxAttempts; attempt++ {
+		t := time.NewTimer(b.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+		conn, _, err := s.cfg.dialer().DialContext(ctx, s.uri, s.cfg.header)
+		if err == nil {
+			return conn
+		}
+		s.cfg.logger.Log(LevelInfo, "redial failed", Field{"uri", s.uri}, Field{"attempt", attempt + 1}, Field{"err", err})
+	}
+	return nil
+}
+
+func closeWebsocket(conn *websocket.Conn, logger Logger) {
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if err := conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+		logger.Log(LevelDebug, "sending websocket close message failed", Field{"err", err})
+	}
+}
+
 type websocketCaller struct {
-	conn     *websocket.Conn
-	sendChan chan *sendRequest
+	uri      string
+	sendChan chan wsRequest
 	cancel   context.CancelFunc
+	done     <-chan struct{}
 	wg       *sync.WaitGroup
 	once     sync.Once
 	timeout  time.Duration
+	cfg      *clientConfig
+	pending  *pendingCalls
+	mu       sync.Mutex
+	err      error // guarded by mu; why the caller stopped working
 }
 
-func newWebsocketCaller(ctx context.Context, uri string, timeout time.Duration, notifier Notifier) (*websocketCaller, error) {
-	var header = http.Header{}
-	conn, _, err := websocket.DefaultDialer.Dial(uri, header)
+func newWebsocketCaller(ctx context.Context, uri string, cfg *clientConfig) (*websocketCaller, error) {
+	conn, _, err := cfg.dialer().Dial(uri, cfg.header)
 	if err != nil {
 		return nil, err
 	}
 
-	sendChan := make(chan *sendRequest, 16)
+	sendChan := make(chan wsRequest, 16)
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(ctx)
-	w := &websocketCaller{conn: conn, wg: &wg, cancel: cancel, sendChan: sendChan, timeout: timeout}
-	processor := NewResponseProcessor()
+	w := &websocketCaller{
+		uri:      uri,
+		wg:       &wg,
+		cancel:   cancel,
+		done:     ctx.Done(),
+		sendChan: sendChan,
+		timeout:  cfg.timeout,
+		cfg:      cfg,
+		pending:  newPendingCalls(),
+	}
+	s := &wsSession{uri: uri, cfg: cfg}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		s.serve(ctx, conn, w.serve)
+		err := ErrConnLost // redialing gave up
+		if ctx.Err() != nil {
+			err = ErrClosed
+		}
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		err = w.err
+		w.mu.Unlock()
+		w.pending.fail(err, false)
+	}()
+	return w, nil
+}
+
+// serve exchanges requests and responses over conn until it is broken or ctx is done.
+func (w *websocketCaller) serve(ctx context.Context, conn *websocket.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() { // routine:recv
 		defer wg.Done()
 		defer cancel()
 		for {
-			select {
-			case <-ctx.Done():
+			var msg json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				if ctx.Err() == nil {
+					w.cfg.logger.Log(LevelWarn, "websocket read failed", Field{"uri", w.uri}, Field{"err", err})
+				}
 				return
-			default:
+			}
+			if msg = bytes.TrimSpace(msg); len(msg) > 0 && msg[0] == '[' { // responses to a batch request
+				var resps []clientResponse
+				if err := json.Unmarshal(msg, &resps); err != nil {
+					w.cfg.logger.Log(LevelWarn, "invalid batch response", Field{"uri", w.uri}, Field{"err", err})
+					continue
+				}
+				w.pending.resolveBatch(resps)
+				continue
 			}
 			var resp websocketResponse
-			if err := conn.ReadJSON(&resp); err != nil {
-				select {
-				case <-ctx.Done():
-					return
-				default:
+			if err := json.Unmarshal(msg, &resp); err != nil {
+				w.cfg.logger.Log(LevelWarn, "invalid response", Field{"uri", w.uri}, Field{"err", err})
+				continue
+			}
+			if resp.Id == nil && resp.Method == "" && resp.Error != nil { // error answering a batch as a whole
+				if !w.pending.failBatch(resp.decode(nil)) {
+					w.cfg.logger.Log(LevelWarn, "error response without id", Field{"uri", w.uri}, Field{"err", resp.decode(nil)})
 				}
-				log.Printf("conn.ReadJSON|err:%v", err.Error())
-				return
+				continue
 			}
 			if resp.Id == nil { // RPC notifications
-				if notifier != nil {
-					switch resp.Method {
-					case "aria2.onDownloadStart":
-						notifier.OnDownloadStart(resp.Params)
-					case "aria2.onDownloadPause":
-						notifier.OnDownloadPause(resp.Params)
-					case "aria2.onDownloadStop":
-						notifier.OnDownloadStop(resp.Params)
-					case "aria2.onDownloadComplete":
-						notifier.OnDownloadComplete(resp.Params)
-					case "aria2.onDownloadError":
-						notifier.OnDownloadError(resp.Params)
-					case "aria2.onBtDownloadComplete":
-						notifier.OnBtDownloadComplete(resp.Params)
-					default:
-						log.Printf("unexpected notification: %s", resp.Method)
-					}
-				}
+				w.cfg.notify(resp)
 				continue
 			}
-			processor.Process(resp.clientResponse)
+			w.pending.resolve(resp.clientResponse)
 		}
 	}()
-	wg.Add(1)
-	go func() { // routine:send
-		defer wg.Done()
-		defer cancel()
-		defer w.conn.Close()
 
+	func() { // routine:send
 		for {
 			select {
-			case <-ctx.Done():
-				if err := w.conn.WriteMessage(websocket.CloseMessage,
-					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
-					log.Printf("sending websocket close message: %v", err)
+			case <-connCtx.Done():
+				if ctx.Err() != nil {
+					closeWebsocket(conn, w.cfg.logger)
 				}
 				return
-			case req := <-sendChan:
-				processor.Add(req.request.Id, func(resp clientResponse) error {
-					err := resp.decode(req.reply)
-					req.cancel()
-					return err
-				})
-				w.conn.SetWriteDeadline(time.Now().Add(timeout))
-				w.conn.WriteJSON(req.request)
+			case req := <-w.sendChan:
+				msg := req.message(w.pending)
+				if msg == nil {
+					continue // abandoned by its caller
+				}
+				if w.timeout > 0 {
+					conn.SetWriteDeadline(time.Now().Add(w.timeout))
+				}
+				if err := conn.WriteJSON(msg); err != nil {
+					w.cfg.logger.Log(LevelWarn, "websocket write failed", Field{"uri", w.uri}, Field{"method", req.method()}, Field{"err", err})
+					cancel()
+				}
 			}
 		}
 	}()
-
-	return w, nil
+	conn.Close()
+	wg.Wait()
+	if ctx.Err() == nil {
+		w.pending.fail(ErrConnLost, true)
+	}
 }
 
+// Close closes the connection; calls still pending fail with ErrClosed.
 func (w *websocketCaller) Close() (err error) {
 	w.once.Do(func() {
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = ErrClosed
+		}
+		w.mu.Unlock()
 		w.cancel()
 		w.wg.Wait()
 	})
 	return
 }
 
-func (w websocketCaller) Call(method string, params, reply interface{}) (err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
-	defer cancel()
+func (w *websocketCaller) stopped() error {
 	select {
-	case w.sendChan <- &sendRequest{cancel: cancel, request: &clientRequest{
+	case <-w.done:
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.err == nil {
+			return ErrClosed
+		}
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// wsRequest is a message to write to the websocket connection: a request, or a batch of them.
+type wsRequest struct {
+	reqs  []*clientRequest
+	batch bool
+}
+
+// message returns what to write for the requests still pending, marking them sent; nil if none is.
+func (r wsRequest) message(pending *pendingCalls) interface{} {
+	reqs := make([]*clientRequest, 0, len(r.reqs))
+	for _, req := range r.reqs {
+		if pending.markSent(req.Id) {
+			reqs = append(reqs, req)
+		}
+	}
+	switch {
+	case len(reqs) == 0:
+		return nil
+	case r.batch:
+		ids := make([]uint64, len(reqs))
+		for i, req := range reqs {
+			ids[i] = req.Id
+		}
+		pending.sentBatch(ids)
+		return reqs
+	}
+	return reqs[0]
+}
+
+func (r wsRequest) method() string {
+	if r.batch {
+		return batchMethod
+	}
+	return r.reqs[0].Method
+}
+
+func (w *websocketCaller) Call(ctx context.Context, method string, params, reply interface{}) (err error) {
+	if err = w.stopped(); err != nil {
+		return
+	}
+	id := reqid()
+	defer logCall(w.cfg.logger, method, id, time.Now(), &err)
+	if w.cfg.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.callTimeout)
+		defer cancel()
+	}
+	req := &clientRequest{
 		Version: "2.0",
 		Method:  method,
 		Params:  params,
-		Id:      reqid(),
-	}, reply: reply}:
+		Id:      id,
+	}
+	result := w.pending.add(req.Id)
+	defer w.pending.remove(req.Id)
+	select {
+	case w.sendChan <- wsRequest{reqs: []*clientRequest{req}}:
+	case r := <-result: // failed while waiting to be sent
+		return r.err
+	case <-w.done:
+		return w.stopped()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	default:
-		return errors.New("sending channel blocking")
+	select {
+	case r := <-result:
+		if r.err != nil {
+			return r.err
+		}
+		return r.resp.decode(reply)
+	case <-w.done:
+		return w.stopped()
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+func (w *websocketCaller) CallBatch(ctx context.Context, calls []batchCall) (err error) {
+	if err = w.stopped(); err != nil {
+		return
+	}
+	defer logCall(w.cfg.logger, batchMethod, 0, time.Now(), &err)
+	if w.cfg.callTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.callTimeout)
+		defer cancel()
+	}
+	reqs := newBatchRequests(calls)
+	results := make([]<-chan callResult, len(reqs))
+	for i, req := range reqs {
+		results[i] = w.pending.add(req.Id)
+		defer w.pending.remove(req.Id)
+	}
 	select {
+	case w.sendChan <- wsRequest{reqs: reqs, batch: true}:
+	case r := <-results[0]: // failed while waiting to be sent
+		return r.err
+	case <-w.done:
+		return w.stopped()
 	case <-ctx.Done():
-		if err := ctx.Err(); err == context.DeadlineExceeded {
-			return err
+		return ctx.Err()
+	}
+
+	resps := make([]clientResponse, 0, len(reqs))
+	for _, result := range results {
+		select {
+		case r := <-result:
+			if r.err == errNoBatchResponse { // left unanswered by the response to the batch; see resolveBatch
+				continue
+			}
+			if r.err != nil {
+				return r.err
+			}
+			resps = append(resps, r.resp)
+		case <-w.done:
+			return w.stopped()
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
+	resolveBatch(resps, reqs, calls)
 	return
 }
 
-type sendRequest struct {
-	cancel  context.CancelFunc
-	request *clientRequest
-	reply   interface{}
-}
-
 var reqid = func() func() uint64 {
 	var id = uint64(time.Now().UnixNano())
 	return func() uint64 {
@@ -2,22 +2,251 @@ package rpc
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zyxar/argo/rpc/ariatest"
 )
 
 func TestWebsocketCaller(t *testing.T) {
-	time.Sleep(time.Second)
-	c, err := newWebsocketCaller(context.Background(), "ws://localhost:6800/jsonrpc", time.Second, &DummyNotifier{})
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := newWebsocketCaller(context.Background(), srv.WebsocketURL, newClientConfig(time.Second, &DummyNotifier{}))
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 	defer c.Close()
 
 	var info VersionInfo
-	if err := c.Call(aria2GetVersion, []interface{}{}, &info); err != nil {
+	if err := c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info); err != nil {
 		t.Error(err.Error())
-	} else {
-		println(info.Version)
+	} else if info.Version != ariatest.Version {
+		t.Errorf("Version = %q, want %q", info.Version, ariatest.Version)
+	}
+}
+
+func TestHTTPCallerContextCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := newHTTPCaller(context.Background(), u, newClientConfig(10*time.Second, nil))
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var info VersionInfo
+	if err := c.Call(ctx, aria2GetVersion, []interface{}{}, &info); !errors.Is(err, context.Canceled) {
+		t.Errorf("Call() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWebsocketCallerContextDeadline(t *testing.T) {
+	srv := silentServer()
+	defer srv.Close()
+	c, err := newWebsocketCaller(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), newClientConfig(10*time.Second, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var info VersionInfo
+	if err := c.Call(ctx, aria2GetVersion, []interface{}{}, &info); err != context.DeadlineExceeded {
+		t.Errorf("Call() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := len(c.pending.calls); n != 0 {
+		t.Errorf("%d calls still pending after timeout", n)
+	}
+}
+
+func silentServer() *httptest.Server {
+	var upgrader websocket.Upgrader
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for { // never reply
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestWebsocketCallerError(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := newWebsocketCaller(context.Background(), srv.WebsocketURL, newClientConfig(time.Second, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var info StatusInfo
+	err = c.Call(context.Background(), aria2TellStatus, []interface{}{"0000000000000001"}, &info)
+	if e, ok := err.(*Error); !ok || e.Code != 1 {
+		t.Errorf("Call() = %#v, want *Error with code 1", err)
+	}
+}
+
+func TestWebsocketCallerClose(t *testing.T) {
+	srv := silentServer()
+	defer srv.Close()
+	c, err := newWebsocketCaller(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), newClientConfig(10*time.Second, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		var info VersionInfo
+		errc <- c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info)
+	}()
+	for { // wait until the request is on the wire
+		c.pending.mu.Lock()
+		sent := false
+		for _, call := range c.pending.calls {
+			sent = call.sent
+		}
+		c.pending.mu.Unlock()
+		if sent {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	if err := <-errc; err != ErrClosed {
+		t.Errorf("pending Call() = %v, want %v", err, ErrClosed)
+	}
+	var info VersionInfo
+	if err := c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info); err != ErrClosed {
+		t.Errorf("Call() after Close = %v, want %v", err, ErrClosed)
+	}
+}
+
+type chanNotifier chan string
+
+func (n chanNotifier) send(method string, events []Event) {
+	for _, e := range events {
+		n <- method + ":" + e.Gid
+	}
+}
+
+func (n chanNotifier) OnDownloadStart(events []Event)      { n.send("start", events) }
+func (n chanNotifier) OnDownloadPause(events []Event)      { n.send("pause", events) }
+func (n chanNotifier) OnDownloadStop(events []Event)       { n.send("stop", events) }
+func (n chanNotifier) OnDownloadComplete(events []Event)   { n.send("complete", events) }
+func (n chanNotifier) OnDownloadError(events []Event)      { n.send("error", events) }
+func (n chanNotifier) OnBtDownloadComplete(events []Event) { n.send("btcomplete", events) }
+
+func waitState(t *testing.T, states <-chan ConnState, want ConnState) {
+	t.Helper()
+	for {
+		select {
+		case s := <-states:
+			if s == want {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for state %s", want)
+		}
+	}
+}
+
+var testBackoff = Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}
+
+func TestWebsocketCallerReconnect(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	states := make(chan ConnState, 16)
+	notifier := make(chanNotifier, 16)
+	cfg := newClientConfig(time.Second, notifier, WithReconnect(testBackoff), WithConnStateHandler(func(s ConnState) { states <- s }))
+	c, err := newWebsocketCaller(context.Background(), srv.WebsocketURL, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, states, StateConnected)
+
+	srv.CloseClientConnections()
+	waitState(t, states, StateReconnecting)
+	waitState(t, states, StateConnected)
+	var info VersionInfo
+	if err := c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info); err != nil {
+		t.Fatal(err)
+	}
+	srv.Notify("aria2.onDownloadStart", "0000000000000001")
+	if got := <-notifier; got != "start:0000000000000001" {
+		t.Errorf("notification = %q", got)
+	}
+	c.Close()
+	waitState(t, states, StateClosed)
+}
+
+func TestWebsocketCallerConnLost(t *testing.T) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.ReadMessage()
+		conn.Close() // drop the connection with the request in flight
+	}))
+	states := make(chan ConnState, 16)
+	cfg := newClientConfig(time.Second, nil, WithReconnect(Backoff{Initial: 10 * time.Millisecond, MaxAttempts: 2}), WithConnStateHandler(func(s ConnState) { states <- s }))
+	c, err := newWebsocketCaller(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var info VersionInfo
+	if err := c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info); err != ErrConnLost {
+		t.Errorf("Call() = %v, want %v", err, ErrConnLost)
+	}
+
+	srv.Close() // redialing gives up
+	waitState(t, states, StateClosed)
+	if err := c.Call(context.Background(), aria2GetVersion, []interface{}{}, &info); err != ErrConnLost {
+		t.Errorf("Call() after giving up = %v, want %v", err, ErrConnLost)
+	}
+}
+
+func TestHTTPCallerNotifierReconnect(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	states := make(chan ConnState, 16)
+	notifier := make(chanNotifier, 16)
+	u, _ := url.Parse(srv.URL)
+	c := newHTTPCaller(context.Background(), u, newClientConfig(time.Second, notifier, WithReconnect(testBackoff), WithConnStateHandler(func(s ConnState) { states <- s })))
+	defer c.Close()
+	waitState(t, states, StateConnected)
+
+	srv.CloseClientConnections()
+	waitState(t, states, StateReconnecting)
+	waitState(t, states, StateConnected)
+	deadline := time.After(time.Second)
+	for { // the server may register the new connection after the handshake completes
+		srv.Notify("aria2.onDownloadComplete", "0000000000000002")
+		select {
+		case got := <-notifier:
+			if got != "complete:0000000000000002" {
+				t.Errorf("notification = %q", got)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no notification after reconnecting")
+		case <-time.After(20 * time.Millisecond):
+		}
 	}
 }
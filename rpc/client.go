@@ -2,25 +2,46 @@ package rpc
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/url"
+	"path"
+	"strings"
 	"time"
+
+	"github.com/zyxar/argo/magnet"
 )
 
-// Option is a container for specifying Call parameters and returning results
+// Option is a container for specifying Call parameters and returning results.
+// Options sent to aria2 are built with its typed setters, e.g. Option{}.Dir("/tmp").Split(4),
+// and validated against OptionCatalog before they are sent.
 type Option map[string]interface{}
 
 type Client interface {
 	Protocol
+	ContextProtocol
+	// Subscribe returns a channel of download events selected by filter.
+	// Events are buffered per subscriber; filter.Overflow decides what to drop when the buffer is full.
+	// The channel is closed when ctx is done or the client is closed.
+	Subscribe(ctx context.Context, filter EventFilter) <-chan DownloadEvent
+	// Wait blocks until the download of gid is complete, failed or removed, and returns its final status;
+	// a download ending in error or removed yields a *DownloadError.
+	Wait(ctx context.Context, gid string) (info StatusInfo, err error)
+	// WaitAll is like Wait for several downloads; it returns once all of them reached a final status.
+	WaitAll(ctx context.Context, gids ...string) (infos []StatusInfo, err error)
+	// Batch returns an empty batch of calls, sent in a single round trip by its Do method.
+	Batch() *Batch
 	Close() error
 }
 
 type client struct {
 	caller
-	url   *url.URL
-	token string
+	url          *url.URL
+	token        string
+	events       *eventHub
+	unchecked    bool
+	pollInterval time.Duration
 }
 
 var (
@@ -29,28 +50,83 @@ var (
 	errConnTimeout      = errors.New("connect to aria2 daemon timeout")
 )
 
-// New returns an instance of Client
-func New(ctx context.Context, uri string, token string, timeout time.Duration, notifier Notifier) (Client, error) {
+// ErrMagnetMixed is returned by AddURI, before anything is sent to aria2 daemon, for uris having a magnet URI among others.
+var ErrMagnetMixed = errors.New("magnet uri mixed with other uris")
+
+// checkURIs reports whether uris may be added as one download: a magnet URI must be the only one.
+func checkURIs(uris []string) error {
+	if len(uris) < 2 {
+		return nil
+	}
+	for _, uri := range uris {
+		if magnet.IsMagnet(uri) {
+			return ErrMagnetMixed
+		}
+	}
+	return nil
+}
+
+// New returns an instance of Client; it is NewWithOptions with WithToken(token), WithTimeout(timeout) and WithNotifier(notifier) before options.
+func New(ctx context.Context, uri string, token string, timeout time.Duration, notifier Notifier, options ...ClientOption) (Client, error) {
+	return NewWithOptions(ctx, uri, append([]ClientOption{WithToken(token), WithTimeout(timeout), WithNotifier(notifier)}, options...)...)
+}
+
+// NewWithOptions returns an instance of Client talking to aria2 daemon at uri, of scheme http, https, ws or wss.
+// Schemes http+get and https+get are http and https with WithHTTPGet(""); a uri of path /rpc, as in http://localhost:6800/rpc,
+// implies WithXMLRPC.
+// The client is closed when ctx is done.
+func NewWithOptions(ctx context.Context, uri string, options ...ClientOption) (Client, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
 	}
+	if u.Scheme == "http+get" || u.Scheme == "https+get" {
+		options = append([]ClientOption{WithHTTPGet("")}, options...)
+		v := *u
+		v.Scheme = strings.TrimSuffix(u.Scheme, "+get")
+		u = &v
+	}
+	cfg := newClientConfig(DefaultTimeout, nil, options...)
 	var caller caller
 	switch u.Scheme {
 	case "http", "https":
-		caller = newHTTPCaller(ctx, u, timeout, notifier)
+		if cfg.xml || path.Base(u.Path) == "rpc" {
+			cfg.xml = true
+			caller = xmlCaller{newHTTPCaller(ctx, u, cfg)}
+			break
+		}
+		caller = newHTTPCaller(ctx, u, cfg)
 	case "ws", "wss":
-		caller, err = newWebsocketCaller(ctx, u.String(), timeout, notifier)
+		caller, err = newWebsocketCaller(ctx, u.String(), cfg)
 		if err != nil {
 			return nil, err
 		}
 	default:
 		return nil, errInvalidParameter
 	}
-	c := &client{caller: caller, url: u, token: token}
+	caller = newInterceptedCaller(caller, cfg.interceptors)
+	c := &client{caller: caller, url: u, token: cfg.token, events: cfg.events, unchecked: cfg.unchecked, pollInterval: cfg.poll}
 	return c, nil
 }
 
+// validate validates the options among args for scope, unless the client was created WithoutOptionValidation.
+func (c *client) validate(scope OptionScope, args ...interface{}) error {
+	if c.unchecked {
+		return nil
+	}
+	return validateOptions(scope, args...)
+}
+
+func (c *client) Subscribe(ctx context.Context, filter EventFilter) <-chan DownloadEvent {
+	return c.events.subscribe(ctx, filter)
+}
+
+func (c *client) Close() error {
+	err := c.caller.Close()
+	c.events.close()
+	return err
+}
+
 // `aria2.addUri([secret, ]uris[, options[, position]])`
 // This method adds a new download. uris is an array of HTTP/FTP/SFTP/BitTorrent URIs (strings) pointing to the same resource.
 // If you mix URIs pointing to different resources, then the download may fail or be corrupted without aria2 complaining.
@@ -61,6 +137,17 @@ func New(ctx context.Context, uri string, token string, timeout time.Duration, n
 // If position is omitted or position is larger than the current size of the queue, the new download is appended to the end of the queue.
 // This method returns the GID of the newly registered download.
 func (c *client) AddURI(uris []string, options ...interface{}) (gid string, err error) {
+	return c.AddURIContext(context.Background(), uris, options...)
+}
+
+// AddURIContext is like AddURI but carries ctx through the round trip to aria2.
+func (c *client) AddURIContext(ctx context.Context, uris []string, options ...interface{}) (gid string, err error) {
+	if err = checkURIs(uris); err != nil {
+		return
+	}
+	if err = c.validate(ScopeInputFile, options...); err != nil {
+		return
+	}
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -69,7 +156,7 @@ func (c *client) AddURI(uris []string, options ...interface{}) (gid string, err
 	if options != nil {
 		params = append(params, options...)
 	}
-	err = c.Call(aria2AddURI, params, &gid)
+	err = c.Call(ctx, aria2AddURI, params, &gid)
 	return
 }
 
@@ -89,24 +176,62 @@ func (c *client) AddURI(uris []string, options ...interface{}) (gid string, err
 // If a file with the same name already exists, it is overwritten!
 // If the file cannot be saved successfully or --rpc-save-upload-metadata is false, the downloads added by this method are not saved by --save-session.
 func (c *client) AddTorrent(filename string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentContext(context.Background(), filename, options...)
+}
+
+// AddTorrentContext is like AddTorrent but carries ctx through the round trip to aria2.
+func (c *client) AddTorrentContext(ctx context.Context, filename string, options ...interface{}) (gid string, err error) {
+	if err = c.validate(ScopeInputFile, options...); err != nil {
+		return
+	}
 	co, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return
 	}
-	file := base64.StdEncoding.EncodeToString(co)
+	return c.AddTorrentDataContext(ctx, co, nil, options...)
+}
+
+// AddTorrentData is like AddTorrent but uploads data, the contents of a ".torrent" file,
+// with webSeeds, the URIs of the web-seeding of the torrent; webSeeds may be nil.
+func (c *client) AddTorrentData(data []byte, webSeeds []string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentDataContext(context.Background(), data, webSeeds, options...)
+}
+
+// AddTorrentDataContext is like AddTorrentData but carries ctx through the round trip to aria2.
+func (c *client) AddTorrentDataContext(ctx context.Context, data []byte, webSeeds []string, options ...interface{}) (gid string, err error) {
+	if err = c.validate(ScopeInputFile, options...); err != nil {
+		return
+	}
 	params := make([]interface{}, 0, 3)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	params = append(params, file)
-	params = append(params, []interface{}{})
+	params = append(params, base64Data(data))
+	if webSeeds == nil {
+		webSeeds = []string{}
+	}
+	params = append(params, webSeeds)
 	if options != nil {
 		params = append(params, options...)
 	}
-	err = c.Call(aria2AddTorrent, params, &gid)
+	err = c.Call(ctx, aria2AddTorrent, params, &gid)
 	return
 }
 
+// AddTorrentReader is like AddTorrentData but uploads the ".torrent" file read from r until EOF.
+func (c *client) AddTorrentReader(r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentReaderContext(context.Background(), r, webSeeds, options...)
+}
+
+// AddTorrentReaderContext is like AddTorrentReader but carries ctx through the round trip to aria2.
+func (c *client) AddTorrentReaderContext(ctx context.Context, r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return c.AddTorrentDataContext(ctx, data, webSeeds, options...)
+}
+
 // `aria2.addMetalink([secret, ]metalink[, options[, position]])`
 // This method adds a Metalink download by uploading a ".metalink" file.
 // metalink is a base64-encoded string which contains the contents of the ".metalink" file.
@@ -120,35 +245,74 @@ func (c *client) AddTorrent(filename string, options ...interface{}) (gid string
 // If a file with the same name already exists, it is overwritten!
 // If the file cannot be saved successfully or --rpc-save-upload-metadata is false, the downloads added by this method are not saved by --save-session.
 func (c *client) AddMetalink(filename string, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkContext(context.Background(), filename, options...)
+}
+
+// AddMetalinkContext is like AddMetalink but carries ctx through the round trip to aria2.
+func (c *client) AddMetalinkContext(ctx context.Context, filename string, options ...interface{}) (gid []string, err error) {
+	if err = c.validate(ScopeInputFile, options...); err != nil {
+		return
+	}
 	co, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return
 	}
-	file := base64.StdEncoding.EncodeToString(co)
+	return c.AddMetalinkDataContext(ctx, co, options...)
+}
+
+// AddMetalinkData is like AddMetalink but uploads data, the contents of a ".metalink" file.
+func (c *client) AddMetalinkData(data []byte, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkDataContext(context.Background(), data, options...)
+}
+
+// AddMetalinkDataContext is like AddMetalinkData but carries ctx through the round trip to aria2.
+func (c *client) AddMetalinkDataContext(ctx context.Context, data []byte, options ...interface{}) (gid []string, err error) {
+	if err = c.validate(ScopeInputFile, options...); err != nil {
+		return
+	}
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	params = append(params, file)
+	params = append(params, base64Data(data))
 	if options != nil {
 		params = append(params, options...)
 	}
-	err = c.Call(aria2AddMetalink, params, &gid)
+	err = c.Call(ctx, aria2AddMetalink, params, &gid)
 	return
 }
 
+// AddMetalinkReader is like AddMetalinkData but uploads the ".metalink" file read from r until EOF.
+func (c *client) AddMetalinkReader(r io.Reader, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkReaderContext(context.Background(), r, options...)
+}
+
+// AddMetalinkReaderContext is like AddMetalinkReader but carries ctx through the round trip to aria2.
+func (c *client) AddMetalinkReaderContext(ctx context.Context, r io.Reader, options ...interface{}) (gid []string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return c.AddMetalinkDataContext(ctx, data, options...)
+}
+
 // `aria2.remove([secret, ]gid)`
 // This method removes the download denoted by gid (string).
 // If the specified download is in progress, it is first stopped.
 // The status of the removed download becomes removed.
 // This method returns GID of removed download.
 func (c *client) Remove(gid string) (g string, err error) {
+	return c.RemoveContext(context.Background(), gid)
+}
+
+// RemoveContext is like Remove but carries ctx through the round trip to aria2.
+func (c *client) RemoveContext(ctx context.Context, gid string) (g string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2Remove, params, &g)
+	err = c.Call(ctx, aria2Remove, params, &g)
 	return
 }
 
@@ -156,12 +320,17 @@ func (c *client) Remove(gid string) (g string, err error) {
 // This method removes the download denoted by gid.
 // This method behaves just like aria2.remove() except that this method removes the download without performing any actions which take time, such as contacting BitTorrent trackers to unregister the download first.
 func (c *client) ForceRemove(gid string) (g string, err error) {
+	return c.ForceRemoveContext(context.Background(), gid)
+}
+
+// ForceRemoveContext is like ForceRemove but carries ctx through the round trip to aria2.
+func (c *client) ForceRemoveContext(ctx context.Context, gid string) (g string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2ForceRemove, params, &g)
+	err = c.Call(ctx, aria2ForceRemove, params, &g)
 	return
 }
 
@@ -173,12 +342,17 @@ func (c *client) ForceRemove(gid string) (g string, err error) {
 // To change status to waiting, use the aria2.unpause() method.
 // This method returns GID of paused download.
 func (c *client) Pause(gid string) (g string, err error) {
+	return c.PauseContext(context.Background(), gid)
+}
+
+// PauseContext is like Pause but carries ctx through the round trip to aria2.
+func (c *client) PauseContext(ctx context.Context, gid string) (g string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2Pause, params, &g)
+	err = c.Call(ctx, aria2Pause, params, &g)
 	return
 }
 
@@ -186,11 +360,16 @@ func (c *client) Pause(gid string) (g string, err error) {
 // This method is equal to calling aria2.pause() for every active/waiting download.
 // This methods returns OK.
 func (c *client) PauseAll() (ok string, err error) {
+	return c.PauseAllContext(context.Background())
+}
+
+// PauseAllContext is like PauseAll but carries ctx through the round trip to aria2.
+func (c *client) PauseAllContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2PauseAll, params, &ok)
+	err = c.Call(ctx, aria2PauseAll, params, &ok)
 	return
 }
 
@@ -198,12 +377,17 @@ func (c *client) PauseAll() (ok string, err error) {
 // This method pauses the download denoted by gid.
 // This method behaves just like aria2.pause() except that this method pauses downloads without performing any actions which take time, such as contacting BitTorrent trackers to unregister the download first.
 func (c *client) ForcePause(gid string) (g string, err error) {
+	return c.ForcePauseContext(context.Background(), gid)
+}
+
+// ForcePauseContext is like ForcePause but carries ctx through the round trip to aria2.
+func (c *client) ForcePauseContext(ctx context.Context, gid string) (g string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2ForcePause, params, &g)
+	err = c.Call(ctx, aria2ForcePause, params, &g)
 	return
 }
 
@@ -211,11 +395,16 @@ func (c *client) ForcePause(gid string) (g string, err error) {
 // This method is equal to calling aria2.forcePause() for every active/waiting download.
 // This methods returns OK.
 func (c *client) ForcePauseAll() (ok string, err error) {
+	return c.ForcePauseAllContext(context.Background())
+}
+
+// ForcePauseAllContext is like ForcePauseAll but carries ctx through the round trip to aria2.
+func (c *client) ForcePauseAllContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2ForcePauseAll, params, &ok)
+	err = c.Call(ctx, aria2ForcePauseAll, params, &ok)
 	return
 }
 
@@ -223,12 +412,17 @@ func (c *client) ForcePauseAll() (ok string, err error) {
 // This method changes the status of the download denoted by gid (string) from paused to waiting, making the download eligible to be restarted.
 // This method returns the GID of the unpaused download.
 func (c *client) Unpause(gid string) (g string, err error) {
+	return c.UnpauseContext(context.Background(), gid)
+}
+
+// UnpauseContext is like Unpause but carries ctx through the round trip to aria2.
+func (c *client) UnpauseContext(ctx context.Context, gid string) (g string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2Unpause, params, &g)
+	err = c.Call(ctx, aria2Unpause, params, &g)
 	return
 }
 
@@ -236,11 +430,16 @@ func (c *client) Unpause(gid string) (g string, err error) {
 // This method is equal to calling aria2.unpause() for every active/waiting download.
 // This methods returns OK.
 func (c *client) UnpauseAll() (ok string, err error) {
+	return c.UnpauseAllContext(context.Background())
+}
+
+// UnpauseAllContext is like UnpauseAll but carries ctx through the round trip to aria2.
+func (c *client) UnpauseAllContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2UnpauseAll, params, &ok)
+	err = c.Call(ctx, aria2UnpauseAll, params, &ok)
 	return
 }
 
@@ -254,6 +453,11 @@ func (c *client) UnpauseAll() (ok string, err error) {
 // The response is a struct and contains following keys. Values are strings.
 // https://aria2.github.io/manual/en/html/aria2c.html#aria2.tellStatus
 func (c *client) TellStatus(gid string, keys ...string) (info StatusInfo, err error) {
+	return c.TellStatusContext(context.Background(), gid, keys...)
+}
+
+// TellStatusContext is like TellStatus but carries ctx through the round trip to aria2.
+func (c *client) TellStatusContext(ctx context.Context, gid string, keys ...string) (info StatusInfo, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -262,22 +466,28 @@ func (c *client) TellStatus(gid string, keys ...string) (info StatusInfo, err er
 	if keys != nil {
 		params = append(params, keys)
 	}
-	err = c.Call(aria2TellStatus, params, &info)
+	err = c.Call(ctx, aria2TellStatus, params, &info)
 	return
 }
 
 // `aria2.getUris([secret, ]gid)`
 // This method returns the URIs used in the download denoted by gid (string).
 // The response is an array of structs and it contains following keys. Values are string.
-// 	uri        URI
-// 	status    'used' if the URI is in use. 'waiting' if the URI is still waiting in the queue.
+//
+//	uri        URI
+//	status    'used' if the URI is in use. 'waiting' if the URI is still waiting in the queue.
 func (c *client) GetURIs(gid string) (infos []URIInfo, err error) {
+	return c.GetURIsContext(context.Background(), gid)
+}
+
+// GetURIsContext is like GetURIs but carries ctx through the round trip to aria2.
+func (c *client) GetURIsContext(ctx context.Context, gid string) (infos []URIInfo, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2GetURIs, params, &infos)
+	err = c.Call(ctx, aria2GetURIs, params, &infos)
 	return
 }
 
@@ -286,12 +496,17 @@ func (c *client) GetURIs(gid string) (infos []URIInfo, err error) {
 // The response is an array of structs which contain following keys. Values are strings.
 // https://aria2.github.io/manual/en/html/aria2c.html#aria2.getFiles
 func (c *client) GetFiles(gid string) (infos []FileInfo, err error) {
+	return c.GetFilesContext(context.Background(), gid)
+}
+
+// GetFilesContext is like GetFiles but carries ctx through the round trip to aria2.
+func (c *client) GetFilesContext(ctx context.Context, gid string) (infos []FileInfo, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2GetFiles, params, &infos)
+	err = c.Call(ctx, aria2GetFiles, params, &infos)
 	return
 }
 
@@ -301,12 +516,17 @@ func (c *client) GetFiles(gid string) (infos []FileInfo, err error) {
 // The response is an array of structs and contains the following keys. Values are strings.
 // https://aria2.github.io/manual/en/html/aria2c.html#aria2.getPeers
 func (c *client) GetPeers(gid string) (infos []PeerInfo, err error) {
+	return c.GetPeersContext(context.Background(), gid)
+}
+
+// GetPeersContext is like GetPeers but carries ctx through the round trip to aria2.
+func (c *client) GetPeersContext(ctx context.Context, gid string) (infos []PeerInfo, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2GetPeers, params, &infos)
+	err = c.Call(ctx, aria2GetPeers, params, &infos)
 	return
 }
 
@@ -315,12 +535,17 @@ func (c *client) GetPeers(gid string) (infos []PeerInfo, err error) {
 // The response is an array of structs and contains the following keys. Values are strings.
 // https://aria2.github.io/manual/en/html/aria2c.html#aria2.getServers
 func (c *client) GetServers(gid string) (infos []ServerInfo, err error) {
+	return c.GetServersContext(context.Background(), gid)
+}
+
+// GetServersContext is like GetServers but carries ctx through the round trip to aria2.
+func (c *client) GetServersContext(ctx context.Context, gid string) (infos []ServerInfo, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2GetServers, params, &infos)
+	err = c.Call(ctx, aria2GetServers, params, &infos)
 	return
 }
 
@@ -329,6 +554,11 @@ func (c *client) GetServers(gid string) (infos []ServerInfo, err error) {
 // The response is an array of the same structs as returned by the aria2.tellStatus() method.
 // For the keys parameter, please refer to the aria2.tellStatus() method.
 func (c *client) TellActive(keys ...string) (infos []StatusInfo, err error) {
+	return c.TellActiveContext(context.Background(), keys...)
+}
+
+// TellActiveContext is like TellActive but carries ctx through the round trip to aria2.
+func (c *client) TellActiveContext(ctx context.Context, keys ...string) (infos []StatusInfo, err error) {
 	params := make([]interface{}, 0, 1)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -336,7 +566,7 @@ func (c *client) TellActive(keys ...string) (infos []StatusInfo, err error) {
 	if keys != nil {
 		params = append(params, keys)
 	}
-	err = c.Call(aria2TellActive, params, &infos)
+	err = c.Call(ctx, aria2TellActive, params, &infos)
 	return
 }
 
@@ -354,6 +584,11 @@ func (c *client) TellActive(keys ...string) (infos []StatusInfo, err error) {
 // aria2.tellWaiting(-1, 2) returns ["C", "B"].
 // The response is an array of the same structs as returned by aria2.tellStatus() method.
 func (c *client) TellWaiting(offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.TellWaitingContext(context.Background(), offset, num, keys...)
+}
+
+// TellWaitingContext is like TellWaiting but carries ctx through the round trip to aria2.
+func (c *client) TellWaitingContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error) {
 	params := make([]interface{}, 0, 3)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -363,7 +598,7 @@ func (c *client) TellWaiting(offset, num int, keys ...string) (infos []StatusInf
 	if keys != nil {
 		params = append(params, keys)
 	}
-	err = c.Call(aria2TellWaiting, params, &infos)
+	err = c.Call(ctx, aria2TellWaiting, params, &infos)
 	return
 }
 
@@ -375,6 +610,11 @@ func (c *client) TellWaiting(offset, num int, keys ...string) (infos []StatusInf
 // offset and num have the same semantics as described in the aria2.tellWaiting() method.
 // The response is an array of the same structs as returned by the aria2.tellStatus() method.
 func (c *client) TellStopped(offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.TellStoppedContext(context.Background(), offset, num, keys...)
+}
+
+// TellStoppedContext is like TellStopped but carries ctx through the round trip to aria2.
+func (c *client) TellStoppedContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error) {
 	params := make([]interface{}, 0, 3)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -384,7 +624,7 @@ func (c *client) TellStopped(offset, num int, keys ...string) (infos []StatusInf
 	if keys != nil {
 		params = append(params, keys)
 	}
-	err = c.Call(aria2TellStopped, params, &infos)
+	err = c.Call(ctx, aria2TellStopped, params, &infos)
 	return
 }
 
@@ -398,6 +638,11 @@ func (c *client) TellStopped(offset, num int, keys ...string) (infos []StatusInf
 // The response is an integer denoting the resulting position.
 // For example, if GID#2089b05ecca3d829 is currently in position 3, aria2.changePosition('2089b05ecca3d829', -1, 'POS_CUR') will change its position to 2. Additionally aria2.changePosition('2089b05ecca3d829', 0, 'POS_SET') will change its position to 0 (the beginning of the queue).
 func (c *client) ChangePosition(gid string, pos int, how string) (p int, err error) {
+	return c.ChangePositionContext(context.Background(), gid, pos, how)
+}
+
+// ChangePositionContext is like ChangePosition but carries ctx through the round trip to aria2.
+func (c *client) ChangePositionContext(ctx context.Context, gid string, pos int, how string) (p int, err error) {
 	params := make([]interface{}, 0, 3)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -405,7 +650,7 @@ func (c *client) ChangePosition(gid string, pos int, how string) (p int, err err
 	params = append(params, gid)
 	params = append(params, pos)
 	params = append(params, how)
-	err = c.Call(aria2ChangePosition, params, &p)
+	err = c.Call(ctx, aria2ChangePosition, params, &p)
 	return
 }
 
@@ -424,6 +669,11 @@ func (c *client) ChangePosition(gid string, pos int, how string) (p int, err err
 // The first integer is the number of URIs deleted.
 // The second integer is the number of URIs added.
 func (c *client) ChangeURI(gid string, fileindex int, delUris []string, addUris []string, position ...int) (p []int, err error) {
+	return c.ChangeURIContext(context.Background(), gid, fileindex, delUris, addUris, position...)
+}
+
+// ChangeURIContext is like ChangeURI but carries ctx through the round trip to aria2.
+func (c *client) ChangeURIContext(ctx context.Context, gid string, fileindex int, delUris []string, addUris []string, position ...int) (p []int, err error) {
 	params := make([]interface{}, 0, 5)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -435,7 +685,7 @@ func (c *client) ChangeURI(gid string, fileindex int, delUris []string, addUris
 	if position != nil {
 		params = append(params, position[0])
 	}
-	err = c.Call(aria2ChangeURI, params, &p)
+	err = c.Call(ctx, aria2ChangeURI, params, &p)
 	return
 }
 
@@ -445,27 +695,42 @@ func (c *client) ChangeURI(gid string, fileindex int, delUris []string, addUris
 // The values are strings.
 // Note that this method does not return options which have no default value and have not been set on the command-line, in configuration files or RPC methods.
 func (c *client) GetOption(gid string) (m Option, err error) {
+	return c.GetOptionContext(context.Background(), gid)
+}
+
+// GetOptionContext is like GetOption but carries ctx through the round trip to aria2.
+func (c *client) GetOptionContext(ctx context.Context, gid string) (m Option, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2GetOption, params, &m)
+	err = c.Call(ctx, aria2GetOption, params, &m)
 	return
 }
 
 // `aria2.changeOption([secret, ]gid, options)`
 // This method changes options of the download denoted by gid (string) dynamically. options is a struct.
 // The following options are available for active downloads:
-// 	bt-max-peers
-// 	bt-request-peer-speed-limit
-// 	bt-remove-unselected-file
-// 	force-save
-// 	max-download-limit
-// 	max-upload-limit
+//
+//	bt-max-peers
+//	bt-request-peer-speed-limit
+//	bt-remove-unselected-file
+//	force-save
+//	max-download-limit
+//	max-upload-limit
+//
 // For waiting or paused downloads, in addition to the above options, options listed in Input File subsection are available, except for following options: dry-run, metalink-base-uri, parameterized-uri, pause, piece-length and rpc-save-upload-metadata option.
 // This method returns OK for success.
 func (c *client) ChangeOption(gid string, option Option) (ok string, err error) {
+	return c.ChangeOptionContext(context.Background(), gid, option)
+}
+
+// ChangeOptionContext is like ChangeOption but carries ctx through the round trip to aria2.
+func (c *client) ChangeOptionContext(ctx context.Context, gid string, option Option) (ok string, err error) {
+	if err = c.validate(ScopeDownload, option); err != nil {
+		return
+	}
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
@@ -474,7 +739,7 @@ func (c *client) ChangeOption(gid string, option Option) (ok string, err error)
 	if option != nil {
 		params = append(params, option)
 	}
-	err = c.Call(aria2ChangeOption, params, &ok)
+	err = c.Call(ctx, aria2ChangeOption, params, &ok)
 	return
 }
 
@@ -485,11 +750,16 @@ func (c *client) ChangeOption(gid string, option Option) (ok string, err error)
 // Values are strings.
 // Note that this method does not return options which have no default value and have not been set on the command-line, in configuration files or RPC methods. Because global options are used as a template for the options of newly added downloads, the response contains keys returned by the aria2.getOption() method.
 func (c *client) GetGlobalOption() (m Option, err error) {
+	return c.GetGlobalOptionContext(context.Background())
+}
+
+// GetGlobalOptionContext is like GetGlobalOption but carries ctx through the round trip to aria2.
+func (c *client) GetGlobalOptionContext(ctx context.Context) (m Option, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2GetGlobalOption, params, &m)
+	err = c.Call(ctx, aria2GetGlobalOption, params, &m)
 	return
 }
 
@@ -497,48 +767,64 @@ func (c *client) GetGlobalOption() (m Option, err error) {
 // This method changes global options dynamically.
 // options is a struct.
 // The following options are available:
-// 	bt-max-open-files
-// 	download-result
-// 	log
-// 	log-level
-// 	max-concurrent-downloads
-// 	max-download-result
-// 	max-overall-download-limit
-// 	max-overall-upload-limit
-// 	save-cookies
-// 	save-session
-// 	server-stat-of
+//
+//	bt-max-open-files
+//	download-result
+//	log
+//	log-level
+//	max-concurrent-downloads
+//	max-download-result
+//	max-overall-download-limit
+//	max-overall-upload-limit
+//	save-cookies
+//	save-session
+//	server-stat-of
+//
 // In addition, options listed in the Input File subsection are available, except for following options: checksum, index-out, out, pause and select-file.
 // With the log option, you can dynamically start logging or change log file.
 // To stop logging, specify an empty string("") as the parameter value.
 // Note that log file is always opened in append mode.
 // This method returns OK for success.
 func (c *client) ChangeGlobalOption(options Option) (ok string, err error) {
+	return c.ChangeGlobalOptionContext(context.Background(), options)
+}
+
+// ChangeGlobalOptionContext is like ChangeGlobalOption but carries ctx through the round trip to aria2.
+func (c *client) ChangeGlobalOptionContext(ctx context.Context, options Option) (ok string, err error) {
+	if err = c.validate(ScopeGlobal, options); err != nil {
+		return
+	}
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, options)
-	err = c.Call(aria2ChangeGlobalOption, params, &ok)
+	err = c.Call(ctx, aria2ChangeGlobalOption, params, &ok)
 	return
 }
 
 // `aria2.getGlobalStat([secret])`
 // This method returns global statistics such as the overall download and upload speeds.
 // The response is a struct and contains the following keys. Values are strings.
-// 	downloadSpeed      Overall download speed (byte/sec).
-// 	uploadSpeed        Overall upload speed(byte/sec).
-// 	numActive          The number of active downloads.
-// 	numWaiting         The number of waiting downloads.
-// 	numStopped         The number of stopped downloads in the current session.
-//                     This value is capped by the --max-download-result option.
-// 	numStoppedTotal    The number of stopped downloads in the current session and not capped by the --max-download-result option.
+//
+//		downloadSpeed      Overall download speed (byte/sec).
+//		uploadSpeed        Overall upload speed(byte/sec).
+//		numActive          The number of active downloads.
+//		numWaiting         The number of waiting downloads.
+//		numStopped         The number of stopped downloads in the current session.
+//	                    This value is capped by the --max-download-result option.
+//		numStoppedTotal    The number of stopped downloads in the current session and not capped by the --max-download-result option.
 func (c *client) GetGlobalStat() (info GlobalStatInfo, err error) {
+	return c.GetGlobalStatContext(context.Background())
+}
+
+// GetGlobalStatContext is like GetGlobalStat but carries ctx through the round trip to aria2.
+func (c *client) GetGlobalStatContext(ctx context.Context) (info GlobalStatInfo, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2GetGlobalStat, params, &info)
+	err = c.Call(ctx, aria2GetGlobalStat, params, &info)
 	return
 }
 
@@ -546,11 +832,16 @@ func (c *client) GetGlobalStat() (info GlobalStatInfo, err error) {
 // This method purges completed/error/removed downloads to free memory.
 // This method returns OK.
 func (c *client) PurgeDownloadResult() (ok string, err error) {
+	return c.PurgeDownloadResultContext(context.Background())
+}
+
+// PurgeDownloadResultContext is like PurgeDownloadResult but carries ctx through the round trip to aria2.
+func (c *client) PurgeDownloadResultContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2PurgeDownloadResult, params, &ok)
+	err = c.Call(ctx, aria2PurgeDownloadResult, params, &ok)
 	return
 }
 
@@ -558,39 +849,56 @@ func (c *client) PurgeDownloadResult() (ok string, err error) {
 // This method removes a completed/error/removed download denoted by gid from memory.
 // This method returns OK for success.
 func (c *client) RemoveDownloadResult(gid string) (ok string, err error) {
+	return c.RemoveDownloadResultContext(context.Background(), gid)
+}
+
+// RemoveDownloadResultContext is like RemoveDownloadResult but carries ctx through the round trip to aria2.
+func (c *client) RemoveDownloadResultContext(ctx context.Context, gid string) (ok string, err error) {
 	params := make([]interface{}, 0, 2)
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
 	params = append(params, gid)
-	err = c.Call(aria2RemoveDownloadResult, params, &ok)
+	err = c.Call(ctx, aria2RemoveDownloadResult, params, &ok)
 	return
 }
 
 // `aria2.getVersion([secret])`
 // This method returns the version of aria2 and the list of enabled features.
 // The response is a struct and contains following keys.
-// 	version            Version number of aria2 as a string.
-// 	enabledFeatures    List of enabled features. Each feature is given as a string.
+//
+//	version            Version number of aria2 as a string.
+//	enabledFeatures    List of enabled features. Each feature is given as a string.
 func (c *client) GetVersion() (info VersionInfo, err error) {
+	return c.GetVersionContext(context.Background())
+}
+
+// GetVersionContext is like GetVersion but carries ctx through the round trip to aria2.
+func (c *client) GetVersionContext(ctx context.Context) (info VersionInfo, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2GetVersion, params, &info)
+	err = c.Call(ctx, aria2GetVersion, params, &info)
 	return
 }
 
 // `aria2.getSessionInfo([secret])`
 // This method returns session information.
 // The response is a struct and contains following key.
-// 	sessionId    Session ID, which is generated each time when aria2 is invoked.
+//
+//	sessionId    Session ID, which is generated each time when aria2 is invoked.
 func (c *client) GetSessionInfo() (info SessionInfo, err error) {
+	return c.GetSessionInfoContext(context.Background())
+}
+
+// GetSessionInfoContext is like GetSessionInfo but carries ctx through the round trip to aria2.
+func (c *client) GetSessionInfoContext(ctx context.Context) (info SessionInfo, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2GetSessionInfo, params, &info)
+	err = c.Call(ctx, aria2GetSessionInfo, params, &info)
 	return
 }
 
@@ -598,11 +906,16 @@ func (c *client) GetSessionInfo() (info SessionInfo, err error) {
 // This method shutdowns aria2.
 // This method returns OK.
 func (c *client) Shutdown() (ok string, err error) {
+	return c.ShutdownContext(context.Background())
+}
+
+// ShutdownContext is like Shutdown but carries ctx through the round trip to aria2.
+func (c *client) ShutdownContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2Shutdown, params, &ok)
+	err = c.Call(ctx, aria2Shutdown, params, &ok)
 	return
 }
 
@@ -611,11 +924,16 @@ func (c *client) Shutdown() (ok string, err error) {
 // This method behaves like :func:'aria2.shutdown` without performing any actions which take time, such as contacting BitTorrent trackers to unregister downloads first.
 // This method returns OK.
 func (c *client) ForceShutdown() (ok string, err error) {
+	return c.ForceShutdownContext(context.Background())
+}
+
+// ForceShutdownContext is like ForceShutdown but carries ctx through the round trip to aria2.
+func (c *client) ForceShutdownContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2ForceShutdown, params, &ok)
+	err = c.Call(ctx, aria2ForceShutdown, params, &ok)
 	return
 }
 
@@ -623,11 +941,16 @@ func (c *client) ForceShutdown() (ok string, err error) {
 // This method saves the current session to a file specified by the --save-session option.
 // This method returns OK if it succeeds.
 func (c *client) SaveSession() (ok string, err error) {
+	return c.SaveSessionContext(context.Background())
+}
+
+// SaveSessionContext is like SaveSession but carries ctx through the round trip to aria2.
+func (c *client) SaveSessionContext(ctx context.Context) (ok string, err error) {
 	params := []string{}
 	if c.token != "" {
 		params = append(params, "token:"+c.token)
 	}
-	err = c.Call(aria2SaveSession, params, &ok)
+	err = c.Call(ctx, aria2SaveSession, params, &ok)
 	return
 }
 
@@ -639,11 +962,16 @@ func (c *client) SaveSession() (ok string, err error) {
 // This method returns an array of responses.
 // The elements will be either a one-item array containing the return value of the method call or a struct of fault element if an encapsulated method call fails.
 func (c *client) Multicall(methods []Method) (r []interface{}, err error) {
+	return c.MulticallContext(context.Background(), methods)
+}
+
+// MulticallContext is like Multicall but carries ctx through the round trip to aria2.
+func (c *client) MulticallContext(ctx context.Context, methods []Method) (r []interface{}, err error) {
 	if len(methods) == 0 {
 		err = errInvalidParameter
 		return
 	}
-	err = c.Call(aria2Multicall, []interface{}{methods}, &r)
+	err = c.Call(ctx, aria2Multicall, []interface{}{methods}, &r)
 	return
 }
 
@@ -652,6 +980,11 @@ func (c *client) Multicall(methods []Method) (r []interface{}, err error) {
 // Unlike other methods, this method does not require secret token.
 // This is safe because this method jsut returns the available method names.
 func (c *client) ListMethods() (methods []string, err error) {
-	err = c.Call(aria2ListMethods, []string{}, &methods)
+	return c.ListMethodsContext(context.Background())
+}
+
+// ListMethodsContext is like ListMethods but carries ctx through the round trip to aria2.
+func (c *client) ListMethodsContext(ctx context.Context) (methods []string, err error) {
+	err = c.Call(ctx, aria2ListMethods, []string{}, &methods)
 	return
 }
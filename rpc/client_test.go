@@ -1,46 +1,62 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
 )
 
-func TestHTTPAll(t *testing.T) {
-	const targetURL = "https://nodejs.org/dist/index.json"
-	rpc, err := New(context.Background(), "http://localhost:6800/jsonrpc", "", time.Second, &DummyNotifier{})
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer rpc.Close()
+const targetURL = "https://nodejs.org/dist/index.json"
+
+func testAll(t *testing.T, rpc Client) {
 	g, err := rpc.AddURI([]string{targetURL})
 	if err != nil {
 		t.Fatal(err)
 	}
-	println(g)
 	if _, err = rpc.TellActive(); err != nil {
 		t.Error(err)
 	}
 	if _, err = rpc.PauseAll(); err != nil {
 		t.Error(err)
 	}
-	if _, err = rpc.TellStatus(g); err != nil {
+	if info, err := rpc.TellStatus(g); err != nil {
 		t.Error(err)
+	} else if info.Gid != g || info.Status != "paused" {
+		t.Errorf("TellStatus(%q) = %s/%s, want %s/paused", g, info.Gid, info.Status, g)
 	}
-	if _, err = rpc.GetURIs(g); err != nil {
+	if uris, err := rpc.GetURIs(g); err != nil {
 		t.Error(err)
+	} else if len(uris) != 1 || uris[0].URI != targetURL {
+		t.Errorf("GetURIs(%q) = %v", g, uris)
 	}
-	if _, err = rpc.GetFiles(g); err != nil {
+	if files, err := rpc.GetFiles(g); err != nil {
 		t.Error(err)
+	} else if len(files) != 1 || files[0].Index != "1" {
+		t.Errorf("GetFiles(%q) = %v", g, files)
 	}
 	if _, err = rpc.GetPeers(g); err != nil {
 		t.Error(err)
 	}
-	if _, err = rpc.TellActive(); err != nil {
+	if infos, err := rpc.TellActive(); err != nil {
 		t.Error(err)
+	} else if len(infos) != 0 {
+		t.Errorf("TellActive() = %d downloads, want 0", len(infos))
 	}
-	if _, err = rpc.TellWaiting(0, 1); err != nil {
+	if infos, err := rpc.TellWaiting(0, 1); err != nil {
 		t.Error(err)
+	} else if len(infos) != 1 || infos[0].Gid != g {
+		t.Errorf("TellWaiting(0, 1) = %v", infos)
 	}
 	if _, err = rpc.TellStopped(0, 1); err != nil {
 		t.Error(err)
@@ -51,8 +67,10 @@ func TestHTTPAll(t *testing.T) {
 	if _, err = rpc.GetGlobalOption(); err != nil {
 		t.Error(err)
 	}
-	if _, err = rpc.GetGlobalStat(); err != nil {
+	if stat, err := rpc.GetGlobalStat(); err != nil {
 		t.Error(err)
+	} else if stat.NumWaiting != "1" {
+		t.Errorf("GetGlobalStat().NumWaiting = %s, want 1", stat.NumWaiting)
 	}
 	if _, err = rpc.GetSessionInfo(); err != nil {
 		t.Error(err)
@@ -63,63 +81,314 @@ func TestHTTPAll(t *testing.T) {
 	if _, err = rpc.TellActive(); err != nil {
 		t.Error(err)
 	}
+	if infos, err := rpc.TellStopped(0, 1, "gid", "status"); err != nil {
+		t.Error(err)
+	} else if len(infos) != 1 || infos[0].Status != "removed" {
+		t.Errorf("TellStopped(0, 1) = %v", infos)
+	}
+}
+
+func TestHTTPAll(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	rpc, err := New(context.Background(), srv.URL, "", time.Second, &DummyNotifier{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rpc.Close()
+	testAll(t, rpc)
 }
 
 func TestWebsocketAll(t *testing.T) {
-	const targetURL = "https://nodejs.org/dist/index.json"
-	rpc, err := New(context.Background(), "ws://localhost:6800/jsonrpc", "", time.Second, &DummyNotifier{})
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	rpc, err := New(context.Background(), srv.WebsocketURL, "", time.Second, &DummyNotifier{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer rpc.Close()
-	g, err := rpc.AddURI([]string{targetURL})
+	testAll(t, rpc)
+}
+
+func TestXMLRPCAll(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	rpc, err := New(context.Background(), srv.XMLRPCURL, "", time.Second, &DummyNotifier{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	println(g)
-	if _, err = rpc.TellActive(); err != nil {
-		t.Error(err)
+	defer rpc.Close()
+	testAll(t, rpc)
+}
+
+func TestSecret(t *testing.T) {
+	srv := ariatest.NewServer("s3cr3t")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		rpc, err := New(context.Background(), uri, "wrong", time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = rpc.GetVersion(); err == nil || err.Error() != "Unauthorized" {
+			t.Errorf("%s: GetVersion() with wrong secret = %v, want Unauthorized", uri, err)
+		}
+		if _, err = rpc.ListMethods(); err != nil {
+			t.Errorf("%s: ListMethods() = %v", uri, err)
+		}
+		rpc.Close()
+
+		rpc, err = New(context.Background(), uri, "s3cr3t", time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAll(t, rpc)
+		rpc.Close()
 	}
-	if _, err = rpc.PauseAll(); err != nil {
-		t.Error(err)
+}
+
+func TestMulticall(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	rpc, err := New(context.Background(), srv.URL, "", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err = rpc.TellStatus(g); err != nil {
-		t.Error(err)
+	defer rpc.Close()
+	r, err := rpc.Multicall([]Method{
+		{Name: aria2GetVersion},
+		{Name: aria2TellStatus, Params: []interface{}{"0000000000000001"}},
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err = rpc.GetURIs(g); err != nil {
-		t.Error(err)
+	if len(r) != 2 {
+		t.Fatalf("Multicall() = %v, want 2 results", r)
 	}
-	if _, err = rpc.GetFiles(g); err != nil {
-		t.Error(err)
+	if _, ok := r[0].([]interface{}); !ok {
+		t.Errorf("Multicall()[0] = %v, want one-item array", r[0])
 	}
-	if _, err = rpc.GetPeers(g); err != nil {
-		t.Error(err)
+	if _, ok := r[1].(map[string]interface{}); !ok {
+		t.Errorf("Multicall()[1] = %v, want fault struct", r[1])
 	}
-	if _, err = rpc.TellActive(); err != nil {
-		t.Error(err)
+}
+
+type countingTransport struct {
+	n int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// chanLogger forwards the messages of entries at LevelWarn and above.
+type chanLogger chan string
+
+func (l chanLogger) Log(level Level, msg string, fields ...Field) {
+	if level < LevelWarn {
+		return
 	}
-	if _, err = rpc.TellWaiting(0, 1); err != nil {
-		t.Error(err)
+	select {
+	case l <- msg:
+	default:
 	}
-	if _, err = rpc.TellStopped(0, 1); err != nil {
-		t.Error(err)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	srv := ariatest.NewServer("s3cr3t")
+	defer srv.Close()
+	var headers, dials, proxied int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Argo") == "1" {
+			atomic.AddInt32(&headers, 1)
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	options := []ClientOption{
+		WithToken("s3cr3t"),
+		WithTimeout(time.Second),
+		WithHeader(http.Header{"X-Argo": {"1"}}),
+		WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}),
+		WithProxy(func(*http.Request) (*url.URL, error) {
+			atomic.AddInt32(&proxied, 1)
+			return nil, nil
+		}),
+	}
+	for _, uri := range []string{ts.URL + "/jsonrpc", "ws" + strings.TrimPrefix(ts.URL, "http") + "/jsonrpc"} {
+		atomic.StoreInt32(&headers, 0)
+		atomic.StoreInt32(&dials, 0)
+		atomic.StoreInt32(&proxied, 0)
+		rpc, err := NewWithOptions(context.Background(), uri, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAll(t, rpc)
+		rpc.Close()
+		if atomic.LoadInt32(&headers) == 0 || atomic.LoadInt32(&dials) == 0 || atomic.LoadInt32(&proxied) == 0 {
+			t.Errorf("%s: %d requests with header, %d dials, %d proxied", uri, headers, dials, proxied)
+		}
 	}
-	if _, err = rpc.GetOption(g); err != nil {
-		t.Error(err)
+
+	transport := &countingTransport{}
+	rpc, err := NewWithOptions(context.Background(), srv.URL, WithToken("s3cr3t"), WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err = rpc.GetGlobalOption(); err != nil {
-		t.Error(err)
+	defer rpc.Close()
+	if _, err = rpc.GetVersion(); err != nil || atomic.LoadInt32(&transport.n) != 1 {
+		t.Errorf("GetVersion() = %v, %d requests through custom client", err, transport.n)
 	}
-	if _, err = rpc.GetGlobalStat(); err != nil {
-		t.Error(err)
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL} {
+		rpc, err := NewWithOptions(context.Background(), uri, WithMaxMessageSize(512), WithoutReconnect())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = rpc.GetVersion(); err != nil {
+			t.Errorf("%s: GetVersion() = %v", uri, err)
+		}
+		if _, err = rpc.ListMethods(); err == nil {
+			t.Errorf("%s: ListMethods() larger than max message size succeeded", uri)
+		}
+		rpc.Close()
 	}
-	if _, err = rpc.GetSessionInfo(); err != nil {
-		t.Error(err)
+}
+
+func TestCallTimeout(t *testing.T) {
+	silent := silentServer()
+	defer silent.Close()
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer blocking.Close()
+	for _, uri := range []string{blocking.URL, "ws" + strings.TrimPrefix(silent.URL, "http")} {
+		rpc, err := NewWithOptions(context.Background(), uri, WithTimeout(time.Second), WithCallTimeout(50*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if _, err = rpc.GetVersion(); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second/2 {
+			t.Errorf("%s: GetVersion() = %v after %v, want %v", uri, err, time.Since(start), context.DeadlineExceeded)
+		}
+		rpc.Close()
 	}
-	if _, err = rpc.Remove(g); err != nil {
-		t.Error(err)
+}
+
+func TestLogger(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	logs := make(chanLogger, 16)
+	rpc, err := NewWithOptions(context.Background(), srv.WebsocketURL, WithLogger(logs), WithReconnect(testBackoff))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if _, err = rpc.TellActive(); err != nil {
-		t.Error(err)
+	defer rpc.Close()
+	srv.CloseClientConnections()
+	select {
+	case line := <-logs:
+		if line != "websocket read failed" {
+			t.Errorf("log = %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("nothing logged on a lost connection")
+	}
+}
+
+func TestAddData(t *testing.T) {
+	torrent := []byte("d8:announce0:4:infod4:name1:aee")
+	metalink := []byte(`<?xml version="1.0" encoding="UTF-8"?><metalink xmlns="urn:ietf:params:xml:ns:metalink">` +
+		`<file name="a"><url>http://example.org/a</url></file><file name="b"><url>http://example.org/b</url></file></metalink>`)
+	seeds := []string{"http://example.org/seed/a"}
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		c, err := New(context.Background(), uri, "secret", time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gid, err := c.AddTorrentData(torrent, seeds, Option{}.Pause(true))
+		if err != nil {
+			t.Fatalf("%s: AddTorrentData() = %v", uri, err)
+		}
+		if uris, err := c.GetURIs(gid); err != nil || len(uris) != 1 || uris[0].URI != seeds[0] {
+			t.Errorf("%s: GetURIs() = %v, %v; want the web-seed %s", uri, uris, err, seeds[0])
+		}
+		if _, err = c.AddTorrentReader(bytes.NewReader(torrent), nil); err != nil {
+			t.Errorf("%s: AddTorrentReader() = %v", uri, err)
+		}
+		if _, err = c.AddTorrentData(torrent, nil, Option{"no-such-option": "1"}); err == nil {
+			t.Errorf("%s: AddTorrentData() with an unknown option succeeded", uri)
+		}
+		if gids, err := c.AddMetalinkData(metalink); err != nil || len(gids) != 2 {
+			t.Errorf("%s: AddMetalinkData() = %v, %v", uri, gids, err)
+		}
+		if gids, err := c.AddMetalinkReader(bytes.NewReader(metalink)); err != nil || len(gids) != 2 {
+			t.Errorf("%s: AddMetalinkReader() = %v, %v", uri, gids, err)
+		}
+		b := c.Batch()
+		tc := b.AddTorrentData(torrent, seeds)
+		mc := b.AddMetalinkData(metalink)
+		if err = b.Do(context.Background()); err != nil || tc.Err != nil || mc.Err != nil || len(mc.Result) != 2 {
+			t.Errorf("%s: Batch.Do() = %v, %v, %v", uri, err, tc.Err, mc.Err)
+		}
+		c.Close()
+	}
+}
+
+func TestLoggerNotifierDialFailed(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler()) // no websocket endpoint
+	defer srv.Close()
+	logs := make(chanLogger, 16)
+	rpc, err := NewWithOptions(context.Background(), srv.URL+"/jsonrpc", WithNotifier(&DummyNotifier{}), WithLogger(logs), WithReconnect(testBackoff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rpc.Close()
+	select {
+	case line := <-logs:
+		if line != "websocket dial failed, redialing in background" {
+			t.Errorf("log = %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("nothing logged on a failed notification dial")
+	}
+}
+
+func TestAddURIMagnetMixed(t *testing.T) {
+	magnet := "magnet:?xt=urn:btih:" + strings.Repeat("ab", 20)
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	transport := &countingTransport{}
+	c, err := NewWithOptions(context.Background(), srv.URL, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sent := atomic.LoadInt32(&transport.n)
+	for _, uris := range [][]string{{magnet, "http://example.org/a"}, {"http://example.org/a", "MAGNET:?xt=urn:btih:" + strings.Repeat("ab", 20)}} {
+		if _, err = c.AddURI(uris); err != ErrMagnetMixed {
+			t.Errorf("AddURI(%q) = %v, want %v", uris, err, ErrMagnetMixed)
+		}
+		if call := c.Batch().AddURI(uris); call.Err != ErrMagnetMixed {
+			t.Errorf("Batch.AddURI(%q) = %v, want %v", uris, call.Err, ErrMagnetMixed)
+		}
+	}
+	if n := atomic.LoadInt32(&transport.n) - sent; n != 0 {
+		t.Errorf("%d requests sent for rejected uris", n)
+	}
+	if _, err = c.AddURI([]string{magnet}); err != nil {
+		t.Errorf("AddURI(magnet) = %v", err)
+	}
+	if _, err = c.AddURI([]string{"http://example.org/a", "http://example.org/b"}); err != nil {
+		t.Errorf("AddURI(mirrors) = %v", err)
 	}
 }
@@ -0,0 +1,867 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zyxar/argo/magnet"
+)
+
+// Backend is an aria2 daemon of a ClusterClient.
+type Backend struct {
+	Name   string // identifies the daemon in errors, e.g. its host:port
+	Client Client
+}
+
+// BackendError is the error of a call to a backend of a ClusterClient.
+type BackendError struct {
+	Backend string
+	Err     error
+}
+
+func (e *BackendError) Error() string { return e.Backend + ": " + e.Err.Error() }
+
+func (e *BackendError) Unwrap() error { return e.Err }
+
+// ErrNoBackend is returned by the calls of a ClusterClient without backends,
+// and by placements finding no backend able to take a download.
+var ErrNoBackend = errors.New("no backend available")
+
+// Placement picks the backend, among backends, a new download is added to; uris are those of the download,
+// the web seeds of a torrent, or else the magnet URI of its info hash, and the file name of a metalink, or none if added as data.
+type Placement interface {
+	Place(ctx context.Context, backends []Backend, uris []string) (int, error)
+}
+
+// PlacementFunc is a function implementing Placement.
+type PlacementFunc func(ctx context.Context, backends []Backend, uris []string) (int, error)
+
+func (f PlacementFunc) Place(ctx context.Context, backends []Backend, uris []string) (int, error) {
+	return f(ctx, backends, uris)
+}
+
+// RoundRobin places downloads on every backend in turn.
+func RoundRobin() Placement {
+	var n uint64
+	return PlacementFunc(func(_ context.Context, backends []Backend, _ []string) (int, error) {
+		return int((atomic.AddUint64(&n, 1) - 1) % uint64(len(backends))), nil
+	})
+}
+
+// LeastActive places downloads on the backend with the fewest active and waiting downloads;
+// backends failing to report their global statistics are skipped.
+func LeastActive() Placement {
+	return PlacementFunc(func(ctx context.Context, backends []Backend, _ []string) (int, error) {
+		stats, err := globalStats(ctx, backends)
+		best, least := -1, int64(math.MaxInt64)
+		for i, stat := range stats {
+			if stat == nil {
+				continue
+			}
+			if n := atoi64(stat.NumActive) + atoi64(stat.NumWaiting); n < least {
+				best, least = i, n
+			}
+		}
+		return placed(best, err)
+	})
+}
+
+// MostFreeSpeed places downloads on the backend whose download speed is the farthest below its
+// max-overall-download-limit, unlimited backends being the farthest, and the least busy of them first;
+// backends failing to report are skipped.
+func MostFreeSpeed() Placement {
+	return PlacementFunc(func(ctx context.Context, backends []Backend, _ []string) (int, error) {
+		free := make([]int64, len(backends))
+		ok := make([]bool, len(backends))
+		err := fanOut(backends, func(i int, c Client) error {
+			b := c.Batch()
+			stat := b.GetGlobalStat()
+			option := b.GetGlobalOption()
+			if err := b.Do(ctx); err != nil {
+				return err
+			}
+			for _, err := range []error{stat.Err, option.Err} {
+				if err != nil {
+					return err
+				}
+			}
+			limit := int64(math.MaxInt64)
+			if v, _ := option.Result["max-overall-download-limit"].(string); v != "" {
+				if n, err := ParseSize(v); err == nil && n > 0 {
+					limit = int64(n)
+				}
+			}
+			free[i], ok[i] = limit-atoi64(stat.Result.DownloadSpeed), true
+			return nil
+		})
+		best := -1
+		for i := range backends {
+			if ok[i] && (best < 0 || free[i] > free[best]) {
+				best = i
+			}
+		}
+		return placed(best, err)
+	})
+}
+
+// HashByHost places the downloads of a host on the same backend, by the hash of the host of the first URI;
+// a magnet link, and so a torrent without web seeds, is placed by its info hash, and any other URI without host is hashed whole.
+// Metalinks have no URI to hash but their file name: those added as data all go to the same backend.
+func HashByHost() Placement {
+	return PlacementFunc(func(_ context.Context, backends []Backend, uris []string) (int, error) {
+		var key string
+		if len(uris) != 0 {
+			key = uris[0]
+			if m, err := magnet.Parse(key); err == nil {
+				key = m.InfoHash + m.InfoHashV2
+			} else if u, err := url.Parse(key); err == nil && u.Host != "" {
+				key = u.Hostname()
+			}
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return int(h.Sum32() % uint32(len(backends))), nil
+	})
+}
+
+// placed returns the backend best picked by a placement, or err, the reason why none was.
+func placed(best int, err error) (int, error) {
+	if best >= 0 {
+		return best, nil
+	}
+	if err == nil {
+		err = ErrNoBackend
+	}
+	return -1, err
+}
+
+func globalStats(ctx context.Context, backends []Backend) ([]*GlobalStatInfo, error) {
+	stats := make([]*GlobalStatInfo, len(backends))
+	err := fanOut(backends, func(i int, c Client) error {
+		stat, err := c.GetGlobalStatContext(ctx)
+		if err == nil {
+			stats[i] = &stat
+		}
+		return err
+	})
+	return stats, err
+}
+
+func atoi64(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// fanOut calls fn with every backend concurrently, and returns the first error in the order of backends, as a *BackendError.
+func fanOut(backends []Backend, fn func(i int, c Client) error) error {
+	if len(backends) == 0 {
+		return ErrNoBackend
+	}
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		wg.Add(1)
+		go func(i int, c Client) {
+			defer wg.Done()
+			errs[i] = fn(i, c)
+		}(i, b.Client)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return &BackendError{Backend: backends[i].Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// rebuildPage is the number of waiting, and of stopped, downloads pulled at once by ClusterClient.Rebuild.
+const rebuildPage = 1000
+
+// ClusterClient implements Protocol and ContextProtocol over many aria2 daemons:
+// a new download is added to the backend picked by its Placement, a call about a GID is routed to the backend
+// owning the download, and a global call fans out to every backend, merging their results.
+//
+// The owner of a GID is learnt from the calls adding or listing downloads; a GID not known yet, e.g. that of
+// a download following a magnet link, makes the client Rebuild its map of GIDs once before giving up.
+// Such rebuilds are shared by concurrent calls, and made at most once per MinRebuildInterval.
+type ClusterClient struct {
+	backends  []Backend
+	placement Placement
+
+	mu   sync.RWMutex
+	gids map[string]int // GID to index of its backend
+
+	rebuildMu  sync.Mutex
+	rebuilding *rebuildCall // guarded by rebuildMu; the rebuild for unknown GIDs in flight, if any
+	rebuilt    time.Time    // guarded by rebuildMu; when the last rebuild for unknown GIDs ended
+}
+
+// MinRebuildInterval is the least time between two rebuilds of a ClusterClient looking for unknown GIDs,
+// so that calls about many stale GIDs do not scan every backend once each.
+const MinRebuildInterval = time.Second
+
+type rebuildCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewClusterClient returns a ClusterClient over backends placing downloads by placement, RoundRobin if nil.
+func NewClusterClient(placement Placement, backends ...Backend) *ClusterClient {
+	if placement == nil {
+		placement = RoundRobin()
+	}
+	return &ClusterClient{backends: backends, placement: placement, gids: make(map[string]int)}
+}
+
+// Backends returns the backends of c.
+func (c *ClusterClient) Backends() []Backend {
+	return c.backends
+}
+
+// Close closes the clients of every backend.
+func (c *ClusterClient) Close() (err error) {
+	for _, b := range c.backends {
+		if e := b.Client.Close(); e != nil && err == nil {
+			err = &BackendError{Backend: b.Name, Err: e}
+		}
+	}
+	return
+}
+
+// Rebuild replaces the map of GIDs to backends with one pulled from TellActive, TellWaiting and TellStopped of every backend.
+// Backends failing to answer keep their GIDs already known; the first failure is returned.
+func (c *ClusterClient) Rebuild(ctx context.Context) error {
+	owned := make([][]string, len(c.backends))
+	err := fanOut(c.backends, func(i int, b Client) (err error) {
+		owned[i], err = backendGIDs(ctx, b)
+		return
+	})
+	gids := make(map[string]int)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for gid, i := range c.gids {
+		if owned[i] == nil {
+			gids[gid] = i
+		}
+	}
+	for i := range owned {
+		for _, gid := range owned[i] {
+			gids[gid] = i
+		}
+	}
+	c.gids = gids
+	return err
+}
+
+// backendGIDs returns the GIDs of all downloads of b; non-nil unless it fails.
+func backendGIDs(ctx context.Context, b Client) ([]string, error) {
+	gids := []string{}
+	for offset := 0; ; offset += rebuildPage {
+		batch := b.Batch()
+		calls := []*StatusesCall{batch.TellWaiting(offset, rebuildPage, "gid"), batch.TellStopped(offset, rebuildPage, "gid")}
+		if offset == 0 {
+			calls = append(calls, batch.TellActive("gid"))
+		}
+		if err := batch.Do(ctx); err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			if call.Err != nil {
+				return nil, call.Err
+			}
+			for _, info := range call.Result {
+				gids = append(gids, info.Gid)
+			}
+		}
+		if len(calls[0].Result) < rebuildPage && len(calls[1].Result) < rebuildPage {
+			return gids, nil
+		}
+	}
+}
+
+func (c *ClusterClient) learn(i int, gids ...string) {
+	c.mu.Lock()
+	for _, gid := range gids {
+		c.gids[gid] = i
+	}
+	c.mu.Unlock()
+}
+
+func (c *ClusterClient) learnInfos(i int, infos []StatusInfo) {
+	gids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.Gid != "" {
+			gids = append(gids, info.Gid)
+		}
+	}
+	c.learn(i, gids...)
+}
+
+func (c *ClusterClient) forget(gid string) {
+	c.mu.Lock()
+	delete(c.gids, gid)
+	c.mu.Unlock()
+}
+
+func (c *ClusterClient) lookup(gid string) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.gids[gid]
+	return i, ok
+}
+
+// owner returns the index of the backend owning gid, rebuilding the map of GIDs if it is not known.
+func (c *ClusterClient) owner(ctx context.Context, gid string) (int, error) {
+	if i, ok := c.lookup(gid); ok {
+		return i, nil
+	}
+	err := c.refresh(ctx)
+	if i, ok := c.lookup(gid); ok {
+		return i, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return -1, fmt.Errorf("%w: %s", ErrGIDNotFound, gid)
+}
+
+// refresh rebuilds the map of GIDs, unless it was within MinRebuildInterval; a rebuild in flight is waited for instead.
+func (c *ClusterClient) refresh(ctx context.Context) error {
+	c.rebuildMu.Lock()
+	if call := c.rebuilding; call != nil {
+		c.rebuildMu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !c.rebuilt.IsZero() && time.Since(c.rebuilt) < MinRebuildInterval {
+		c.rebuildMu.Unlock()
+		return nil
+	}
+	call := &rebuildCall{done: make(chan struct{})}
+	c.rebuilding = call
+	c.rebuildMu.Unlock()
+
+	call.err = c.Rebuild(ctx)
+	c.rebuildMu.Lock()
+	c.rebuilding, c.rebuilt = nil, time.Now()
+	c.rebuildMu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// route calls fn with the backend owning gid; a backend which no longer knows gid makes c forget it.
+func (c *ClusterClient) route(ctx context.Context, gid string, fn func(b Client) error) error {
+	i, err := c.owner(ctx, gid)
+	if err != nil {
+		return err
+	}
+	if err = fn(c.backends[i].Client); err != nil {
+		if errors.Is(err, ErrGIDNotFound) {
+			c.forget(gid)
+		}
+		return &BackendError{Backend: c.backends[i].Name, Err: err}
+	}
+	return nil
+}
+
+// add adds a download, by fn, to the backend picked for uris.
+func (c *ClusterClient) add(ctx context.Context, uris []string, fn func(b Client) ([]string, error)) ([]string, error) {
+	if len(c.backends) == 0 {
+		return nil, ErrNoBackend
+	}
+	i, err := c.placement.Place(ctx, c.backends, uris)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(c.backends) {
+		return nil, fmt.Errorf("placement picked backend %d of %d", i, len(c.backends))
+	}
+	gids, err := fn(c.backends[i].Client)
+	if err != nil {
+		return nil, &BackendError{Backend: c.backends[i].Name, Err: err}
+	}
+	c.learn(i, gids...)
+	return gids, nil
+}
+
+// first calls fn with the first backend, for calls whose result is the same on every backend, or is not merged.
+func (c *ClusterClient) first(fn func(b Client) error) error {
+	if len(c.backends) == 0 {
+		return ErrNoBackend
+	}
+	if err := fn(c.backends[0].Client); err != nil {
+		return &BackendError{Backend: c.backends[0].Name, Err: err}
+	}
+	return nil
+}
+
+// all calls fn with every backend, returning "OK" if it succeeds on all of them.
+func (c *ClusterClient) all(fn func(b Client) (string, error)) (ok string, err error) {
+	err = fanOut(c.backends, func(_ int, b Client) error {
+		_, err := fn(b)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	return "OK", nil
+}
+
+// statuses fans fn out and concatenates the downloads of every backend, in the order of backends.
+func (c *ClusterClient) statuses(fn func(b Client) ([]StatusInfo, error)) (infos []StatusInfo, err error) {
+	results := make([][]StatusInfo, len(c.backends))
+	err = fanOut(c.backends, func(i int, b Client) (err error) {
+		if results[i], err = fn(b); err == nil {
+			c.learnInfos(i, results[i])
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	infos = []StatusInfo{}
+	for _, result := range results {
+		infos = append(infos, result...)
+	}
+	return
+}
+
+// AddURIContext adds the download to the backend picked for uris; see Protocol.
+func (c *ClusterClient) AddURIContext(ctx context.Context, uris []string, options ...interface{}) (gid string, err error) {
+	if err = checkURIs(uris); err != nil {
+		return
+	}
+	gids, err := c.add(ctx, uris, func(b Client) ([]string, error) {
+		gid, err := b.AddURIContext(ctx, uris, options...)
+		return []string{gid}, err
+	})
+	if err != nil {
+		return
+	}
+	return gids[0], nil
+}
+
+// AddTorrentContext is like AddTorrentDataContext with the data read from filename, and no web seeds; see Protocol.
+func (c *ClusterClient) AddTorrentContext(ctx context.Context, filename string, options ...interface{}) (gid string, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	return c.AddTorrentDataContext(ctx, data, nil, options...)
+}
+
+// AddMetalinkContext adds the downloads to the backend picked for filename; see Protocol.
+func (c *ClusterClient) AddMetalinkContext(ctx context.Context, filename string, options ...interface{}) (gid []string, err error) {
+	return c.add(ctx, []string{filename}, func(b Client) ([]string, error) {
+		return b.AddMetalinkContext(ctx, filename, options...)
+	})
+}
+
+// AddTorrentDataContext adds the download to the backend picked for webSeeds or, if there is none,
+// for the magnet URI of the info hash of data; see ContextProtocol.
+func (c *ClusterClient) AddTorrentDataContext(ctx context.Context, data []byte, webSeeds []string, options ...interface{}) (gid string, err error) {
+	uris := webSeeds
+	if len(uris) == 0 {
+		if hash := torrentInfoHash(data); hash != "" {
+			uris = []string{(&magnet.Magnet{InfoHash: hash}).String()}
+		}
+	}
+	gids, err := c.add(ctx, uris, func(b Client) ([]string, error) {
+		gid, err := b.AddTorrentDataContext(ctx, data, webSeeds, options...)
+		return []string{gid}, err
+	})
+	if err != nil {
+		return
+	}
+	return gids[0], nil
+}
+
+// AddTorrentReaderContext is like AddTorrentDataContext with the data read from r.
+func (c *ClusterClient) AddTorrentReaderContext(ctx context.Context, r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return c.AddTorrentDataContext(ctx, data, webSeeds, options...)
+}
+
+// AddMetalinkDataContext adds the downloads to the backend picked for no uri; see ContextProtocol.
+func (c *ClusterClient) AddMetalinkDataContext(ctx context.Context, data []byte, options ...interface{}) (gid []string, err error) {
+	return c.add(ctx, nil, func(b Client) ([]string, error) {
+		return b.AddMetalinkDataContext(ctx, data, options...)
+	})
+}
+
+// AddMetalinkReaderContext is like AddMetalinkDataContext with the data read from r.
+func (c *ClusterClient) AddMetalinkReaderContext(ctx context.Context, r io.Reader, options ...interface{}) (gid []string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return c.AddMetalinkDataContext(ctx, data, options...)
+}
+
+func (c *ClusterClient) RemoveContext(ctx context.Context, gid string) (g string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { g, err = b.RemoveContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) ForceRemoveContext(ctx context.Context, gid string) (g string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { g, err = b.ForceRemoveContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) PauseContext(ctx context.Context, gid string) (g string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { g, err = b.PauseContext(ctx, gid); return })
+	return
+}
+
+// PauseAllContext pauses the downloads of every backend.
+func (c *ClusterClient) PauseAllContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.PauseAllContext(ctx) })
+}
+
+func (c *ClusterClient) ForcePauseContext(ctx context.Context, gid string) (g string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { g, err = b.ForcePauseContext(ctx, gid); return })
+	return
+}
+
+// ForcePauseAllContext force-pauses the downloads of every backend.
+func (c *ClusterClient) ForcePauseAllContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.ForcePauseAllContext(ctx) })
+}
+
+func (c *ClusterClient) UnpauseContext(ctx context.Context, gid string) (g string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { g, err = b.UnpauseContext(ctx, gid); return })
+	return
+}
+
+// UnpauseAllContext unpauses the downloads of every backend.
+func (c *ClusterClient) UnpauseAllContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.UnpauseAllContext(ctx) })
+}
+
+func (c *ClusterClient) TellStatusContext(ctx context.Context, gid string, keys ...string) (info StatusInfo, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { info, err = b.TellStatusContext(ctx, gid, keys...); return })
+	return
+}
+
+func (c *ClusterClient) GetURIsContext(ctx context.Context, gid string) (infos []URIInfo, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { infos, err = b.GetURIsContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) GetFilesContext(ctx context.Context, gid string) (infos []FileInfo, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { infos, err = b.GetFilesContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) GetPeersContext(ctx context.Context, gid string) (infos []PeerInfo, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { infos, err = b.GetPeersContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) GetServersContext(ctx context.Context, gid string) (infos []ServerInfo, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { infos, err = b.GetServersContext(ctx, gid); return })
+	return
+}
+
+// TellActiveContext returns the active downloads of every backend, in the order of backends.
+func (c *ClusterClient) TellActiveContext(ctx context.Context, keys ...string) (infos []StatusInfo, err error) {
+	return c.statuses(func(b Client) ([]StatusInfo, error) { return b.TellActiveContext(ctx, keys...) })
+}
+
+// TellWaitingContext returns the waiting downloads of every backend at offset and num of its own queue, in the order of backends.
+func (c *ClusterClient) TellWaitingContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.statuses(func(b Client) ([]StatusInfo, error) { return b.TellWaitingContext(ctx, offset, num, keys...) })
+}
+
+// TellStoppedContext returns the stopped downloads of every backend at offset and num of its own list, in the order of backends.
+func (c *ClusterClient) TellStoppedContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.statuses(func(b Client) ([]StatusInfo, error) { return b.TellStoppedContext(ctx, offset, num, keys...) })
+}
+
+// ChangePositionContext moves the download of gid in the queue of the backend owning it.
+func (c *ClusterClient) ChangePositionContext(ctx context.Context, gid string, pos int, how string) (p int, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { p, err = b.ChangePositionContext(ctx, gid, pos, how); return })
+	return
+}
+
+func (c *ClusterClient) ChangeURIContext(ctx context.Context, gid string, fileindex int, delUris []string, addUris []string, position ...int) (p []int, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) {
+		p, err = b.ChangeURIContext(ctx, gid, fileindex, delUris, addUris, position...)
+		return
+	})
+	return
+}
+
+func (c *ClusterClient) GetOptionContext(ctx context.Context, gid string) (m Option, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { m, err = b.GetOptionContext(ctx, gid); return })
+	return
+}
+
+func (c *ClusterClient) ChangeOptionContext(ctx context.Context, gid string, option Option) (ok string, err error) {
+	err = c.route(ctx, gid, func(b Client) (err error) { ok, err = b.ChangeOptionContext(ctx, gid, option); return })
+	return
+}
+
+// GetGlobalOptionContext returns the global options of the first backend.
+func (c *ClusterClient) GetGlobalOptionContext(ctx context.Context) (m Option, err error) {
+	err = c.first(func(b Client) (err error) { m, err = b.GetGlobalOptionContext(ctx); return })
+	return
+}
+
+// ChangeGlobalOptionContext changes the global options of every backend.
+func (c *ClusterClient) ChangeGlobalOptionContext(ctx context.Context, options Option) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.ChangeGlobalOptionContext(ctx, options) })
+}
+
+// GetGlobalStatContext returns the sums of the global statistics of every backend.
+func (c *ClusterClient) GetGlobalStatContext(ctx context.Context) (info GlobalStatInfo, err error) {
+	stats, err := globalStats(ctx, c.backends)
+	if err != nil {
+		return
+	}
+	var sum [6]int64
+	for _, stat := range stats {
+		for i, v := range []string{stat.DownloadSpeed, stat.UploadSpeed, stat.NumActive, stat.NumWaiting, stat.NumStopped, stat.NumStoppedTotal} {
+			sum[i] += atoi64(v)
+		}
+	}
+	s := func(i int) string { return strconv.FormatInt(sum[i], 10) }
+	return GlobalStatInfo{
+		DownloadSpeed:   s(0),
+		UploadSpeed:     s(1),
+		NumActive:       s(2),
+		NumWaiting:      s(3),
+		NumStopped:      s(4),
+		NumStoppedTotal: s(5),
+	}, nil
+}
+
+// PurgeDownloadResultContext purges the stopped downloads of every backend,
+// then rebuilds the map of GIDs to forget theirs; a failure of this rebuild is not returned.
+func (c *ClusterClient) PurgeDownloadResultContext(ctx context.Context) (ok string, err error) {
+	if ok, err = c.all(func(b Client) (string, error) { return b.PurgeDownloadResultContext(ctx) }); err != nil {
+		return
+	}
+	c.Rebuild(ctx)
+	return
+}
+
+func (c *ClusterClient) RemoveDownloadResultContext(ctx context.Context, gid string) (ok string, err error) {
+	if err = c.route(ctx, gid, func(b Client) (err error) { ok, err = b.RemoveDownloadResultContext(ctx, gid); return }); err == nil {
+		c.forget(gid)
+	}
+	return
+}
+
+// GetVersionContext returns the version of the first backend.
+func (c *ClusterClient) GetVersionContext(ctx context.Context) (info VersionInfo, err error) {
+	err = c.first(func(b Client) (err error) { info, err = b.GetVersionContext(ctx); return })
+	return
+}
+
+// GetSessionInfoContext returns the session of the first backend.
+func (c *ClusterClient) GetSessionInfoContext(ctx context.Context) (info SessionInfo, err error) {
+	err = c.first(func(b Client) (err error) { info, err = b.GetSessionInfoContext(ctx); return })
+	return
+}
+
+// ShutdownContext shuts every backend down.
+func (c *ClusterClient) ShutdownContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.ShutdownContext(ctx) })
+}
+
+// ForceShutdownContext shuts every backend down forcefully.
+func (c *ClusterClient) ForceShutdownContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.ForceShutdownContext(ctx) })
+}
+
+// SaveSessionContext saves the session of every backend.
+func (c *ClusterClient) SaveSessionContext(ctx context.Context) (ok string, err error) {
+	return c.all(func(b Client) (string, error) { return b.SaveSessionContext(ctx) })
+}
+
+// MulticallContext is not implemented, as the calls could belong to different backends;
+// use the Client of a backend, or a Batch of it, instead.
+func (c *ClusterClient) MulticallContext(ctx context.Context, methods []Method) (r []interface{}, err error) {
+	return nil, errNotImplemented
+}
+
+// ListMethodsContext returns the methods of the first backend.
+func (c *ClusterClient) ListMethodsContext(ctx context.Context) (methods []string, err error) {
+	err = c.first(func(b Client) (err error) { methods, err = b.ListMethodsContext(ctx); return })
+	return
+}
+
+func (c *ClusterClient) AddURI(uris []string, options ...interface{}) (gid string, err error) {
+	return c.AddURIContext(context.Background(), uris, options...)
+}
+
+func (c *ClusterClient) AddTorrent(filename string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentContext(context.Background(), filename, options...)
+}
+
+func (c *ClusterClient) AddMetalink(filename string, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkContext(context.Background(), filename, options...)
+}
+
+func (c *ClusterClient) AddTorrentData(data []byte, webSeeds []string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentDataContext(context.Background(), data, webSeeds, options...)
+}
+
+func (c *ClusterClient) AddTorrentReader(r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error) {
+	return c.AddTorrentReaderContext(context.Background(), r, webSeeds, options...)
+}
+
+func (c *ClusterClient) AddMetalinkData(data []byte, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkDataContext(context.Background(), data, options...)
+}
+
+func (c *ClusterClient) AddMetalinkReader(r io.Reader, options ...interface{}) (gid []string, err error) {
+	return c.AddMetalinkReaderContext(context.Background(), r, options...)
+}
+
+func (c *ClusterClient) Remove(gid string) (g string, err error) {
+	return c.RemoveContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) ForceRemove(gid string) (g string, err error) {
+	return c.ForceRemoveContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) Pause(gid string) (g string, err error) {
+	return c.PauseContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) PauseAll() (ok string, err error) {
+	return c.PauseAllContext(context.Background())
+}
+
+func (c *ClusterClient) ForcePause(gid string) (g string, err error) {
+	return c.ForcePauseContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) ForcePauseAll() (ok string, err error) {
+	return c.ForcePauseAllContext(context.Background())
+}
+
+func (c *ClusterClient) Unpause(gid string) (g string, err error) {
+	return c.UnpauseContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) UnpauseAll() (ok string, err error) {
+	return c.UnpauseAllContext(context.Background())
+}
+
+func (c *ClusterClient) TellStatus(gid string, keys ...string) (info StatusInfo, err error) {
+	return c.TellStatusContext(context.Background(), gid, keys...)
+}
+
+func (c *ClusterClient) GetURIs(gid string) (infos []URIInfo, err error) {
+	return c.GetURIsContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) GetFiles(gid string) (infos []FileInfo, err error) {
+	return c.GetFilesContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) GetPeers(gid string) (infos []PeerInfo, err error) {
+	return c.GetPeersContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) GetServers(gid string) (infos []ServerInfo, err error) {
+	return c.GetServersContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) TellActive(keys ...string) (infos []StatusInfo, err error) {
+	return c.TellActiveContext(context.Background(), keys...)
+}
+
+func (c *ClusterClient) TellWaiting(offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.TellWaitingContext(context.Background(), offset, num, keys...)
+}
+
+func (c *ClusterClient) TellStopped(offset, num int, keys ...string) (infos []StatusInfo, err error) {
+	return c.TellStoppedContext(context.Background(), offset, num, keys...)
+}
+
+func (c *ClusterClient) ChangePosition(gid string, pos int, how string) (p int, err error) {
+	return c.ChangePositionContext(context.Background(), gid, pos, how)
+}
+
+func (c *ClusterClient) ChangeURI(gid string, fileindex int, delUris []string, addUris []string, position ...int) (p []int, err error) {
+	return c.ChangeURIContext(context.Background(), gid, fileindex, delUris, addUris, position...)
+}
+
+func (c *ClusterClient) GetOption(gid string) (m Option, err error) {
+	return c.GetOptionContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) ChangeOption(gid string, option Option) (ok string, err error) {
+	return c.ChangeOptionContext(context.Background(), gid, option)
+}
+
+func (c *ClusterClient) GetGlobalOption() (m Option, err error) {
+	return c.GetGlobalOptionContext(context.Background())
+}
+
+func (c *ClusterClient) ChangeGlobalOption(options Option) (ok string, err error) {
+	return c.ChangeGlobalOptionContext(context.Background(), options)
+}
+
+func (c *ClusterClient) GetGlobalStat() (info GlobalStatInfo, err error) {
+	return c.GetGlobalStatContext(context.Background())
+}
+
+func (c *ClusterClient) PurgeDownloadResult() (ok string, err error) {
+	return c.PurgeDownloadResultContext(context.Background())
+}
+
+func (c *ClusterClient) RemoveDownloadResult(gid string) (ok string, err error) {
+	return c.RemoveDownloadResultContext(context.Background(), gid)
+}
+
+func (c *ClusterClient) GetVersion() (info VersionInfo, err error) {
+	return c.GetVersionContext(context.Background())
+}
+
+func (c *ClusterClient) GetSessionInfo() (info SessionInfo, err error) {
+	return c.GetSessionInfoContext(context.Background())
+}
+
+func (c *ClusterClient) Shutdown() (ok string, err error) {
+	return c.ShutdownContext(context.Background())
+}
+
+func (c *ClusterClient) ForceShutdown() (ok string, err error) {
+	return c.ForceShutdownContext(context.Background())
+}
+
+func (c *ClusterClient) SaveSession() (ok string, err error) {
+	return c.SaveSessionContext(context.Background())
+}
+
+func (c *ClusterClient) Multicall(methods []Method) (r []interface{}, err error) {
+	return c.MulticallContext(context.Background(), methods)
+}
+
+func (c *ClusterClient) ListMethods() (methods []string, err error) {
+	return c.ListMethodsContext(context.Background())
+}
@@ -0,0 +1,238 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+var (
+	_ Protocol        = (*ClusterClient)(nil)
+	_ ContextProtocol = (*ClusterClient)(nil)
+)
+
+// newTestCluster starts n servers and returns them with their backends.
+func newTestCluster(t *testing.T, n int) ([]*ariatest.Server, []Backend) {
+	t.Helper()
+	srvs := make([]*ariatest.Server, n)
+	backends := make([]Backend, n)
+	for i := range srvs {
+		srvs[i] = ariatest.NewServer("")
+		c, err := New(context.Background(), srvs[i].URL, "", time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backends[i] = Backend{Name: srvs[i].URL, Client: c}
+	}
+	return srvs, backends
+}
+
+func closeTestCluster(srvs []*ariatest.Server, backends []Backend) {
+	for i := range srvs {
+		backends[i].Client.Close()
+		srvs[i].Close()
+	}
+}
+
+func TestClusterClient(t *testing.T) {
+	srvs, backends := newTestCluster(t, 3)
+	defer closeTestCluster(srvs, backends)
+	c := NewClusterClient(nil, backends...)
+	gids := make([]string, 3)
+	for i := range gids {
+		// ariatest numbers GIDs alike on every server, unlike aria2
+		gid, err := c.AddURI([]string{targetURL}, Option{}.Pause(true).Gid(fmt.Sprintf("%016x", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gids[i] = gid
+		// round robin: the i-th download is on the i-th backend
+		if _, err = backends[i].Client.TellStatus(gid); err != nil {
+			t.Errorf("download %d not on backend %d: %v", i, i, err)
+		}
+	}
+	for _, gid := range gids {
+		if info, err := c.TellStatus(gid, "gid", "status"); err != nil || info.Gid != gid || info.Status != "paused" {
+			t.Errorf("TellStatus(%s) = %+v, %v", gid, info, err)
+		}
+	}
+	if stat, err := c.GetGlobalStat(); err != nil || stat.NumWaiting != "3" || stat.NumActive != "0" {
+		t.Errorf("GetGlobalStat() = %+v, %v", stat, err)
+	}
+	if infos, err := c.TellWaiting(0, 10, "gid"); err != nil || len(infos) != 3 || infos[0].Gid != gids[0] || infos[2].Gid != gids[2] {
+		t.Errorf("TellWaiting() = %v, %v", infos, err)
+	}
+	if ok, err := c.UnpauseAll(); err != nil || ok != "OK" {
+		t.Errorf("UnpauseAll() = %q, %v", ok, err)
+	}
+	if infos, err := c.TellActive("gid"); err != nil || len(infos) != 3 {
+		t.Errorf("TellActive() = %v, %v", infos, err)
+	}
+	if ok, err := c.ChangeGlobalOption(Option{}.MaxConcurrentDownloads(2)); err != nil || ok != "OK" {
+		t.Errorf("ChangeGlobalOption() = %q, %v", ok, err)
+	}
+	for _, b := range backends {
+		if m, err := b.Client.GetGlobalOption(); err != nil || m["max-concurrent-downloads"] != "2" {
+			t.Errorf("%s: GetGlobalOption() = %v, %v", b.Name, m, err)
+		}
+	}
+
+	// a fresh client learns the owners of GIDs from the backends
+	fresh := NewClusterClient(nil, backends...)
+	if g, err := fresh.Remove(gids[1]); err != nil || g != gids[1] {
+		t.Errorf("Remove(%s) = %q, %v", gids[1], g, err)
+	}
+	if ok, err := fresh.RemoveDownloadResult(gids[1]); err != nil || ok != "OK" {
+		t.Errorf("RemoveDownloadResult(%s) = %q, %v", gids[1], ok, err)
+	}
+	if _, err := fresh.TellStatus(gids[1]); !errors.Is(err, ErrGIDNotFound) {
+		t.Errorf("TellStatus() of removed download = %v, want %v", err, ErrGIDNotFound)
+	}
+	if _, err := c.TellStatus(gids[1]); !errors.Is(err, ErrGIDNotFound) {
+		t.Errorf("TellStatus() of download removed by another client = %v, want %v", err, ErrGIDNotFound)
+	}
+	if _, err := c.Multicall(nil); err != errNotImplemented {
+		t.Errorf("Multicall() = %v", err)
+	}
+}
+
+func TestClusterClientBackendError(t *testing.T) {
+	srvs, backends := newTestCluster(t, 2)
+	defer closeTestCluster(srvs, backends)
+	c := NewClusterClient(nil, backends...)
+	gid, err := c.AddURI([]string{targetURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvs[1].Close()
+	var berr *BackendError
+	if _, err := c.TellActive(); !errors.As(err, &berr) || berr.Backend != backends[1].Name {
+		t.Errorf("TellActive() = %v, want *BackendError of %s", err, backends[1].Name)
+	}
+	if _, err := c.TellStatus(gid); err != nil {
+		t.Errorf("TellStatus() on a live backend = %v", err)
+	}
+	if _, err := NewClusterClient(nil).AddURI([]string{targetURL}); err != ErrNoBackend {
+		t.Errorf("AddURI() without backends = %v, want %v", err, ErrNoBackend)
+	}
+}
+
+func TestClusterClientGIDs(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	transport := &countingTransport{}
+	b, err := NewWithOptions(context.Background(), srv.URL, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	c := NewClusterClient(nil, Backend{Name: srv.URL, Client: b})
+
+	// concurrent calls about unknown GIDs share one rebuild, and later ones within MinRebuildInterval make none
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := c.TellStatus(fmt.Sprintf("%016x", i+1)); !errors.Is(err, ErrGIDNotFound) {
+				t.Errorf("TellStatus() of an unknown GID = %v, want %v", err, ErrGIDNotFound)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 20; i++ {
+		c.TellStatus(fmt.Sprintf("%016x", i+100))
+	}
+	if n := atomic.LoadInt32(&transport.n); n != 1 {
+		t.Errorf("%d requests for 40 unknown GIDs, want a single rebuild", n)
+	}
+
+	// a purge forgets the GIDs of stopped downloads
+	done, err := c.AddURI([]string{targetURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting, err := c.AddURI([]string{targetURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.Complete(done); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.PurgeDownloadResult(); err != nil || ok != "OK" {
+		t.Errorf("PurgeDownloadResult() = %q, %v", ok, err)
+	}
+	if _, known := c.lookup(done); known || len(c.gids) != 1 {
+		t.Errorf("GIDs known after purge = %v, want only %s", c.gids, waiting)
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	srvs, backends := newTestCluster(t, 3)
+	defer closeTestCluster(srvs, backends)
+	ctx := context.Background()
+	for _, uri := range []string{targetURL, targetURL} {
+		backends[0].Client.AddURI([]string{uri})
+	}
+	backends[2].Client.AddURI([]string{targetURL})
+	if i, err := LeastActive().Place(ctx, backends, nil); err != nil || i != 1 {
+		t.Errorf("LeastActive() = %d, %v, want 1", i, err)
+	}
+
+	backends[0].Client.ChangeGlobalOption(Option{}.MaxOverallDownloadLimit(MiB))
+	backends[1].Client.ChangeGlobalOption(Option{}.MaxOverallDownloadLimit(2 * MiB))
+	backends[2].Client.ChangeGlobalOption(Option{}.MaxOverallDownloadLimit(0))
+	if i, err := MostFreeSpeed().Place(ctx, backends, nil); err != nil || i != 2 {
+		t.Errorf("MostFreeSpeed() = %d, %v, want 2", i, err)
+	}
+	backends[2].Client.ChangeGlobalOption(Option{}.MaxOverallDownloadLimit(512 * KiB))
+	if i, err := MostFreeSpeed().Place(ctx, backends, nil); err != nil || i != 1 {
+		t.Errorf("MostFreeSpeed() = %d, %v, want 1", i, err)
+	}
+
+	hash := HashByHost()
+	a, _ := hash.Place(ctx, backends, []string{"http://example.org/a"})
+	b, _ := hash.Place(ctx, backends, []string{"https://example.org:8443/b"})
+	if a != b {
+		t.Errorf("HashByHost() = %d, %d for the same host", a, b)
+	}
+
+	srvs[1].Close()
+	if i, err := LeastActive().Place(ctx, backends, nil); err != nil || i != 2 {
+		t.Errorf("LeastActive() with a backend down = %d, %v, want 2", i, err)
+	}
+}
+
+func TestHashByHostTorrent(t *testing.T) {
+	srvs, backends := newTestCluster(t, 3)
+	defer closeTestCluster(srvs, backends)
+	ctx := context.Background()
+	hash := HashByHost()
+	// a torrent without web seeds is placed by its info hash, as a magnet link of it
+	torrent := []byte("d4:infod6:lengthi3e4:name1:ae3:urllee")
+	var placedURIs []string
+	c := NewClusterClient(PlacementFunc(func(ctx context.Context, backends []Backend, uris []string) (int, error) {
+		placedURIs = uris
+		return hash.Place(ctx, backends, uris)
+	}), backends...)
+	gid, err := c.AddTorrentData(torrent, nil, Option{}.Pause(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := torrentInfoHash(torrent)
+	if len(placedURIs) != 1 || placedURIs[0] != "magnet:?xt=urn:btih:"+hashed {
+		t.Errorf("AddTorrentData() placed by %q, want the info hash %s", placedURIs, hashed)
+	}
+	i, _ := hash.Place(ctx, backends, []string{"magnet:?dn=a&xt=urn:btih:" + strings.ToUpper(hashed) + "&tr=udp://t:80"})
+	if _, err = backends[i].Client.TellStatus(gid); err != nil {
+		t.Errorf("AddTorrentData() not on backend %d of the magnet link of the torrent: %v", i, err)
+	}
+}
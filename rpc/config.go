@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOption configures a Client created by NewWithOptions or New.
+type ClientOption func(*clientConfig)
+
+// DefaultTimeout is the timeout of clients created by NewWithOptions without WithTimeout.
+const DefaultTimeout = 10 * time.Second
+
+type clientConfig struct {
+	token        string
+	timeout      time.Duration
+	callTimeout  time.Duration
+	callTimeSet  bool
+	notifier     Notifier
+	httpClient   *http.Client
+	dial         func(ctx context.Context, network, addr string) (net.Conn, error)
+	header       http.Header
+	logger       Logger
+	proxy        func(*http.Request) (*url.URL, error)
+	maxMsgSize   int64
+	get          bool   // send calls of http/https clients with GET
+	jsonp        string // name of the JSONP callback of GET calls
+	xml          bool   // speak XML-RPC rather than JSON-RPC over http/https
+	interceptors []Interceptor
+
+	reconnect bool
+	backoff   Backoff
+	onState   func(ConnState)
+	events    *eventHub
+	unchecked bool // send options without validating them
+	poll      time.Duration
+
+	tls        *tls.Config
+	rootCAs    *x509.CertPool
+	certs      []tls.Certificate
+	serverName string
+	pins       [][sha256.Size]byte
+}
+
+func newClientConfig(timeout time.Duration, notifier Notifier, options ...ClientOption) *clientConfig {
+	cfg := &clientConfig{
+		timeout:   timeout,
+		notifier:  notifier,
+		reconnect: true,
+		backoff:   DefaultBackoff,
+		events:    newEventHub(),
+		poll:      DefaultWaitPollInterval,
+		logger:    NopLogger,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	if !cfg.callTimeSet {
+		cfg.callTimeout = cfg.timeout
+	}
+	return cfg
+}
+
+// WithToken sets the secret token of aria2 daemon, i.e. --rpc-secret.
+func WithToken(token string) ClientOption {
+	return func(cfg *clientConfig) { cfg.token = token }
+}
+
+// WithTimeout sets the timeout of connecting to aria2 daemon, and of every call unless WithCallTimeout is given.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.timeout = d }
+}
+
+// WithCallTimeout bounds the round trip of every call, on top of the deadline of its context; 0 means unbounded.
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.callTimeout, cfg.callTimeSet = d, true }
+}
+
+// WithNotifier sets the handler of the notifications of aria2 daemon.
+func WithNotifier(n Notifier) ClientOption {
+	return func(cfg *clientConfig) { cfg.notifier = n }
+}
+
+// WithHTTPClient sends the calls of http/https clients with c, instead of a client built from the other options;
+// the TLS, dial and proxy options still apply to the notification websocket.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(cfg *clientConfig) { cfg.httpClient = c }
+}
+
+// WithDialContext sets the function dialing the network connections to aria2 daemon, for both http and websocket.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) ClientOption {
+	return func(cfg *clientConfig) { cfg.dial = dial }
+}
+
+// WithHeader adds header to every http request, and to the handshake of every websocket connection.
+func WithHeader(header http.Header) ClientOption {
+	return func(cfg *clientConfig) {
+		if cfg.header == nil {
+			cfg.header = http.Header{}
+		}
+		for k, v := range header {
+			cfg.header[k] = append(cfg.header[k], v...)
+		}
+	}
+}
+
+// WithLogger sets where the client logs, e.g. NewStdLogger(log.New(os.Stderr, "argo ", log.LstdFlags), LevelInfo);
+// nothing is logged by default.
+func WithLogger(l Logger) ClientOption {
+	return func(cfg *clientConfig) { cfg.logger = l }
+}
+
+// WithProxy sets the proxy of http requests and websocket connections, e.g. http.ProxyURL(u);
+// by default, http requests go direct and websocket connections use http.ProxyFromEnvironment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(cfg *clientConfig) { cfg.proxy = proxy }
+}
+
+// WithMaxMessageSize limits the size of a message read from aria2 daemon, e.g. the response to TellStopped; 0 means unlimited.
+func WithMaxMessageSize(n int64) ClientOption {
+	return func(cfg *clientConfig) { cfg.maxMsgSize = n }
+}
+
+// WithHTTPGet sends the calls of http/https clients with GET rather than POST, the request being encoded in the query;
+// see the "http+get" scheme of NewWithOptions. If callback is not empty, aria2 daemon is asked for a JSONP response
+// wrapped in a call of callback, e.g. for reverse proxies letting only scripts through.
+func WithHTTPGet(callback string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.get = true
+		cfg.jsonp = callback
+	}
+}
+
+// WithReconnect sets the backoff between attempts to re-establish a lost websocket connection.
+func WithReconnect(b Backoff) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.reconnect = true
+		cfg.backoff = b
+	}
+}
+
+// WithoutReconnect disables re-establishing a lost websocket connection;
+// the client stops working once the connection is lost.
+func WithoutReconnect() ClientOption {
+	return func(cfg *clientConfig) { cfg.reconnect = false }
+}
+
+// WithConnStateHandler registers fn to be called on every state change of the websocket connection,
+// i.e. the rpc connection of ws/wss clients, or the notification stream of http/https clients.
+func WithConnStateHandler(fn func(ConnState)) ClientOption {
+	return func(cfg *clientConfig) { cfg.onState = fn }
+}
+
+// WithoutOptionValidation sends options as they are, instead of checking them against OptionCatalog first;
+// e.g. to use options of aria2 newer than the catalog.
+func WithoutOptionValidation() ClientOption {
+	return func(cfg *clientConfig) { cfg.unchecked = true }
+}
+
+// WithWaitPollInterval sets how often Wait polls the status of a download; DefaultWaitPollInterval by default.
+func WithWaitPollInterval(d time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.poll = d }
+}
+
+// Backoff is an exponential backoff policy.
+type Backoff struct {
+	Initial     time.Duration // delay before the first attempt
+	Max         time.Duration // upper bound of the delay
+	Multiplier  float64       // factor applied to the delay after each failed attempt
+	Jitter      float64       // randomization factor in [0, 1] applied to every delay
+	MaxAttempts int           // give up after so many consecutive failed attempts; 0 means never
+}
+
+// DefaultBackoff is the reconnection policy of clients created without WithReconnect.
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay before the attempt-th (0-based) attempt.
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && (b.Max <= 0 || d < float64(b.Max)); i++ {
+		d *= b.Multiplier
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// ConnState represents the state of a websocket connection to aria2 daemon.
+type ConnState int
+
+const (
+	// StateConnected means the connection is established, or re-established.
+	StateConnected ConnState = iota
+	// StateReconnecting means the connection was lost and is being re-established.
+	StateReconnecting
+	// StateClosed means the connection was closed by the client or could not be re-established; it is final.
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DownloadErrorCode is an exit status of aria2, which is also the errorCode of a failed download (see StatusInfo.ErrorCode).
+// Every code but 0 is an error itself, so that errors.Is(err, ErrDiskFull) holds for a *DownloadError,
+// or a fault response of aria2, carrying it.
+type DownloadErrorCode int
+
+// Exit status of aria2, as documented in its EXIT STATUS section.
+const (
+	ErrUnknown           DownloadErrorCode = iota + 1 // 1: an unknown error occurred
+	ErrTimeout                                        // 2: time out occurred
+	ErrResourceNotFound                               // 3: a resource was not found
+	ErrMaxFileNotFound                                // 4: aria2 saw the specified number of "resource not found" errors, see --max-file-not-found
+	ErrTooSlow                                        // 5: download speed was too slow, see --lowest-speed-limit
+	ErrNetwork                                        // 6: a network problem occurred
+	ErrUnfinished                                     // 7: there were unfinished downloads, e.g. aria2 was shut down
+	ErrResumeUnsupported                              // 8: remote server did not support resume when it was required to complete download
+	ErrDiskFull                                       // 9: there was not enough disk space available
+	ErrPieceLength                                    // 10: piece length was different from one in .aria2 control file, see --allow-piece-length-change
+	ErrDuplicateDownload                              // 11: aria2 was downloading the same file at that moment
+	ErrDuplicateInfoHash                              // 12: aria2 was downloading the same info hash torrent at that moment
+	ErrFileExists                                     // 13: file already existed, see --allow-overwrite
+	ErrRename                                         // 14: renaming file failed, see --auto-file-renaming
+	ErrFileOpen                                       // 15: aria2 could not open existing file
+	ErrFileCreate                                     // 16: aria2 could not create new file or truncate existing file
+	ErrFileIO                                         // 17: a file I/O error occurred
+	ErrDirCreate                                      // 18: aria2 could not create directory
+	ErrNameResolution                                 // 19: name resolution failed
+	ErrMetalinkParse                                  // 20: aria2 could not parse Metalink document
+	ErrFTPCommand                                     // 21: FTP command failed
+	ErrHTTPHeader                                     // 22: HTTP response header was bad or unexpected
+	ErrTooManyRedirects                               // 23: too many redirects occurred
+	ErrHTTPAuth                                       // 24: HTTP authorization failed
+	ErrBencodeParse                                   // 25: aria2 could not parse bencoded file, usually a .torrent file
+	ErrTorrentCorrupted                               // 26: .torrent file was corrupted or missing information that aria2 needed
+	ErrMagnet                                         // 27: magnet URI was bad
+	ErrBadOption                                      // 28: bad/unrecognized option was given or unexpected option argument was given
+	ErrServerOverloaded                               // 29: remote server was unable to handle the request due to a temporary overloading or maintenance
+	ErrJSONRPCParse                                   // 30: aria2 could not parse JSON-RPC request
+	_                                                 // 31: reserved, not used
+	ErrChecksum                                       // 32: checksum validation failed
+)
+
+var downloadErrorDescriptions = [...]string{
+	0:                    "all downloads were successful",
+	ErrUnknown:           "an unknown error occurred",
+	ErrTimeout:           "time out occurred",
+	ErrResourceNotFound:  "a resource was not found",
+	ErrMaxFileNotFound:   "aria2 saw the specified number of \"resource not found\" errors",
+	ErrTooSlow:           "download aborted because download speed was too slow",
+	ErrNetwork:           "network problem occurred",
+	ErrUnfinished:        "there were unfinished downloads",
+	ErrResumeUnsupported: "remote server did not support resume when resume was required to complete download",
+	ErrDiskFull:          "there was not enough disk space available",
+	ErrPieceLength:       "piece length was different from one in .aria2 control file",
+	ErrDuplicateDownload: "aria2 was downloading same file at that moment",
+	ErrDuplicateInfoHash: "aria2 was downloading same info hash torrent at that moment",
+	ErrFileExists:        "file already existed",
+	ErrRename:            "renaming file failed",
+	ErrFileOpen:          "aria2 could not open existing file",
+	ErrFileCreate:        "aria2 could not create new file or truncate existing file",
+	ErrFileIO:            "file I/O error occurred",
+	ErrDirCreate:         "aria2 could not create directory",
+	ErrNameResolution:    "name resolution failed",
+	ErrMetalinkParse:     "aria2 could not parse Metalink document",
+	ErrFTPCommand:        "FTP command failed",
+	ErrHTTPHeader:        "HTTP response header was bad or unexpected",
+	ErrTooManyRedirects:  "too many redirects occurred",
+	ErrHTTPAuth:          "HTTP authorization failed",
+	ErrBencodeParse:      "aria2 could not parse bencoded file",
+	ErrTorrentCorrupted:  ".torrent file was corrupted or missing information that aria2 needed",
+	ErrMagnet:            "magnet URI was bad",
+	ErrBadOption:         "bad/unrecognized option was given or unexpected option argument was given",
+	ErrServerOverloaded:  "remote server was unable to handle the request due to a temporary overloading or maintenance",
+	ErrJSONRPCParse:      "aria2 could not parse JSON-RPC request",
+	ErrChecksum:          "checksum validation failed",
+}
+
+// Description returns the description of c in the aria2 manual.
+func (c DownloadErrorCode) Description() string {
+	if c >= 0 && int(c) < len(downloadErrorDescriptions) && downloadErrorDescriptions[c] != "" {
+		return downloadErrorDescriptions[c]
+	}
+	return "unknown error code " + strconv.Itoa(int(c))
+}
+
+func (c DownloadErrorCode) Error() string { return c.Description() }
+
+// Retryable reports whether a download failing with c may succeed if retried as is, later:
+// the error is transient, e.g. a timeout or a network problem, rather than one of the request, the file or the disk.
+func (c DownloadErrorCode) Retryable() bool {
+	switch c {
+	case ErrTimeout, ErrTooSlow, ErrNetwork, ErrUnfinished, ErrNameResolution, ErrServerOverloaded:
+		return true
+	}
+	return false
+}
+
+// ErrGIDNotFound is matched by the fault response of aria2 to a call about a GID it does not know,
+// e.g. one which is purged, or which is not in the waiting queue for ChangePosition.
+var ErrGIDNotFound = errors.New("gid not found")
+
+// Is reports whether e matches ErrGIDNotFound, by message. The code of a fault is not an exit status of a download,
+// aria2 sets it to 1 for most faults, so e matches no DownloadErrorCode; a *DownloadError does.
+func (e *Error) Is(target error) bool {
+	if target == ErrGIDNotFound {
+		msg := strings.ToLower(e.Message)
+		return strings.Contains(msg, "gid") && strings.Contains(msg, "not found")
+	}
+	return false
+}
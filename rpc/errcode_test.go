@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestDownloadErrorCode(t *testing.T) {
+	for _, c := range []struct {
+		code      DownloadErrorCode
+		desc      string
+		retryable bool
+	}{
+		{0, "all downloads were successful", false},
+		{ErrUnknown, "an unknown error occurred", false},
+		{ErrTimeout, "time out occurred", true},
+		{ErrDiskFull, "there was not enough disk space available", false},
+		{ErrHTTPAuth, "HTTP authorization failed", false},
+		{ErrServerOverloaded, "remote server was unable to handle the request due to a temporary overloading or maintenance", true},
+		{ErrChecksum, "checksum validation failed", false},
+		{31, "unknown error code 31", false},
+		{99, "unknown error code 99", false},
+	} {
+		if desc := c.code.Description(); desc != c.desc {
+			t.Errorf("%d.Description() = %q, want %q", c.code, desc, c.desc)
+		}
+		if r := c.code.Retryable(); r != c.retryable {
+			t.Errorf("%d.Retryable() = %v, want %v", c.code, r, c.retryable)
+		}
+	}
+	if ErrFileExists != 13 || ErrChecksum != 32 {
+		t.Errorf("ErrFileExists = %d, ErrChecksum = %d", ErrFileExists, ErrChecksum)
+	}
+}
+
+func TestDownloadErrorIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &DownloadError{Gid: "1", Status: "error", Code: ErrDiskFull})
+	if !errors.Is(err, ErrDiskFull) || errors.Is(err, ErrFileExists) {
+		t.Errorf("errors.Is(%v) mismatch", err)
+	}
+	if err.Error() != "wrapped: download 1 failed with error code 9: there was not enough disk space available" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	var code DownloadErrorCode
+	if !errors.As(err, &code) || code != ErrDiskFull {
+		t.Errorf("errors.As() = %d", code)
+	}
+	if removed := (&DownloadError{Gid: "1", Status: "removed"}); errors.Unwrap(removed) != nil {
+		t.Errorf("Unwrap() of removed download = %v", errors.Unwrap(removed))
+	}
+	fault := &Error{Code: 1, Message: "GID 2089b05ecca3d829 is not found"}
+	if !errors.Is(fault, ErrGIDNotFound) || errors.Is(fault, ErrUnknown) || errors.Is(fault, ErrTimeout) {
+		t.Errorf("errors.Is(%v) mismatch", fault)
+	}
+	if errors.Is(&Error{Code: E_NO_METHOD, Message: "Method not found"}, ErrGIDNotFound) {
+		t.Error("E_NO_METHOD matches ErrGIDNotFound")
+	}
+}
+
+func TestFaultIs(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		c, err := New(context.Background(), uri, "", 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.TellStatus("2089b05ecca3d829"); !errors.Is(err, ErrGIDNotFound) {
+			t.Errorf("%s: TellStatus() = %v, want %v", uri, err, ErrGIDNotFound)
+		}
+		if _, err = c.ChangePosition("2089b05ecca3d829", 0, "POS_SET"); !errors.Is(err, ErrGIDNotFound) {
+			t.Errorf("%s: ChangePosition() = %v, want %v", uri, err, ErrGIDNotFound)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of a download notification sent by aria2 daemon.
+type EventType int
+
+const (
+	EventDownloadStart      EventType = iota + 1 // aria2.onDownloadStart
+	EventDownloadPause                           // aria2.onDownloadPause
+	EventDownloadStop                            // aria2.onDownloadStop
+	EventDownloadComplete                        // aria2.onDownloadComplete
+	EventDownloadError                           // aria2.onDownloadError
+	EventBtDownloadComplete                      // aria2.onBtDownloadComplete
+)
+
+var eventMethods = map[string]EventType{
+	"aria2.onDownloadStart":      EventDownloadStart,
+	"aria2.onDownloadPause":      EventDownloadPause,
+	"aria2.onDownloadStop":       EventDownloadStop,
+	"aria2.onDownloadComplete":   EventDownloadComplete,
+	"aria2.onDownloadError":      EventDownloadError,
+	"aria2.onBtDownloadComplete": EventBtDownloadComplete,
+}
+
+func (t EventType) String() string {
+	switch t {
+	case EventDownloadStart:
+		return "start"
+	case EventDownloadPause:
+		return "pause"
+	case EventDownloadStop:
+		return "stop"
+	case EventDownloadComplete:
+		return "complete"
+	case EventDownloadError:
+		return "error"
+	case EventBtDownloadComplete:
+		return "btcomplete"
+	}
+	return "unknown"
+}
+
+// DownloadEvent is a notification about a download, as delivered to subscribers.
+type DownloadEvent struct {
+	Type EventType
+	Gid  string    // GID of the download
+	Time time.Time // when the notification was received
+}
+
+// OverflowPolicy decides what happens to an event when a subscriber's buffer is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the incoming event.
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room for the incoming one.
+	DropOldest
+)
+
+// DefaultEventBuffer is the channel capacity of subscriptions which do not specify one.
+const DefaultEventBuffer = 64
+
+// EventFilter selects the events delivered to a subscriber, and how they are buffered.
+type EventFilter struct {
+	Types    []EventType // deliver events of these types only; all types if empty
+	Gids     []string    // deliver events of these downloads only; all downloads if empty
+	Buffer   int         // capacity of the event channel; DefaultEventBuffer if not positive
+	Overflow OverflowPolicy
+}
+
+type subscriber struct {
+	types    map[EventType]bool
+	gids     map[string]bool
+	overflow OverflowPolicy
+	ch       chan DownloadEvent
+}
+
+func (s *subscriber) match(e DownloadEvent) bool {
+	return (len(s.types) == 0 || s.types[e.Type]) && (len(s.gids) == 0 || s.gids[e.Gid])
+}
+
+// deliver never blocks; the sole sender of s.ch is the publisher holding the hub lock.
+func (s *subscriber) deliver(e DownloadEvent) {
+	select {
+	case s.ch <- e:
+		return
+	default:
+	}
+	if s.overflow != DropOldest {
+		return
+	}
+	select {
+	case <-s.ch:
+	default:
+	}
+	select {
+	case s.ch <- e:
+	default:
+	}
+}
+
+// eventHub fans notifications out to subscribers, each with its own bounded buffer,
+// so that a slow subscriber never stalls the connection to aria2 daemon.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{} // guarded by mu
+	closed bool                     // guarded by mu
+	done   chan struct{}            // closed by close
+	start  func()                   // called on every subscription, if set
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[*subscriber]struct{}), done: make(chan struct{})}
+}
+
+func (h *eventHub) subscribe(ctx context.Context, filter EventFilter) <-chan DownloadEvent {
+	size := filter.Buffer
+	if size <= 0 {
+		size = DefaultEventBuffer
+	}
+	s := &subscriber{
+		types:    make(map[EventType]bool, len(filter.Types)),
+		gids:     make(map[string]bool, len(filter.Gids)),
+		overflow: filter.Overflow,
+		ch:       make(chan DownloadEvent, size),
+	}
+	for _, t := range filter.Types {
+		s.types[t] = true
+	}
+	for _, gid := range filter.Gids {
+		s.gids[gid] = true
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(s.ch)
+		return s.ch
+	}
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	if h.start != nil {
+		h.start()
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			h.unsubscribe(s)
+		case <-h.done:
+		}
+	}()
+	return s.ch
+}
+
+func (h *eventHub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+func (h *eventHub) publish(method string, events []Event) {
+	t, ok := eventMethods[method]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, e := range events {
+		de := DownloadEvent{Type: t, Gid: e.Gid, Time: now}
+		for s := range h.subs {
+			if s.match(de) {
+				s.deliver(de)
+			}
+		}
+	}
+}
+
+// close ends every subscription; later subscriptions receive a closed channel.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	close(h.done)
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
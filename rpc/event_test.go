@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func recvEvent(t *testing.T, ch <-chan DownloadEvent) DownloadEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	return DownloadEvent{}
+}
+
+func assertClosed(t *testing.T, ch <-chan DownloadEvent) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("event channel not closed")
+		}
+	}
+}
+
+func TestEventHubFilter(t *testing.T) {
+	h := newEventHub()
+	defer h.close()
+	all := h.subscribe(context.Background(), EventFilter{})
+	stops := h.subscribe(context.Background(), EventFilter{Types: []EventType{EventDownloadStop, EventDownloadError}})
+	one := h.subscribe(context.Background(), EventFilter{Gids: []string{"2"}})
+
+	h.publish("aria2.onDownloadStart", []Event{{Gid: "1"}, {Gid: "2"}})
+	h.publish("aria2.onDownloadError", []Event{{Gid: "1"}})
+	h.publish("aria2.unknown", []Event{{Gid: "2"}})
+
+	for _, want := range []DownloadEvent{{Type: EventDownloadStart, Gid: "1"}, {Type: EventDownloadStart, Gid: "2"}, {Type: EventDownloadError, Gid: "1"}} {
+		if e := recvEvent(t, all); e.Type != want.Type || e.Gid != want.Gid {
+			t.Errorf("all: event = %s:%s, want %s:%s", e.Type, e.Gid, want.Type, want.Gid)
+		}
+	}
+	if e := recvEvent(t, stops); e.Type != EventDownloadError || e.Gid != "1" {
+		t.Errorf("stops: event = %s:%s, want error:1", e.Type, e.Gid)
+	}
+	if e := recvEvent(t, one); e.Type != EventDownloadStart || e.Gid != "2" {
+		t.Errorf("one: event = %s:%s, want start:2", e.Type, e.Gid)
+	}
+	if len(all)+len(stops)+len(one) != 0 {
+		t.Errorf("unexpected events left: %d/%d/%d", len(all), len(stops), len(one))
+	}
+}
+
+func TestEventHubOverflow(t *testing.T) {
+	h := newEventHub()
+	defer h.close()
+	newest := h.subscribe(context.Background(), EventFilter{Buffer: 2, Overflow: DropNewest})
+	oldest := h.subscribe(context.Background(), EventFilter{Buffer: 2, Overflow: DropOldest})
+	h.publish("aria2.onDownloadStart", []Event{{Gid: "1"}, {Gid: "2"}, {Gid: "3"}})
+
+	for _, tc := range []struct {
+		name string
+		ch   <-chan DownloadEvent
+		want []string
+	}{
+		{"DropNewest", newest, []string{"1", "2"}},
+		{"DropOldest", oldest, []string{"2", "3"}},
+	} {
+		if len(tc.ch) != len(tc.want) {
+			t.Errorf("%s: %d events buffered, want %d", tc.name, len(tc.ch), len(tc.want))
+			continue
+		}
+		for _, gid := range tc.want {
+			if e := recvEvent(t, tc.ch); e.Gid != gid {
+				t.Errorf("%s: event of %s, want %s", tc.name, e.Gid, gid)
+			}
+		}
+	}
+}
+
+func TestEventHubUnsubscribe(t *testing.T) {
+	h := newEventHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := h.subscribe(ctx, EventFilter{})
+	other := h.subscribe(context.Background(), EventFilter{})
+	cancel()
+	assertClosed(t, ch)
+	h.publish("aria2.onDownloadStart", []Event{{Gid: "1"}}) // must not send on the closed channel
+	recvEvent(t, other)
+
+	h.close()
+	assertClosed(t, other)
+	assertClosed(t, h.subscribe(context.Background(), EventFilter{}))
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		states := make(chan ConnState, 16)
+		c, err := New(context.Background(), uri, "", time.Second, nil, WithReconnect(testBackoff), WithConnStateHandler(func(s ConnState) { states <- s }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := c.Subscribe(context.Background(), EventFilter{Types: []EventType{EventDownloadComplete}})
+		waitState(t, states, StateConnected) // http clients open the notification stream on subscription
+		var gid string
+		deadline := time.After(2 * time.Second)
+		for gid == "" { // the server may register the connection after the handshake completes
+			g, err := c.AddURI([]string{targetURL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.Complete(g); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case e := <-events:
+				if e.Type != EventDownloadComplete || e.Gid != g {
+					t.Errorf("%s: event = %s:%s, want complete:%s", uri, e.Type, e.Gid, g)
+				}
+				gid = g
+			case <-deadline:
+				t.Fatalf("%s: no event", uri)
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		c.Close()
+		assertClosed(t, events)
+	}
+}
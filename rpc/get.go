@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// newGetRequest returns the GET request of the JSON-RPC request, or batch of them, encoded in payload.
+// The whole request goes base64-encoded in the params parameter, leaving out method and id,
+// which aria2 takes for a batch call; a single request is thus answered with its id as sent, like a POST one.
+func newGetRequest(ctx context.Context, uri string, payload *bytes.Buffer, callback string) (*http.Request, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("params", base64.StdEncoding.EncodeToString(bytes.TrimSpace(payload.Bytes())))
+	if callback != "" {
+		query.Set("jsoncallback", callback)
+	}
+	u.RawQuery = query.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+var errJSONP = errors.New("invalid JSONP response")
+
+// unwrapJSONP returns the JSON argument of the call of callback read from body.
+func unwrapJSONP(body io.Reader, callback string) (io.Reader, error) {
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	b = bytes.TrimSuffix(b, []byte(";"))
+	if !bytes.HasPrefix(b, []byte(callback+"(")) || !bytes.HasSuffix(b, []byte(")")) {
+		return nil, errJSONP
+	}
+	return bytes.NewReader(b[len(callback)+1 : len(b)-1]), nil
+}
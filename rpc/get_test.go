@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// methodTransport records the methods of the requests it sends.
+type methodTransport struct {
+	methods chan string
+}
+
+func (m methodTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	select {
+	case m.methods <- r.Method + " " + r.URL.Query().Get("jsoncallback"):
+	default:
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTPGetAll(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	for _, c := range []struct {
+		uri     string
+		options []ClientOption
+		want    string
+	}{
+		{"http+get" + strings.TrimPrefix(srv.URL, "http"), nil, "GET "},
+		{srv.URL, []ClientOption{WithHTTPGet("cb")}, "GET cb"},
+	} {
+		tr := methodTransport{methods: make(chan string, 1)}
+		options := append([]ClientOption{WithHTTPClient(&http.Client{Transport: tr})}, c.options...)
+		rpc, err := New(context.Background(), c.uri, "secret", time.Second, nil, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAll(t, rpc)
+		if m := <-tr.methods; m != c.want {
+			t.Errorf("%s: request %q, want %q", c.uri, m, c.want)
+		}
+
+		b := rpc.Batch()
+		version := b.GetVersion()
+		missing := b.TellStatus("0000000000000000")
+		if err := b.DoArray(context.Background()); err != nil || version.Err != nil || version.Result.Version == "" {
+			t.Errorf("%s: DoArray() = %v; GetVersion = %+v, %v", c.uri, err, version.Result, version.Err)
+		}
+		if !errors.Is(missing.Err, ErrGIDNotFound) {
+			t.Errorf("%s: TellStatus of missing GID = %v", c.uri, missing.Err)
+		}
+		if err := b.Do(context.Background()); err != nil {
+			t.Errorf("%s: Do() of an empty batch = %v", c.uri, err)
+		}
+		rpc.Close()
+	}
+}
+
+func TestUnwrapJSONP(t *testing.T) {
+	for _, c := range []struct {
+		body, want string
+		err        error
+	}{
+		{`cb({"id":1});`, `{"id":1}`, nil},
+		{" cb([1,2])\n", `[1,2]`, nil},
+		{`{"id":1}`, "", errJSONP},
+		{`other({"id":1})`, "", errJSONP},
+	} {
+		r, err := unwrapJSONP(strings.NewReader(c.body), "cb")
+		if err != c.err {
+			t.Errorf("unwrapJSONP(%q) = %v, want %v", c.body, err, c.err)
+			continue
+		}
+		if err == nil {
+			b, _ := ioutil.ReadAll(r)
+			if string(b) != c.want {
+				t.Errorf("unwrapJSONP(%q) = %q, want %q", c.body, b, c.want)
+			}
+		}
+	}
+}
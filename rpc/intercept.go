@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Invoker sends a call of method to aria2 daemon, decoding its result into reply.
+type Invoker func(ctx context.Context, method string, params, reply interface{}) error
+
+// Interceptor is a middleware of the calls of a client: it may inspect or alter the call, and must call next to send it,
+// e.g. to log, measure, trace, retry or refuse calls. params is the slice of parameters as sent,
+// the secret token included as a "token:..." string, which RedactToken masks.
+// A JSON-RPC batch array of Batch.DoArray is seen as a single call of method "batch", params being its []Method and reply nil.
+type Interceptor func(ctx context.Context, method string, params, reply interface{}, next Invoker) error
+
+// WithInterceptors adds interceptors to the calls of the client; the first is the outermost, i.e. sees a call first.
+// It may be given more than once, the interceptors of later options being inner ones.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(cfg *clientConfig) { cfg.interceptors = append(cfg.interceptors, interceptors...) }
+}
+
+// ChainInterceptors returns an Interceptor running interceptors in order, the first being the outermost.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, method string, params, reply interface{}, next Invoker) error {
+		return chain(interceptors, next)(ctx, method, params, reply)
+	}
+}
+
+func chain(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, params, reply interface{}) error {
+			return interceptor(ctx, method, params, reply, next)
+		}
+	}
+	return invoker
+}
+
+// interceptedCaller runs the calls of caller through interceptors.
+type interceptedCaller struct {
+	caller
+	interceptors []Interceptor
+}
+
+func newInterceptedCaller(c caller, interceptors []Interceptor) caller {
+	if len(interceptors) == 0 {
+		return c
+	}
+	return &interceptedCaller{caller: c, interceptors: interceptors}
+}
+
+func (c *interceptedCaller) Call(ctx context.Context, method string, params, reply interface{}) error {
+	return chain(c.interceptors, c.caller.Call)(ctx, method, params, reply)
+}
+
+func (c *interceptedCaller) CallBatch(ctx context.Context, calls []batchCall) error {
+	methods := make([]Method, len(calls))
+	for i, call := range calls {
+		methods[i] = call.method
+	}
+	send := func(ctx context.Context, method string, params, reply interface{}) error {
+		return c.caller.CallBatch(ctx, calls)
+	}
+	return chain(c.interceptors, send)(ctx, batchMethod, methods, nil)
+}
+
+// ErrMethodNotAllowed is returned for calls refused by AllowMethods.
+var ErrMethodNotAllowed = errors.New("method not allowed")
+
+// AllowMethods returns an Interceptor refusing, with ErrMethodNotAllowed, calls of methods other than methods,
+// e.g. aria2.tellStatus; a system.multicall or a batch is refused if any of its calls is.
+func AllowMethods(methods ...string) Interceptor {
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[method] = true
+	}
+	return func(ctx context.Context, method string, params, reply interface{}, next Invoker) error {
+		if err := checkMethod(allowed, method, params); err != nil {
+			return err
+		}
+		return next(ctx, method, params, reply)
+	}
+}
+
+func checkMethod(allowed map[string]bool, method string, params interface{}) error {
+	var calls []Method
+	switch method {
+	case batchMethod:
+		calls, _ = params.([]Method)
+	case aria2Multicall:
+		if p, ok := params.([]interface{}); ok && len(p) == 1 {
+			calls, _ = p[0].([]Method)
+		}
+	default:
+		if !allowed[method] {
+			return fmt.Errorf("%w: %s", ErrMethodNotAllowed, method)
+		}
+		return nil
+	}
+	for _, call := range calls {
+		if !allowed[call.Name] {
+			return fmt.Errorf("%w: %s", ErrMethodNotAllowed, call.Name)
+		}
+	}
+	return nil
+}
+
+// RedactToken returns a copy of params with the secret token masked as "token:***", e.g. to log params;
+// params of system.multicall and batches are masked too. params itself is left as it is.
+func RedactToken(params interface{}) interface{} {
+	switch p := params.(type) {
+	case string:
+		if strings.HasPrefix(p, "token:") {
+			return "token:***"
+		}
+	case []interface{}:
+		r := make([]interface{}, len(p))
+		for i := range p {
+			r[i] = RedactToken(p[i])
+		}
+		return r
+	case []string:
+		r := make([]string, len(p))
+		for i := range p {
+			r[i] = RedactToken(p[i]).(string)
+		}
+		return r
+	case []Method:
+		r := make([]Method, len(p))
+		for i := range p {
+			r[i] = Method{Name: p[i].Name, Params: RedactToken(p[i].Params).([]interface{})}
+		}
+		return r
+	}
+	return params
+}
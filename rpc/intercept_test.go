@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestInterceptors(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		var mu sync.Mutex
+		var seen []string
+		record := func(name string) Interceptor {
+			return func(ctx context.Context, method string, params, reply interface{}, next Invoker) error {
+				mu.Lock()
+				seen = append(seen, name+" "+method)
+				mu.Unlock()
+				return next(ctx, method, params, reply)
+			}
+		}
+		c, err := New(context.Background(), uri, "secret", time.Second, nil,
+			WithInterceptors(record("outer"), ChainInterceptors(record("middle"))), WithInterceptors(record("inner")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAll(t, c)
+		mu.Lock()
+		seen = nil
+		mu.Unlock()
+		if _, err = c.GetVersion(); err != nil {
+			t.Error(err)
+		}
+		for _, mode := range batchModes {
+			b := c.Batch()
+			b.GetGlobalStat()
+			if err = mode.do(b, context.Background()); err != nil {
+				t.Errorf("%s %s: %v", uri, mode.name, err)
+			}
+		}
+		want := []string{"outer aria2.getVersion", "middle aria2.getVersion", "inner aria2.getVersion",
+			"outer system.multicall", "middle system.multicall", "inner system.multicall",
+			"outer batch", "middle batch", "inner batch"}
+		mu.Lock()
+		if !reflect.DeepEqual(seen, want) {
+			t.Errorf("%s: seen %q, want %q", uri, seen, want)
+		}
+		mu.Unlock()
+		c.Close()
+	}
+}
+
+func TestAllowMethods(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := NewWithOptions(context.Background(), srv.URL,
+		WithInterceptors(AllowMethods(aria2GetVersion, aria2TellActive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err = c.GetVersion(); err != nil {
+		t.Error(err)
+	}
+	if _, err = c.AddURI([]string{targetURL}); !errors.Is(err, ErrMethodNotAllowed) || !strings.Contains(err.Error(), aria2AddURI) {
+		t.Errorf("AddURI: %v", err)
+	}
+	for _, mode := range batchModes {
+		b := c.Batch()
+		b.TellActive()
+		if err = mode.do(b, context.Background()); err != nil {
+			t.Errorf("%s: %v", mode.name, err)
+		}
+		b.TellActive()
+		b.Shutdown()
+		if err = mode.do(b, context.Background()); !errors.Is(err, ErrMethodNotAllowed) {
+			t.Errorf("%s: %v", mode.name, err)
+		}
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	params := []interface{}{"token:secret", []string{"http://a"}, map[string]string{"dir": "/tmp"}}
+	methods := []Method{{Name: aria2TellActive, Params: []interface{}{"token:secret"}}}
+	if got, want := RedactToken(params), []interface{}{"token:***", []string{"http://a"}, map[string]string{"dir": "/tmp"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactToken(%v) = %v", params, got)
+	}
+	if params[0] != "token:secret" {
+		t.Error("params changed")
+	}
+	if got := RedactToken([]interface{}{methods}); !reflect.DeepEqual(got, []interface{}{[]Method{{Name: aria2TellActive, Params: []interface{}{"token:***"}}}}) {
+		t.Errorf("RedactToken(%v) = %v", methods, got)
+	}
+}
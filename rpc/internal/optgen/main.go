@@ -0,0 +1,204 @@
+// Command optgen generates the aria2 option catalog of package rpc, and typed setters of rpc.Option, from options.txt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Input file options not accepted by aria2.changeOption.
+var noChangeOption = map[string]bool{
+	"dry-run":                  true,
+	"metalink-base-uri":        true,
+	"parameterized-uri":        true,
+	"pause":                    true,
+	"piece-length":             true,
+	"rpc-save-upload-metadata": true,
+}
+
+// Input file options not accepted by aria2.changeGlobalOption.
+var noChangeGlobalOption = map[string]bool{
+	"checksum":    true,
+	"index-out":   true,
+	"out":         true,
+	"pause":       true,
+	"select-file": true,
+}
+
+var types = map[string]struct {
+	name, param, format string
+}{
+	"string": {"OptionString", "string", "v"},
+	"bool":   {"OptionBool", "bool", "strconv.FormatBool(v)"},
+	"int":    {"OptionInt", "int", "strconv.Itoa(v)"},
+	"size":   {"OptionSize", "Size", "v.String()"},
+	"float":  {"OptionFloat", "float64", "strconv.FormatFloat(v, 'f', -1, 64)"},
+	"enum":   {"OptionEnum", "string", "v"},
+	"list":   {"OptionList", "...string", "v"},
+}
+
+// initialisms are spelled in upper case in setter names.
+var initialisms = map[string]bool{
+	"dht": true, "dscp": true, "ftp": true, "http": true, "https": true, "id": true, "ip": true,
+	"lpd": true, "md": true, "rpc": true, "ssh": true, "tls": true, "uri": true, "utf8": true,
+}
+
+type option struct {
+	name, typ, def, scope, constraint string
+}
+
+func main() {
+	in := flag.String("in", "options.txt", "option table")
+	out := flag.String("out", "option_catalog.go", "generated file")
+	flag.Parse()
+	options, err := parse(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parse(name string) (options []option, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("%s:%d: %d fields, want 5", name, line, len(fields))
+		}
+		o := option{fields[0], fields[1], fields[2], fields[3], fields[4]}
+		if _, ok := types[o.typ]; !ok {
+			return nil, fmt.Errorf("%s:%d: unknown type %q", name, line, o.typ)
+		}
+		options = append(options, o)
+	}
+	return options, s.Err()
+}
+
+func generate(options []option) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by optgen from internal/optgen/options.txt; DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package rpc")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, `import "strconv"`)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "var optionCatalog = map[string]OptionSpec{")
+	for _, o := range options {
+		fields := []string{"Name: " + strconv.Quote(o.name), "Type: " + types[o.typ].name}
+		if o.def != "-" {
+			fields = append(fields, "Default: "+strconv.Quote(o.def))
+		}
+		if scope := scope(o); scope != "" {
+			fields = append(fields, "Scope: "+scope)
+		}
+		if o.constraint != "-" {
+			if o.typ == "enum" {
+				fields = append(fields, fmt.Sprintf("Values: %#v", strings.Split(o.constraint, "|")))
+			} else {
+				bounds := strings.SplitN(o.constraint, "-", 2)
+				if len(bounds) != 2 {
+					return nil, fmt.Errorf("%s: bad constraint %q", o.name, o.constraint)
+				}
+				for i, field := range []string{"Min", "Max"} {
+					if bounds[i] == "" {
+						continue
+					}
+					n, err := number(o.typ, bounds[i])
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", o.name, err)
+					}
+					if n != "0" && n != "0.0" {
+						fields = append(fields, field+": "+n)
+					}
+				}
+			}
+		}
+		fmt.Fprintf(&b, "%q: {%s},\n", o.name, strings.Join(fields, ", "))
+	}
+	fmt.Fprintln(&b, "}")
+	for _, o := range options {
+		t := types[o.typ]
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "// %s sets %s.\n", setter(o.name), o.name)
+		fmt.Fprintf(&b, "func (o Option) %s(v %s) Option {\n", setter(o.name), t.param)
+		fmt.Fprintf(&b, "o[%q] = %s\n", o.name, t.format)
+		fmt.Fprintln(&b, "return o")
+		fmt.Fprintln(&b, "}")
+	}
+	return format.Source(b.Bytes())
+}
+
+func scope(o option) string {
+	var scopes []string
+	input := strings.Contains(o.scope, "i")
+	if input {
+		scopes = append(scopes, "ScopeInputFile")
+	}
+	if input && !noChangeOption[o.name] {
+		scopes = append(scopes, "ScopeDownload")
+	}
+	if strings.Contains(o.scope, "a") {
+		scopes = append(scopes, "ScopeActive")
+	}
+	if strings.Contains(o.scope, "g") || (input && !noChangeGlobalOption[o.name]) {
+		scopes = append(scopes, "ScopeGlobal")
+	}
+	return strings.Join(scopes, " | ")
+}
+
+// number renders a bound of an option value, sizes in bytes.
+func number(typ, v string) (string, error) {
+	unit := int64(1)
+	switch {
+	case typ == "size" && strings.HasSuffix(v, "K"):
+		unit, v = 1<<10, strings.TrimSuffix(v, "K")
+	case typ == "size" && strings.HasSuffix(v, "M"):
+		unit, v = 1<<20, strings.TrimSuffix(v, "M")
+	}
+	if typ == "float" {
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "", err
+		}
+		return v, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(n*unit, 10), nil
+}
+
+func setter(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "-") {
+		if initialisms[word] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
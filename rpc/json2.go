@@ -57,6 +57,76 @@ func EncodeClientRequest(method string, args interface{}) (*bytes.Buffer, error)
 	return &buf, nil
 }
 
+// encodeClientBatch encodes reqs as a JSON-RPC batch request, i.e. an array of requests.
+func encodeClientBatch(reqs []*clientRequest) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(reqs); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// newBatchRequests returns the requests of calls, each with an id of its own.
+func newBatchRequests(calls []batchCall) []*clientRequest {
+	reqs := make([]*clientRequest, len(calls))
+	for i, call := range calls {
+		reqs[i] = &clientRequest{
+			Version: "2.0",
+			Method:  call.method.Name,
+			Params:  call.method.Params,
+			Id:      reqid(),
+		}
+	}
+	return reqs
+}
+
+// decodeClientBatch decodes the response body of a batch request of reqs, matching responses to calls by id;
+// the decoded results, or faults, are set to calls. A response which is not an array, e.g. to an invalid batch,
+// fails the batch as a whole.
+func decodeClientBatch(r io.Reader, reqs []*clientRequest, calls []batchCall) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+	if raw = bytes.TrimSpace(raw); len(raw) == 0 || raw[0] != '[' {
+		var c clientResponse
+		if err := json.Unmarshal(raw, &c); err == nil && c.Error != nil {
+			return c.decode(nil)
+		}
+		return errBatchResponse
+	}
+	var resps []clientResponse
+	if err := json.Unmarshal(raw, &resps); err != nil {
+		return err
+	}
+	resolveBatch(resps, reqs, calls)
+	return nil
+}
+
+// errBatchResponse is returned for a batch whose response is neither an array nor an error.
+var errBatchResponse = errors.New("batch response is not an array")
+
+// errNoBatchResponse is the Err of a call of a batch whose response lacks one for it.
+var errNoBatchResponse = errors.New("no response in batch")
+
+// resolveBatch sets the responses of resps, matched by id, to calls.
+func resolveBatch(resps []clientResponse, reqs []*clientRequest, calls []batchCall) {
+	byID := make(map[uint64]clientResponse, len(resps))
+	for _, resp := range resps {
+		if resp.Id != nil {
+			byID[*resp.Id] = resp
+		}
+	}
+	for i, req := range reqs {
+		resp, ok := byID[req.Id]
+		if !ok {
+			*calls[i].err = errNoBatchResponse
+			continue
+		}
+		*calls[i].err = resp.decode(calls[i].reply)
+	}
+}
+
 func (c clientResponse) decode(reply interface{}) error {
 	if c.Error != nil {
 		jsonErr := &Error{}
@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	LevelDebug Level = iota // every call and notification
+	LevelInfo               // connection state changes
+	LevelWarn               // failures the client recovers from, e.g. a lost websocket connection
+	LevelError              // failures the client does not recover from
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Field is a key-value pair attached to a log entry, e.g. the method or the GID it is about.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives the log entries of a client; see WithLogger.
+// Entries carry fields such as method, id (of the request), gid, latency, uri and err.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...Field) {}
+
+// NopLogger discards every entry; it is the logger of clients created without WithLogger.
+var NopLogger Logger = nopLogger{}
+
+type stdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+// NewStdLogger returns a Logger writing entries of level min and above to l, formatted as
+//
+//	level=warn msg="websocket read failed" uri=ws://localhost:6800/jsonrpc err="unexpected EOF"
+func NewStdLogger(l *log.Logger, min Level) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+func (s *stdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < s.min {
+		return
+	}
+	s.l.Output(2, formatEntry(level, msg, fields))
+}
+
+// PrintfLogger adapts a Printf-style function, e.g. log.Printf or the Printf method of another logger, to a Logger;
+// entries of every level are formatted as by NewStdLogger.
+type PrintfLogger func(format string, v ...interface{})
+
+// Log implements Logger.
+func (f PrintfLogger) Log(level Level, msg string, fields ...Field) {
+	f("%s", formatEntry(level, msg, fields))
+}
+
+func formatEntry(level Level, msg string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString("level=" + level.String() + " msg=" + logValue(msg))
+	for _, f := range fields {
+		b.WriteString(" " + f.Key + "=" + logValue(fmt.Sprint(f.Value)))
+	}
+	return b.String()
+}
+
+func logValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// logCall logs the outcome of a call of method, started at start; id is that of the request, if known.
+func logCall(l Logger, method string, id uint64, start time.Time, err *error) {
+	fields := []Field{{"method", method}}
+	if id != 0 {
+		fields = append(fields, Field{"id", id})
+	}
+	fields = append(fields, Field{"latency", time.Since(start)})
+	if *err != nil {
+		l.Log(LevelWarn, "call failed", append(fields, Field{"err", *err})...)
+		return
+	}
+	l.Log(LevelDebug, "call", fields...)
+}
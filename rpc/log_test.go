@@ -0,0 +1,28 @@
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStdLogger(log.New(&buf, "", 0), LevelInfo)
+	l.Log(LevelDebug, "call", Field{"method", "aria2.getVersion"})
+	l.Log(LevelWarn, "websocket read failed", Field{"uri", "ws://localhost:6800/jsonrpc"}, Field{"err", errors.New("unexpected EOF")}, Field{"gid", ""})
+	want := `level=warn msg="websocket read failed" uri=ws://localhost:6800/jsonrpc err="unexpected EOF" gid=""` + "\n"
+	if buf.String() != want {
+		t.Errorf("log = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintfLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var l Logger = PrintfLogger(log.New(&buf, "", 0).Printf)
+	l.Log(LevelDebug, "call", Field{"method", "aria2.getVersion"})
+	if want := "level=debug msg=call method=aria2.getVersion\n"; buf.String() != want {
+		t.Errorf("log = %q, want %q", buf.String(), want)
+	}
+}
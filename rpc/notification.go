@@ -1,9 +1,5 @@
 package rpc
 
-import (
-	"log"
-)
-
 type Event struct {
 	Gid string `json:"gid"` // GID of the download
 }
@@ -34,11 +30,56 @@ type Notifier interface {
 	OnBtDownloadComplete([]Event)
 }
 
-type DummyNotifier struct{}
+// notify dispatches a notification received from aria2 daemon to n.
+func notify(n Notifier, resp websocketResponse) {
+	switch resp.Method {
+	case "aria2.onDownloadStart":
+		n.OnDownloadStart(resp.Params)
+	case "aria2.onDownloadPause":
+		n.OnDownloadPause(resp.Params)
+	case "aria2.onDownloadStop":
+		n.OnDownloadStop(resp.Params)
+	case "aria2.onDownloadComplete":
+		n.OnDownloadComplete(resp.Params)
+	case "aria2.onDownloadError":
+		n.OnDownloadError(resp.Params)
+	case "aria2.onBtDownloadComplete":
+		n.OnBtDownloadComplete(resp.Params)
+	}
+}
+
+// notify dispatches a notification to the Notifier and the subscribers of the client.
+func (cfg *clientConfig) notify(resp websocketResponse) {
+	if _, ok := eventMethods[resp.Method]; !ok {
+		cfg.logger.Log(LevelWarn, "unexpected notification", Field{"method", resp.Method})
+		return
+	}
+	for _, e := range resp.Params {
+		cfg.logger.Log(LevelDebug, "notification", Field{"method", resp.Method}, Field{"gid", e.Gid})
+	}
+	if cfg.notifier != nil {
+		notify(cfg.notifier, resp)
+	}
+	cfg.events.publish(resp.Method, resp.Params)
+}
+
+// DummyNotifier logs notifications to Logger at LevelInfo, if set.
+type DummyNotifier struct {
+	Logger Logger
+}
+
+func (d DummyNotifier) log(msg string, events []Event) {
+	if d.Logger == nil {
+		return
+	}
+	for _, e := range events {
+		d.Logger.Log(LevelInfo, msg, Field{"gid", e.Gid})
+	}
+}
 
-func (DummyNotifier) OnDownloadStart(events []Event)      { log.Printf("%s started.", events) }
-func (DummyNotifier) OnDownloadPause(events []Event)      { log.Printf("%s paused.", events) }
-func (DummyNotifier) OnDownloadStop(events []Event)       { log.Printf("%s stopped.", events) }
-func (DummyNotifier) OnDownloadComplete(events []Event)   { log.Printf("%s completed.", events) }
-func (DummyNotifier) OnDownloadError(events []Event)      { log.Printf("%s error.", events) }
-func (DummyNotifier) OnBtDownloadComplete(events []Event) { log.Printf("bt %s completed.", events) }
+func (d DummyNotifier) OnDownloadStart(events []Event)      { d.log("download started", events) }
+func (d DummyNotifier) OnDownloadPause(events []Event)      { d.log("download paused", events) }
+func (d DummyNotifier) OnDownloadStop(events []Event)       { d.log("download stopped", events) }
+func (d DummyNotifier) OnDownloadComplete(events []Event)   { d.log("download completed", events) }
+func (d DummyNotifier) OnDownloadError(events []Event)      { d.log("download error", events) }
+func (d DummyNotifier) OnBtDownloadComplete(events []Event) { d.log("bt download completed", events) }
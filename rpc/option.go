@@ -0,0 +1,258 @@
+package rpc
+
+//go:generate go run ./internal/optgen -in internal/optgen/options.txt -out option_catalog.go
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OptionType is the type of the value of an aria2 option.
+type OptionType int
+
+const (
+	OptionString OptionType = iota // any string
+	OptionBool                     // true or false
+	OptionInt                      // decimal integer
+	OptionSize                     // amount of bytes, optionally suffixed with K or M, e.g. 1M
+	OptionFloat                    // decimal number, e.g. 1.0
+	OptionEnum                     // one of OptionSpec.Values
+	OptionList                     // string which may be given more than once, e.g. header
+)
+
+func (t OptionType) String() string {
+	switch t {
+	case OptionString:
+		return "string"
+	case OptionBool:
+		return "bool"
+	case OptionInt:
+		return "int"
+	case OptionSize:
+		return "size"
+	case OptionFloat:
+		return "float"
+	case OptionEnum:
+		return "enum"
+	case OptionList:
+		return "list"
+	}
+	return "unknown"
+}
+
+// OptionScope tells where an aria2 option is accepted; options of zero scope are command-line only.
+type OptionScope uint
+
+const (
+	// ScopeInputFile options are accepted in the input file, and by aria2.addUri, aria2.addTorrent and aria2.addMetalink.
+	ScopeInputFile OptionScope = 1 << iota
+	// ScopeDownload options are changeable by aria2.changeOption.
+	ScopeDownload
+	// ScopeActive options are changeable by aria2.changeOption without restarting an active download.
+	ScopeActive
+	// ScopeGlobal options are changeable by aria2.changeGlobalOption.
+	ScopeGlobal
+)
+
+// OptionSpec describes an aria2 option.
+type OptionSpec struct {
+	Name    string
+	Type    OptionType
+	Default string // default value; empty if there is none
+	Scope   OptionScope
+	Values  []string // allowed values of OptionEnum
+	Min     float64  // lower bound of OptionInt, OptionSize (in bytes) and OptionFloat values
+	Max     float64  // upper bound of OptionInt, OptionSize (in bytes) and OptionFloat values; 0 means unbounded
+}
+
+var (
+	// ErrUnknownOption is wrapped by an OptionError about an option aria2 does not know.
+	ErrUnknownOption = errors.New("unknown option")
+	// ErrOptionScope is wrapped by an OptionError about an option which is not accepted by the method called.
+	ErrOptionScope = errors.New("option not allowed here")
+)
+
+// OptionError reports an invalid option; it is returned before anything is sent to aria2 daemon.
+type OptionError struct {
+	Name string
+	Err  error
+}
+
+func (e *OptionError) Error() string { return "option " + e.Name + ": " + e.Err.Error() }
+
+func (e *OptionError) Unwrap() error { return e.Err }
+
+// LookupOption returns the specification of the aria2 option of name.
+func LookupOption(name string) (spec OptionSpec, ok bool) {
+	spec, ok = optionCatalog[name]
+	return
+}
+
+// OptionCatalog returns the specifications of all known aria2 options, ordered by name.
+func OptionCatalog() []OptionSpec {
+	specs := make([]OptionSpec, 0, len(optionCatalog))
+	for _, spec := range optionCatalog {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Validate checks that value is acceptable for the option.
+// aria2 takes option values as strings; values of OptionList may also be []string.
+func (s OptionSpec) Validate(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return s.validate(v)
+	case []string:
+		if s.Type != OptionList {
+			break
+		}
+		for _, e := range v {
+			if err := s.validate(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if s.Type != OptionList {
+			break
+		}
+		for _, e := range v {
+			if err := s.Validate(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("value of type %T, want string", value)
+}
+
+func (s OptionSpec) validate(v string) error {
+	var n float64
+	switch s.Type {
+	case OptionBool:
+		if v != "true" && v != "false" {
+			return fmt.Errorf("%q is not true or false", v)
+		}
+		return nil
+	case OptionEnum:
+		for _, value := range s.Values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", v, strings.Join(s.Values, ", "))
+	case OptionInt:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		n = float64(i)
+	case OptionSize:
+		size, err := ParseSize(v)
+		if err != nil {
+			return err
+		}
+		n = float64(size)
+	case OptionFloat:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		n = f
+	default:
+		return nil
+	}
+	if n < s.Min || (s.Max != 0 && n > s.Max) {
+		return fmt.Errorf("%s is out of range", v)
+	}
+	return nil
+}
+
+// Validate checks the names, values and scope of options; option names are checked in order.
+func (o Option) Validate(scope OptionScope) error {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec, ok := optionCatalog[name]
+		if !ok {
+			return &OptionError{Name: name, Err: ErrUnknownOption}
+		}
+		if spec.Scope&scope != scope {
+			return &OptionError{Name: name, Err: ErrOptionScope}
+		}
+		if err := spec.Validate(o[name]); err != nil {
+			return &OptionError{Name: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// validateOptions validates every set of options among args, e.g. the options ...interface{} of AddURI.
+func validateOptions(scope OptionScope, args ...interface{}) error {
+	for _, arg := range args {
+		var o Option
+		switch v := arg.(type) {
+		case Option:
+			o = v
+		case map[string]interface{}:
+			o = v
+		case map[string]string:
+			o = make(Option, len(v))
+			for name, value := range v {
+				o[name] = value
+			}
+		default:
+			continue
+		}
+		if err := o.Validate(scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size is an amount of bytes, as taken by options of OptionSize.
+type Size int64
+
+const (
+	KiB Size = 1 << 10
+	MiB Size = 1 << 20
+)
+
+// String formats s the way aria2 parses it, in the largest unit that divides it.
+func (s Size) String() string {
+	switch {
+	case s != 0 && s%MiB == 0:
+		return strconv.FormatInt(int64(s/MiB), 10) + "M"
+	case s != 0 && s%KiB == 0:
+		return strconv.FormatInt(int64(s/KiB), 10) + "K"
+	}
+	return strconv.FormatInt(int64(s), 10)
+}
+
+// ParseSize parses an amount of bytes optionally suffixed with K (1024) or M (1024K), e.g. 1M.
+func ParseSize(v string) (Size, error) {
+	unit := Size(1)
+	s := v
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K', 'k':
+			unit, s = KiB, s[:n-1]
+		case 'M', 'm':
+			unit, s = MiB, s[:n-1]
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 63)
+	if err != nil || Size(n) > (1<<63-1)/unit {
+		return 0, fmt.Errorf("%q is not a size", v)
+	}
+	return Size(n) * unit, nil
+}
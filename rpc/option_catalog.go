@@ -0,0 +1,1359 @@
+// Code generated by optgen from internal/optgen/options.txt; DO NOT EDIT.
+
+package rpc
+
+import "strconv"
+
+var optionCatalog = map[string]OptionSpec{
+	"all-proxy":                        {Name: "all-proxy", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"all-proxy-passwd":                 {Name: "all-proxy-passwd", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"all-proxy-user":                   {Name: "all-proxy-user", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"allow-overwrite":                  {Name: "allow-overwrite", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"allow-piece-length-change":        {Name: "allow-piece-length-change", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"always-resume":                    {Name: "always-resume", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"async-dns":                        {Name: "async-dns", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"async-dns-server":                 {Name: "async-dns-server", Type: OptionString},
+	"auto-file-renaming":               {Name: "auto-file-renaming", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"auto-save-interval":               {Name: "auto-save-interval", Type: OptionInt, Default: "60", Max: 600},
+	"bt-detach-seed-only":              {Name: "bt-detach-seed-only", Type: OptionBool, Default: "false"},
+	"bt-enable-hook-after-hash-check":  {Name: "bt-enable-hook-after-hash-check", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-enable-lpd":                    {Name: "bt-enable-lpd", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-exclude-tracker":               {Name: "bt-exclude-tracker", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-external-ip":                   {Name: "bt-external-ip", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-force-encryption":              {Name: "bt-force-encryption", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-hash-check-seed":               {Name: "bt-hash-check-seed", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-load-saved-metadata":           {Name: "bt-load-saved-metadata", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-lpd-interface":                 {Name: "bt-lpd-interface", Type: OptionString},
+	"bt-max-open-files":                {Name: "bt-max-open-files", Type: OptionInt, Default: "100", Scope: ScopeGlobal, Min: 1},
+	"bt-max-peers":                     {Name: "bt-max-peers", Type: OptionInt, Default: "55", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"bt-metadata-only":                 {Name: "bt-metadata-only", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-min-crypto-level":              {Name: "bt-min-crypto-level", Type: OptionEnum, Default: "plain", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"plain", "arc4"}},
+	"bt-prioritize-piece":              {Name: "bt-prioritize-piece", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-remove-unselected-file":        {Name: "bt-remove-unselected-file", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"bt-request-peer-speed-limit":      {Name: "bt-request-peer-speed-limit", Type: OptionSize, Default: "50K", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"bt-require-crypto":                {Name: "bt-require-crypto", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-save-metadata":                 {Name: "bt-save-metadata", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-seed-unverified":               {Name: "bt-seed-unverified", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-stop-timeout":                  {Name: "bt-stop-timeout", Type: OptionInt, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-tracker":                       {Name: "bt-tracker", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-tracker-connect-timeout":       {Name: "bt-tracker-connect-timeout", Type: OptionInt, Default: "60", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1, Max: 600},
+	"bt-tracker-interval":              {Name: "bt-tracker-interval", Type: OptionInt, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"bt-tracker-timeout":               {Name: "bt-tracker-timeout", Type: OptionInt, Default: "60", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1, Max: 600},
+	"ca-certificate":                   {Name: "ca-certificate", Type: OptionString},
+	"certificate":                      {Name: "certificate", Type: OptionString},
+	"check-certificate":                {Name: "check-certificate", Type: OptionBool, Default: "true"},
+	"check-integrity":                  {Name: "check-integrity", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"checksum":                         {Name: "checksum", Type: OptionString, Scope: ScopeInputFile | ScopeDownload},
+	"conditional-get":                  {Name: "conditional-get", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"conf-path":                        {Name: "conf-path", Type: OptionString},
+	"connect-timeout":                  {Name: "connect-timeout", Type: OptionInt, Default: "60", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1, Max: 600},
+	"console-log-level":                {Name: "console-log-level", Type: OptionEnum, Default: "notice", Values: []string{"debug", "info", "notice", "warn", "error"}},
+	"content-disposition-default-utf8": {Name: "content-disposition-default-utf8", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"continue":                         {Name: "continue", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"daemon":                           {Name: "daemon", Type: OptionBool, Default: "false"},
+	"deferred-input":                   {Name: "deferred-input", Type: OptionBool, Default: "false"},
+	"dht-entry-point":                  {Name: "dht-entry-point", Type: OptionString},
+	"dht-entry-point6":                 {Name: "dht-entry-point6", Type: OptionString},
+	"dht-file-path":                    {Name: "dht-file-path", Type: OptionString},
+	"dht-file-path6":                   {Name: "dht-file-path6", Type: OptionString},
+	"dht-listen-addr6":                 {Name: "dht-listen-addr6", Type: OptionString},
+	"dht-listen-port":                  {Name: "dht-listen-port", Type: OptionString, Default: "6881-6999"},
+	"dht-message-timeout":              {Name: "dht-message-timeout", Type: OptionInt, Default: "10", Min: 1, Max: 60},
+	"dir":                              {Name: "dir", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"disable-ipv6":                     {Name: "disable-ipv6", Type: OptionBool, Default: "false"},
+	"disk-cache":                       {Name: "disk-cache", Type: OptionSize, Default: "16M"},
+	"download-result":                  {Name: "download-result", Type: OptionEnum, Default: "default", Scope: ScopeGlobal, Values: []string{"default", "full", "hide"}},
+	"dry-run":                          {Name: "dry-run", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeGlobal},
+	"dscp":                             {Name: "dscp", Type: OptionInt, Max: 63},
+	"enable-color":                     {Name: "enable-color", Type: OptionBool, Default: "true"},
+	"enable-dht":                       {Name: "enable-dht", Type: OptionBool, Default: "true"},
+	"enable-dht6":                      {Name: "enable-dht6", Type: OptionBool, Default: "false"},
+	"enable-http-keep-alive":           {Name: "enable-http-keep-alive", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"enable-http-pipelining":           {Name: "enable-http-pipelining", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"enable-mmap":                      {Name: "enable-mmap", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"enable-peer-exchange":             {Name: "enable-peer-exchange", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"enable-rpc":                       {Name: "enable-rpc", Type: OptionBool, Default: "false"},
+	"event-poll":                       {Name: "event-poll", Type: OptionEnum, Values: []string{"epoll", "kqueue", "port", "poll", "select"}},
+	"file-allocation":                  {Name: "file-allocation", Type: OptionEnum, Default: "prealloc", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"none", "prealloc", "trunc", "falloc"}},
+	"follow-metalink":                  {Name: "follow-metalink", Type: OptionEnum, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"true", "false", "mem"}},
+	"follow-torrent":                   {Name: "follow-torrent", Type: OptionEnum, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"true", "false", "mem"}},
+	"force-save":                       {Name: "force-save", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"force-sequential":                 {Name: "force-sequential", Type: OptionBool, Default: "false"},
+	"ftp-passwd":                       {Name: "ftp-passwd", Type: OptionString, Default: "ARIA2USER@", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-pasv":                         {Name: "ftp-pasv", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-proxy":                        {Name: "ftp-proxy", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-proxy-passwd":                 {Name: "ftp-proxy-passwd", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-proxy-user":                   {Name: "ftp-proxy-user", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-reuse-connection":             {Name: "ftp-reuse-connection", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"ftp-type":                         {Name: "ftp-type", Type: OptionEnum, Default: "binary", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"binary", "ascii"}},
+	"ftp-user":                         {Name: "ftp-user", Type: OptionString, Default: "anonymous", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"gid":                              {Name: "gid", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"hash-check-only":                  {Name: "hash-check-only", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"header":                           {Name: "header", Type: OptionList, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-accept-gzip":                 {Name: "http-accept-gzip", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-auth-challenge":              {Name: "http-auth-challenge", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-no-cache":                    {Name: "http-no-cache", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-passwd":                      {Name: "http-passwd", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-proxy":                       {Name: "http-proxy", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-proxy-passwd":                {Name: "http-proxy-passwd", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-proxy-user":                  {Name: "http-proxy-user", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"http-user":                        {Name: "http-user", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"https-proxy":                      {Name: "https-proxy", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"https-proxy-passwd":               {Name: "https-proxy-passwd", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"https-proxy-user":                 {Name: "https-proxy-user", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"human-readable":                   {Name: "human-readable", Type: OptionBool, Default: "true"},
+	"index-out":                        {Name: "index-out", Type: OptionList, Scope: ScopeInputFile | ScopeDownload},
+	"input-file":                       {Name: "input-file", Type: OptionString},
+	"interface":                        {Name: "interface", Type: OptionString},
+	"keep-unfinished-download-result":  {Name: "keep-unfinished-download-result", Type: OptionBool, Default: "true", Scope: ScopeGlobal},
+	"listen-port":                      {Name: "listen-port", Type: OptionString, Default: "6881-6999"},
+	"load-cookies":                     {Name: "load-cookies", Type: OptionString},
+	"log":                              {Name: "log", Type: OptionString, Scope: ScopeGlobal},
+	"log-level":                        {Name: "log-level", Type: OptionEnum, Default: "debug", Scope: ScopeGlobal, Values: []string{"debug", "info", "notice", "warn", "error"}},
+	"lowest-speed-limit":               {Name: "lowest-speed-limit", Type: OptionSize, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"max-concurrent-downloads":         {Name: "max-concurrent-downloads", Type: OptionInt, Default: "5", Scope: ScopeGlobal, Min: 1},
+	"max-connection-per-server":        {Name: "max-connection-per-server", Type: OptionInt, Default: "1", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1, Max: 16},
+	"max-download-limit":               {Name: "max-download-limit", Type: OptionSize, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"max-download-result":              {Name: "max-download-result", Type: OptionInt, Default: "1000", Scope: ScopeGlobal},
+	"max-file-not-found":               {Name: "max-file-not-found", Type: OptionInt, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"max-mmap-limit":                   {Name: "max-mmap-limit", Type: OptionSize, Default: "9223372036854775807", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"max-overall-download-limit":       {Name: "max-overall-download-limit", Type: OptionSize, Default: "0", Scope: ScopeGlobal},
+	"max-overall-upload-limit":         {Name: "max-overall-upload-limit", Type: OptionSize, Default: "0", Scope: ScopeGlobal},
+	"max-resume-failure-tries":         {Name: "max-resume-failure-tries", Type: OptionInt, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"max-tries":                        {Name: "max-tries", Type: OptionInt, Default: "5", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"max-upload-limit":                 {Name: "max-upload-limit", Type: OptionSize, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeActive | ScopeGlobal},
+	"metalink-base-uri":                {Name: "metalink-base-uri", Type: OptionString, Scope: ScopeInputFile | ScopeGlobal},
+	"metalink-enable-unique-protocol":  {Name: "metalink-enable-unique-protocol", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"metalink-file":                    {Name: "metalink-file", Type: OptionString},
+	"metalink-language":                {Name: "metalink-language", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"metalink-location":                {Name: "metalink-location", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"metalink-os":                      {Name: "metalink-os", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"metalink-preferred-protocol":      {Name: "metalink-preferred-protocol", Type: OptionEnum, Default: "none", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"http", "https", "ftp", "none"}},
+	"metalink-version":                 {Name: "metalink-version", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"min-split-size":                   {Name: "min-split-size", Type: OptionSize, Default: "20M", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1048576, Max: 1073741824},
+	"min-tls-version":                  {Name: "min-tls-version", Type: OptionEnum, Default: "TLSv1.2", Values: []string{"TLSv1.1", "TLSv1.2", "TLSv1.3"}},
+	"multiple-interface":               {Name: "multiple-interface", Type: OptionString},
+	"netrc-path":                       {Name: "netrc-path", Type: OptionString},
+	"no-conf":                          {Name: "no-conf", Type: OptionBool, Default: "false"},
+	"no-file-allocation-limit":         {Name: "no-file-allocation-limit", Type: OptionSize, Default: "5M", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"no-netrc":                         {Name: "no-netrc", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"no-proxy":                         {Name: "no-proxy", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"on-bt-download-complete":          {Name: "on-bt-download-complete", Type: OptionString},
+	"on-download-complete":             {Name: "on-download-complete", Type: OptionString},
+	"on-download-error":                {Name: "on-download-error", Type: OptionString},
+	"on-download-pause":                {Name: "on-download-pause", Type: OptionString},
+	"on-download-start":                {Name: "on-download-start", Type: OptionString},
+	"on-download-stop":                 {Name: "on-download-stop", Type: OptionString},
+	"optimize-concurrent-downloads":    {Name: "optimize-concurrent-downloads", Type: OptionString, Default: "false", Scope: ScopeGlobal},
+	"out":                              {Name: "out", Type: OptionString, Scope: ScopeInputFile | ScopeDownload},
+	"parameterized-uri":                {Name: "parameterized-uri", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeGlobal},
+	"pause":                            {Name: "pause", Type: OptionBool, Default: "false", Scope: ScopeInputFile},
+	"pause-metadata":                   {Name: "pause-metadata", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"peer-agent":                       {Name: "peer-agent", Type: OptionString, Default: "aria2/1.36.0"},
+	"peer-id-prefix":                   {Name: "peer-id-prefix", Type: OptionString, Default: "A2-1-36-0-"},
+	"piece-length":                     {Name: "piece-length", Type: OptionSize, Default: "1M", Scope: ScopeInputFile | ScopeGlobal, Min: 1048576, Max: 1073741824},
+	"private-key":                      {Name: "private-key", Type: OptionString},
+	"proxy-method":                     {Name: "proxy-method", Type: OptionEnum, Default: "get", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"get", "tunnel"}},
+	"quiet":                            {Name: "quiet", Type: OptionBool, Default: "false"},
+	"realtime-chunk-checksum":          {Name: "realtime-chunk-checksum", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"referer":                          {Name: "referer", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"remote-time":                      {Name: "remote-time", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"remove-control-file":              {Name: "remove-control-file", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"retry-wait":                       {Name: "retry-wait", Type: OptionInt, Default: "0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Max: 600},
+	"reuse-uri":                        {Name: "reuse-uri", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"rlimit-nofile":                    {Name: "rlimit-nofile", Type: OptionInt, Min: 1},
+	"rpc-allow-origin-all":             {Name: "rpc-allow-origin-all", Type: OptionBool, Default: "false"},
+	"rpc-certificate":                  {Name: "rpc-certificate", Type: OptionString},
+	"rpc-listen-all":                   {Name: "rpc-listen-all", Type: OptionBool, Default: "false"},
+	"rpc-listen-port":                  {Name: "rpc-listen-port", Type: OptionInt, Default: "6800", Min: 1024, Max: 65535},
+	"rpc-max-request-size":             {Name: "rpc-max-request-size", Type: OptionSize, Default: "2M"},
+	"rpc-passwd":                       {Name: "rpc-passwd", Type: OptionString},
+	"rpc-private-key":                  {Name: "rpc-private-key", Type: OptionString},
+	"rpc-save-upload-metadata":         {Name: "rpc-save-upload-metadata", Type: OptionBool, Default: "true", Scope: ScopeInputFile | ScopeGlobal},
+	"rpc-secret":                       {Name: "rpc-secret", Type: OptionString},
+	"rpc-secure":                       {Name: "rpc-secure", Type: OptionBool, Default: "false"},
+	"rpc-user":                         {Name: "rpc-user", Type: OptionString},
+	"save-cookies":                     {Name: "save-cookies", Type: OptionString, Scope: ScopeGlobal},
+	"save-not-found":                   {Name: "save-not-found", Type: OptionBool, Default: "true"},
+	"save-session":                     {Name: "save-session", Type: OptionString, Scope: ScopeGlobal},
+	"save-session-interval":            {Name: "save-session-interval", Type: OptionInt, Default: "0"},
+	"seed-ratio":                       {Name: "seed-ratio", Type: OptionFloat, Default: "1.0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"seed-time":                        {Name: "seed-time", Type: OptionFloat, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"select-file":                      {Name: "select-file", Type: OptionString, Scope: ScopeInputFile | ScopeDownload},
+	"server-stat-if":                   {Name: "server-stat-if", Type: OptionString},
+	"server-stat-of":                   {Name: "server-stat-of", Type: OptionString, Scope: ScopeGlobal},
+	"server-stat-timeout":              {Name: "server-stat-timeout", Type: OptionInt, Default: "86400"},
+	"show-console-readout":             {Name: "show-console-readout", Type: OptionBool, Default: "true"},
+	"show-files":                       {Name: "show-files", Type: OptionBool, Default: "false"},
+	"socket-recv-buffer-size":          {Name: "socket-recv-buffer-size", Type: OptionSize, Default: "0", Max: 16777216},
+	"split":                            {Name: "split", Type: OptionInt, Default: "5", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1},
+	"ssh-host-key-md":                  {Name: "ssh-host-key-md", Type: OptionString, Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"stop":                             {Name: "stop", Type: OptionInt, Default: "0"},
+	"stop-with-process":                {Name: "stop-with-process", Type: OptionInt},
+	"stream-piece-selector":            {Name: "stream-piece-selector", Type: OptionEnum, Default: "default", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"default", "inorder", "random", "geom"}},
+	"summary-interval":                 {Name: "summary-interval", Type: OptionInt, Default: "60"},
+	"timeout":                          {Name: "timeout", Type: OptionInt, Default: "60", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Min: 1, Max: 600},
+	"torrent-file":                     {Name: "torrent-file", Type: OptionString},
+	"truncate-console-readout":         {Name: "truncate-console-readout", Type: OptionBool, Default: "true"},
+	"uri-selector":                     {Name: "uri-selector", Type: OptionEnum, Default: "feedback", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal, Values: []string{"inorder", "feedback", "adaptive"}},
+	"use-head":                         {Name: "use-head", Type: OptionBool, Default: "false", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+	"user-agent":                       {Name: "user-agent", Type: OptionString, Default: "aria2/1.36.0", Scope: ScopeInputFile | ScopeDownload | ScopeGlobal},
+}
+
+// AllProxy sets all-proxy.
+func (o Option) AllProxy(v string) Option {
+	o["all-proxy"] = v
+	return o
+}
+
+// AllProxyPasswd sets all-proxy-passwd.
+func (o Option) AllProxyPasswd(v string) Option {
+	o["all-proxy-passwd"] = v
+	return o
+}
+
+// AllProxyUser sets all-proxy-user.
+func (o Option) AllProxyUser(v string) Option {
+	o["all-proxy-user"] = v
+	return o
+}
+
+// AllowOverwrite sets allow-overwrite.
+func (o Option) AllowOverwrite(v bool) Option {
+	o["allow-overwrite"] = strconv.FormatBool(v)
+	return o
+}
+
+// AllowPieceLengthChange sets allow-piece-length-change.
+func (o Option) AllowPieceLengthChange(v bool) Option {
+	o["allow-piece-length-change"] = strconv.FormatBool(v)
+	return o
+}
+
+// AlwaysResume sets always-resume.
+func (o Option) AlwaysResume(v bool) Option {
+	o["always-resume"] = strconv.FormatBool(v)
+	return o
+}
+
+// AsyncDns sets async-dns.
+func (o Option) AsyncDns(v bool) Option {
+	o["async-dns"] = strconv.FormatBool(v)
+	return o
+}
+
+// AsyncDnsServer sets async-dns-server.
+func (o Option) AsyncDnsServer(v string) Option {
+	o["async-dns-server"] = v
+	return o
+}
+
+// AutoFileRenaming sets auto-file-renaming.
+func (o Option) AutoFileRenaming(v bool) Option {
+	o["auto-file-renaming"] = strconv.FormatBool(v)
+	return o
+}
+
+// AutoSaveInterval sets auto-save-interval.
+func (o Option) AutoSaveInterval(v int) Option {
+	o["auto-save-interval"] = strconv.Itoa(v)
+	return o
+}
+
+// BtDetachSeedOnly sets bt-detach-seed-only.
+func (o Option) BtDetachSeedOnly(v bool) Option {
+	o["bt-detach-seed-only"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtEnableHookAfterHashCheck sets bt-enable-hook-after-hash-check.
+func (o Option) BtEnableHookAfterHashCheck(v bool) Option {
+	o["bt-enable-hook-after-hash-check"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtEnableLPD sets bt-enable-lpd.
+func (o Option) BtEnableLPD(v bool) Option {
+	o["bt-enable-lpd"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtExcludeTracker sets bt-exclude-tracker.
+func (o Option) BtExcludeTracker(v string) Option {
+	o["bt-exclude-tracker"] = v
+	return o
+}
+
+// BtExternalIP sets bt-external-ip.
+func (o Option) BtExternalIP(v string) Option {
+	o["bt-external-ip"] = v
+	return o
+}
+
+// BtForceEncryption sets bt-force-encryption.
+func (o Option) BtForceEncryption(v bool) Option {
+	o["bt-force-encryption"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtHashCheckSeed sets bt-hash-check-seed.
+func (o Option) BtHashCheckSeed(v bool) Option {
+	o["bt-hash-check-seed"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtLoadSavedMetadata sets bt-load-saved-metadata.
+func (o Option) BtLoadSavedMetadata(v bool) Option {
+	o["bt-load-saved-metadata"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtLPDInterface sets bt-lpd-interface.
+func (o Option) BtLPDInterface(v string) Option {
+	o["bt-lpd-interface"] = v
+	return o
+}
+
+// BtMaxOpenFiles sets bt-max-open-files.
+func (o Option) BtMaxOpenFiles(v int) Option {
+	o["bt-max-open-files"] = strconv.Itoa(v)
+	return o
+}
+
+// BtMaxPeers sets bt-max-peers.
+func (o Option) BtMaxPeers(v int) Option {
+	o["bt-max-peers"] = strconv.Itoa(v)
+	return o
+}
+
+// BtMetadataOnly sets bt-metadata-only.
+func (o Option) BtMetadataOnly(v bool) Option {
+	o["bt-metadata-only"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtMinCryptoLevel sets bt-min-crypto-level.
+func (o Option) BtMinCryptoLevel(v string) Option {
+	o["bt-min-crypto-level"] = v
+	return o
+}
+
+// BtPrioritizePiece sets bt-prioritize-piece.
+func (o Option) BtPrioritizePiece(v string) Option {
+	o["bt-prioritize-piece"] = v
+	return o
+}
+
+// BtRemoveUnselectedFile sets bt-remove-unselected-file.
+func (o Option) BtRemoveUnselectedFile(v bool) Option {
+	o["bt-remove-unselected-file"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtRequestPeerSpeedLimit sets bt-request-peer-speed-limit.
+func (o Option) BtRequestPeerSpeedLimit(v Size) Option {
+	o["bt-request-peer-speed-limit"] = v.String()
+	return o
+}
+
+// BtRequireCrypto sets bt-require-crypto.
+func (o Option) BtRequireCrypto(v bool) Option {
+	o["bt-require-crypto"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtSaveMetadata sets bt-save-metadata.
+func (o Option) BtSaveMetadata(v bool) Option {
+	o["bt-save-metadata"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtSeedUnverified sets bt-seed-unverified.
+func (o Option) BtSeedUnverified(v bool) Option {
+	o["bt-seed-unverified"] = strconv.FormatBool(v)
+	return o
+}
+
+// BtStopTimeout sets bt-stop-timeout.
+func (o Option) BtStopTimeout(v int) Option {
+	o["bt-stop-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// BtTracker sets bt-tracker.
+func (o Option) BtTracker(v string) Option {
+	o["bt-tracker"] = v
+	return o
+}
+
+// BtTrackerConnectTimeout sets bt-tracker-connect-timeout.
+func (o Option) BtTrackerConnectTimeout(v int) Option {
+	o["bt-tracker-connect-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// BtTrackerInterval sets bt-tracker-interval.
+func (o Option) BtTrackerInterval(v int) Option {
+	o["bt-tracker-interval"] = strconv.Itoa(v)
+	return o
+}
+
+// BtTrackerTimeout sets bt-tracker-timeout.
+func (o Option) BtTrackerTimeout(v int) Option {
+	o["bt-tracker-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// CaCertificate sets ca-certificate.
+func (o Option) CaCertificate(v string) Option {
+	o["ca-certificate"] = v
+	return o
+}
+
+// Certificate sets certificate.
+func (o Option) Certificate(v string) Option {
+	o["certificate"] = v
+	return o
+}
+
+// CheckCertificate sets check-certificate.
+func (o Option) CheckCertificate(v bool) Option {
+	o["check-certificate"] = strconv.FormatBool(v)
+	return o
+}
+
+// CheckIntegrity sets check-integrity.
+func (o Option) CheckIntegrity(v bool) Option {
+	o["check-integrity"] = strconv.FormatBool(v)
+	return o
+}
+
+// Checksum sets checksum.
+func (o Option) Checksum(v string) Option {
+	o["checksum"] = v
+	return o
+}
+
+// ConditionalGet sets conditional-get.
+func (o Option) ConditionalGet(v bool) Option {
+	o["conditional-get"] = strconv.FormatBool(v)
+	return o
+}
+
+// ConfPath sets conf-path.
+func (o Option) ConfPath(v string) Option {
+	o["conf-path"] = v
+	return o
+}
+
+// ConnectTimeout sets connect-timeout.
+func (o Option) ConnectTimeout(v int) Option {
+	o["connect-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// ConsoleLogLevel sets console-log-level.
+func (o Option) ConsoleLogLevel(v string) Option {
+	o["console-log-level"] = v
+	return o
+}
+
+// ContentDispositionDefaultUTF8 sets content-disposition-default-utf8.
+func (o Option) ContentDispositionDefaultUTF8(v bool) Option {
+	o["content-disposition-default-utf8"] = strconv.FormatBool(v)
+	return o
+}
+
+// Continue sets continue.
+func (o Option) Continue(v bool) Option {
+	o["continue"] = strconv.FormatBool(v)
+	return o
+}
+
+// Daemon sets daemon.
+func (o Option) Daemon(v bool) Option {
+	o["daemon"] = strconv.FormatBool(v)
+	return o
+}
+
+// DeferredInput sets deferred-input.
+func (o Option) DeferredInput(v bool) Option {
+	o["deferred-input"] = strconv.FormatBool(v)
+	return o
+}
+
+// DHTEntryPoint sets dht-entry-point.
+func (o Option) DHTEntryPoint(v string) Option {
+	o["dht-entry-point"] = v
+	return o
+}
+
+// DHTEntryPoint6 sets dht-entry-point6.
+func (o Option) DHTEntryPoint6(v string) Option {
+	o["dht-entry-point6"] = v
+	return o
+}
+
+// DHTFilePath sets dht-file-path.
+func (o Option) DHTFilePath(v string) Option {
+	o["dht-file-path"] = v
+	return o
+}
+
+// DHTFilePath6 sets dht-file-path6.
+func (o Option) DHTFilePath6(v string) Option {
+	o["dht-file-path6"] = v
+	return o
+}
+
+// DHTListenAddr6 sets dht-listen-addr6.
+func (o Option) DHTListenAddr6(v string) Option {
+	o["dht-listen-addr6"] = v
+	return o
+}
+
+// DHTListenPort sets dht-listen-port.
+func (o Option) DHTListenPort(v string) Option {
+	o["dht-listen-port"] = v
+	return o
+}
+
+// DHTMessageTimeout sets dht-message-timeout.
+func (o Option) DHTMessageTimeout(v int) Option {
+	o["dht-message-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// Dir sets dir.
+func (o Option) Dir(v string) Option {
+	o["dir"] = v
+	return o
+}
+
+// DisableIpv6 sets disable-ipv6.
+func (o Option) DisableIpv6(v bool) Option {
+	o["disable-ipv6"] = strconv.FormatBool(v)
+	return o
+}
+
+// DiskCache sets disk-cache.
+func (o Option) DiskCache(v Size) Option {
+	o["disk-cache"] = v.String()
+	return o
+}
+
+// DownloadResult sets download-result.
+func (o Option) DownloadResult(v string) Option {
+	o["download-result"] = v
+	return o
+}
+
+// DryRun sets dry-run.
+func (o Option) DryRun(v bool) Option {
+	o["dry-run"] = strconv.FormatBool(v)
+	return o
+}
+
+// DSCP sets dscp.
+func (o Option) DSCP(v int) Option {
+	o["dscp"] = strconv.Itoa(v)
+	return o
+}
+
+// EnableColor sets enable-color.
+func (o Option) EnableColor(v bool) Option {
+	o["enable-color"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableDHT sets enable-dht.
+func (o Option) EnableDHT(v bool) Option {
+	o["enable-dht"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableDht6 sets enable-dht6.
+func (o Option) EnableDht6(v bool) Option {
+	o["enable-dht6"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableHTTPKeepAlive sets enable-http-keep-alive.
+func (o Option) EnableHTTPKeepAlive(v bool) Option {
+	o["enable-http-keep-alive"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableHTTPPipelining sets enable-http-pipelining.
+func (o Option) EnableHTTPPipelining(v bool) Option {
+	o["enable-http-pipelining"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableMmap sets enable-mmap.
+func (o Option) EnableMmap(v bool) Option {
+	o["enable-mmap"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnablePeerExchange sets enable-peer-exchange.
+func (o Option) EnablePeerExchange(v bool) Option {
+	o["enable-peer-exchange"] = strconv.FormatBool(v)
+	return o
+}
+
+// EnableRPC sets enable-rpc.
+func (o Option) EnableRPC(v bool) Option {
+	o["enable-rpc"] = strconv.FormatBool(v)
+	return o
+}
+
+// EventPoll sets event-poll.
+func (o Option) EventPoll(v string) Option {
+	o["event-poll"] = v
+	return o
+}
+
+// FileAllocation sets file-allocation.
+func (o Option) FileAllocation(v string) Option {
+	o["file-allocation"] = v
+	return o
+}
+
+// FollowMetalink sets follow-metalink.
+func (o Option) FollowMetalink(v string) Option {
+	o["follow-metalink"] = v
+	return o
+}
+
+// FollowTorrent sets follow-torrent.
+func (o Option) FollowTorrent(v string) Option {
+	o["follow-torrent"] = v
+	return o
+}
+
+// ForceSave sets force-save.
+func (o Option) ForceSave(v bool) Option {
+	o["force-save"] = strconv.FormatBool(v)
+	return o
+}
+
+// ForceSequential sets force-sequential.
+func (o Option) ForceSequential(v bool) Option {
+	o["force-sequential"] = strconv.FormatBool(v)
+	return o
+}
+
+// FTPPasswd sets ftp-passwd.
+func (o Option) FTPPasswd(v string) Option {
+	o["ftp-passwd"] = v
+	return o
+}
+
+// FTPPasv sets ftp-pasv.
+func (o Option) FTPPasv(v bool) Option {
+	o["ftp-pasv"] = strconv.FormatBool(v)
+	return o
+}
+
+// FTPProxy sets ftp-proxy.
+func (o Option) FTPProxy(v string) Option {
+	o["ftp-proxy"] = v
+	return o
+}
+
+// FTPProxyPasswd sets ftp-proxy-passwd.
+func (o Option) FTPProxyPasswd(v string) Option {
+	o["ftp-proxy-passwd"] = v
+	return o
+}
+
+// FTPProxyUser sets ftp-proxy-user.
+func (o Option) FTPProxyUser(v string) Option {
+	o["ftp-proxy-user"] = v
+	return o
+}
+
+// FTPReuseConnection sets ftp-reuse-connection.
+func (o Option) FTPReuseConnection(v bool) Option {
+	o["ftp-reuse-connection"] = strconv.FormatBool(v)
+	return o
+}
+
+// FTPType sets ftp-type.
+func (o Option) FTPType(v string) Option {
+	o["ftp-type"] = v
+	return o
+}
+
+// FTPUser sets ftp-user.
+func (o Option) FTPUser(v string) Option {
+	o["ftp-user"] = v
+	return o
+}
+
+// Gid sets gid.
+func (o Option) Gid(v string) Option {
+	o["gid"] = v
+	return o
+}
+
+// HashCheckOnly sets hash-check-only.
+func (o Option) HashCheckOnly(v bool) Option {
+	o["hash-check-only"] = strconv.FormatBool(v)
+	return o
+}
+
+// Header sets header.
+func (o Option) Header(v ...string) Option {
+	o["header"] = v
+	return o
+}
+
+// HTTPAcceptGzip sets http-accept-gzip.
+func (o Option) HTTPAcceptGzip(v bool) Option {
+	o["http-accept-gzip"] = strconv.FormatBool(v)
+	return o
+}
+
+// HTTPAuthChallenge sets http-auth-challenge.
+func (o Option) HTTPAuthChallenge(v bool) Option {
+	o["http-auth-challenge"] = strconv.FormatBool(v)
+	return o
+}
+
+// HTTPNoCache sets http-no-cache.
+func (o Option) HTTPNoCache(v bool) Option {
+	o["http-no-cache"] = strconv.FormatBool(v)
+	return o
+}
+
+// HTTPPasswd sets http-passwd.
+func (o Option) HTTPPasswd(v string) Option {
+	o["http-passwd"] = v
+	return o
+}
+
+// HTTPProxy sets http-proxy.
+func (o Option) HTTPProxy(v string) Option {
+	o["http-proxy"] = v
+	return o
+}
+
+// HTTPProxyPasswd sets http-proxy-passwd.
+func (o Option) HTTPProxyPasswd(v string) Option {
+	o["http-proxy-passwd"] = v
+	return o
+}
+
+// HTTPProxyUser sets http-proxy-user.
+func (o Option) HTTPProxyUser(v string) Option {
+	o["http-proxy-user"] = v
+	return o
+}
+
+// HTTPUser sets http-user.
+func (o Option) HTTPUser(v string) Option {
+	o["http-user"] = v
+	return o
+}
+
+// HTTPSProxy sets https-proxy.
+func (o Option) HTTPSProxy(v string) Option {
+	o["https-proxy"] = v
+	return o
+}
+
+// HTTPSProxyPasswd sets https-proxy-passwd.
+func (o Option) HTTPSProxyPasswd(v string) Option {
+	o["https-proxy-passwd"] = v
+	return o
+}
+
+// HTTPSProxyUser sets https-proxy-user.
+func (o Option) HTTPSProxyUser(v string) Option {
+	o["https-proxy-user"] = v
+	return o
+}
+
+// HumanReadable sets human-readable.
+func (o Option) HumanReadable(v bool) Option {
+	o["human-readable"] = strconv.FormatBool(v)
+	return o
+}
+
+// IndexOut sets index-out.
+func (o Option) IndexOut(v ...string) Option {
+	o["index-out"] = v
+	return o
+}
+
+// InputFile sets input-file.
+func (o Option) InputFile(v string) Option {
+	o["input-file"] = v
+	return o
+}
+
+// Interface sets interface.
+func (o Option) Interface(v string) Option {
+	o["interface"] = v
+	return o
+}
+
+// KeepUnfinishedDownloadResult sets keep-unfinished-download-result.
+func (o Option) KeepUnfinishedDownloadResult(v bool) Option {
+	o["keep-unfinished-download-result"] = strconv.FormatBool(v)
+	return o
+}
+
+// ListenPort sets listen-port.
+func (o Option) ListenPort(v string) Option {
+	o["listen-port"] = v
+	return o
+}
+
+// LoadCookies sets load-cookies.
+func (o Option) LoadCookies(v string) Option {
+	o["load-cookies"] = v
+	return o
+}
+
+// Log sets log.
+func (o Option) Log(v string) Option {
+	o["log"] = v
+	return o
+}
+
+// LogLevel sets log-level.
+func (o Option) LogLevel(v string) Option {
+	o["log-level"] = v
+	return o
+}
+
+// LowestSpeedLimit sets lowest-speed-limit.
+func (o Option) LowestSpeedLimit(v Size) Option {
+	o["lowest-speed-limit"] = v.String()
+	return o
+}
+
+// MaxConcurrentDownloads sets max-concurrent-downloads.
+func (o Option) MaxConcurrentDownloads(v int) Option {
+	o["max-concurrent-downloads"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxConnectionPerServer sets max-connection-per-server.
+func (o Option) MaxConnectionPerServer(v int) Option {
+	o["max-connection-per-server"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxDownloadLimit sets max-download-limit.
+func (o Option) MaxDownloadLimit(v Size) Option {
+	o["max-download-limit"] = v.String()
+	return o
+}
+
+// MaxDownloadResult sets max-download-result.
+func (o Option) MaxDownloadResult(v int) Option {
+	o["max-download-result"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxFileNotFound sets max-file-not-found.
+func (o Option) MaxFileNotFound(v int) Option {
+	o["max-file-not-found"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxMmapLimit sets max-mmap-limit.
+func (o Option) MaxMmapLimit(v Size) Option {
+	o["max-mmap-limit"] = v.String()
+	return o
+}
+
+// MaxOverallDownloadLimit sets max-overall-download-limit.
+func (o Option) MaxOverallDownloadLimit(v Size) Option {
+	o["max-overall-download-limit"] = v.String()
+	return o
+}
+
+// MaxOverallUploadLimit sets max-overall-upload-limit.
+func (o Option) MaxOverallUploadLimit(v Size) Option {
+	o["max-overall-upload-limit"] = v.String()
+	return o
+}
+
+// MaxResumeFailureTries sets max-resume-failure-tries.
+func (o Option) MaxResumeFailureTries(v int) Option {
+	o["max-resume-failure-tries"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxTries sets max-tries.
+func (o Option) MaxTries(v int) Option {
+	o["max-tries"] = strconv.Itoa(v)
+	return o
+}
+
+// MaxUploadLimit sets max-upload-limit.
+func (o Option) MaxUploadLimit(v Size) Option {
+	o["max-upload-limit"] = v.String()
+	return o
+}
+
+// MetalinkBaseURI sets metalink-base-uri.
+func (o Option) MetalinkBaseURI(v string) Option {
+	o["metalink-base-uri"] = v
+	return o
+}
+
+// MetalinkEnableUniqueProtocol sets metalink-enable-unique-protocol.
+func (o Option) MetalinkEnableUniqueProtocol(v bool) Option {
+	o["metalink-enable-unique-protocol"] = strconv.FormatBool(v)
+	return o
+}
+
+// MetalinkFile sets metalink-file.
+func (o Option) MetalinkFile(v string) Option {
+	o["metalink-file"] = v
+	return o
+}
+
+// MetalinkLanguage sets metalink-language.
+func (o Option) MetalinkLanguage(v string) Option {
+	o["metalink-language"] = v
+	return o
+}
+
+// MetalinkLocation sets metalink-location.
+func (o Option) MetalinkLocation(v string) Option {
+	o["metalink-location"] = v
+	return o
+}
+
+// MetalinkOs sets metalink-os.
+func (o Option) MetalinkOs(v string) Option {
+	o["metalink-os"] = v
+	return o
+}
+
+// MetalinkPreferredProtocol sets metalink-preferred-protocol.
+func (o Option) MetalinkPreferredProtocol(v string) Option {
+	o["metalink-preferred-protocol"] = v
+	return o
+}
+
+// MetalinkVersion sets metalink-version.
+func (o Option) MetalinkVersion(v string) Option {
+	o["metalink-version"] = v
+	return o
+}
+
+// MinSplitSize sets min-split-size.
+func (o Option) MinSplitSize(v Size) Option {
+	o["min-split-size"] = v.String()
+	return o
+}
+
+// MinTLSVersion sets min-tls-version.
+func (o Option) MinTLSVersion(v string) Option {
+	o["min-tls-version"] = v
+	return o
+}
+
+// MultipleInterface sets multiple-interface.
+func (o Option) MultipleInterface(v string) Option {
+	o["multiple-interface"] = v
+	return o
+}
+
+// NetrcPath sets netrc-path.
+func (o Option) NetrcPath(v string) Option {
+	o["netrc-path"] = v
+	return o
+}
+
+// NoConf sets no-conf.
+func (o Option) NoConf(v bool) Option {
+	o["no-conf"] = strconv.FormatBool(v)
+	return o
+}
+
+// NoFileAllocationLimit sets no-file-allocation-limit.
+func (o Option) NoFileAllocationLimit(v Size) Option {
+	o["no-file-allocation-limit"] = v.String()
+	return o
+}
+
+// NoNetrc sets no-netrc.
+func (o Option) NoNetrc(v bool) Option {
+	o["no-netrc"] = strconv.FormatBool(v)
+	return o
+}
+
+// NoProxy sets no-proxy.
+func (o Option) NoProxy(v string) Option {
+	o["no-proxy"] = v
+	return o
+}
+
+// OnBtDownloadComplete sets on-bt-download-complete.
+func (o Option) OnBtDownloadComplete(v string) Option {
+	o["on-bt-download-complete"] = v
+	return o
+}
+
+// OnDownloadComplete sets on-download-complete.
+func (o Option) OnDownloadComplete(v string) Option {
+	o["on-download-complete"] = v
+	return o
+}
+
+// OnDownloadError sets on-download-error.
+func (o Option) OnDownloadError(v string) Option {
+	o["on-download-error"] = v
+	return o
+}
+
+// OnDownloadPause sets on-download-pause.
+func (o Option) OnDownloadPause(v string) Option {
+	o["on-download-pause"] = v
+	return o
+}
+
+// OnDownloadStart sets on-download-start.
+func (o Option) OnDownloadStart(v string) Option {
+	o["on-download-start"] = v
+	return o
+}
+
+// OnDownloadStop sets on-download-stop.
+func (o Option) OnDownloadStop(v string) Option {
+	o["on-download-stop"] = v
+	return o
+}
+
+// OptimizeConcurrentDownloads sets optimize-concurrent-downloads.
+func (o Option) OptimizeConcurrentDownloads(v string) Option {
+	o["optimize-concurrent-downloads"] = v
+	return o
+}
+
+// Out sets out.
+func (o Option) Out(v string) Option {
+	o["out"] = v
+	return o
+}
+
+// ParameterizedURI sets parameterized-uri.
+func (o Option) ParameterizedURI(v bool) Option {
+	o["parameterized-uri"] = strconv.FormatBool(v)
+	return o
+}
+
+// Pause sets pause.
+func (o Option) Pause(v bool) Option {
+	o["pause"] = strconv.FormatBool(v)
+	return o
+}
+
+// PauseMetadata sets pause-metadata.
+func (o Option) PauseMetadata(v bool) Option {
+	o["pause-metadata"] = strconv.FormatBool(v)
+	return o
+}
+
+// PeerAgent sets peer-agent.
+func (o Option) PeerAgent(v string) Option {
+	o["peer-agent"] = v
+	return o
+}
+
+// PeerIDPrefix sets peer-id-prefix.
+func (o Option) PeerIDPrefix(v string) Option {
+	o["peer-id-prefix"] = v
+	return o
+}
+
+// PieceLength sets piece-length.
+func (o Option) PieceLength(v Size) Option {
+	o["piece-length"] = v.String()
+	return o
+}
+
+// PrivateKey sets private-key.
+func (o Option) PrivateKey(v string) Option {
+	o["private-key"] = v
+	return o
+}
+
+// ProxyMethod sets proxy-method.
+func (o Option) ProxyMethod(v string) Option {
+	o["proxy-method"] = v
+	return o
+}
+
+// Quiet sets quiet.
+func (o Option) Quiet(v bool) Option {
+	o["quiet"] = strconv.FormatBool(v)
+	return o
+}
+
+// RealtimeChunkChecksum sets realtime-chunk-checksum.
+func (o Option) RealtimeChunkChecksum(v bool) Option {
+	o["realtime-chunk-checksum"] = strconv.FormatBool(v)
+	return o
+}
+
+// Referer sets referer.
+func (o Option) Referer(v string) Option {
+	o["referer"] = v
+	return o
+}
+
+// RemoteTime sets remote-time.
+func (o Option) RemoteTime(v bool) Option {
+	o["remote-time"] = strconv.FormatBool(v)
+	return o
+}
+
+// RemoveControlFile sets remove-control-file.
+func (o Option) RemoveControlFile(v bool) Option {
+	o["remove-control-file"] = strconv.FormatBool(v)
+	return o
+}
+
+// RetryWait sets retry-wait.
+func (o Option) RetryWait(v int) Option {
+	o["retry-wait"] = strconv.Itoa(v)
+	return o
+}
+
+// ReuseURI sets reuse-uri.
+func (o Option) ReuseURI(v bool) Option {
+	o["reuse-uri"] = strconv.FormatBool(v)
+	return o
+}
+
+// RlimitNofile sets rlimit-nofile.
+func (o Option) RlimitNofile(v int) Option {
+	o["rlimit-nofile"] = strconv.Itoa(v)
+	return o
+}
+
+// RPCAllowOriginAll sets rpc-allow-origin-all.
+func (o Option) RPCAllowOriginAll(v bool) Option {
+	o["rpc-allow-origin-all"] = strconv.FormatBool(v)
+	return o
+}
+
+// RPCCertificate sets rpc-certificate.
+func (o Option) RPCCertificate(v string) Option {
+	o["rpc-certificate"] = v
+	return o
+}
+
+// RPCListenAll sets rpc-listen-all.
+func (o Option) RPCListenAll(v bool) Option {
+	o["rpc-listen-all"] = strconv.FormatBool(v)
+	return o
+}
+
+// RPCListenPort sets rpc-listen-port.
+func (o Option) RPCListenPort(v int) Option {
+	o["rpc-listen-port"] = strconv.Itoa(v)
+	return o
+}
+
+// RPCMaxRequestSize sets rpc-max-request-size.
+func (o Option) RPCMaxRequestSize(v Size) Option {
+	o["rpc-max-request-size"] = v.String()
+	return o
+}
+
+// RPCPasswd sets rpc-passwd.
+func (o Option) RPCPasswd(v string) Option {
+	o["rpc-passwd"] = v
+	return o
+}
+
+// RPCPrivateKey sets rpc-private-key.
+func (o Option) RPCPrivateKey(v string) Option {
+	o["rpc-private-key"] = v
+	return o
+}
+
+// RPCSaveUploadMetadata sets rpc-save-upload-metadata.
+func (o Option) RPCSaveUploadMetadata(v bool) Option {
+	o["rpc-save-upload-metadata"] = strconv.FormatBool(v)
+	return o
+}
+
+// RPCSecret sets rpc-secret.
+func (o Option) RPCSecret(v string) Option {
+	o["rpc-secret"] = v
+	return o
+}
+
+// RPCSecure sets rpc-secure.
+func (o Option) RPCSecure(v bool) Option {
+	o["rpc-secure"] = strconv.FormatBool(v)
+	return o
+}
+
+// RPCUser sets rpc-user.
+func (o Option) RPCUser(v string) Option {
+	o["rpc-user"] = v
+	return o
+}
+
+// SaveCookies sets save-cookies.
+func (o Option) SaveCookies(v string) Option {
+	o["save-cookies"] = v
+	return o
+}
+
+// SaveNotFound sets save-not-found.
+func (o Option) SaveNotFound(v bool) Option {
+	o["save-not-found"] = strconv.FormatBool(v)
+	return o
+}
+
+// SaveSession sets save-session.
+func (o Option) SaveSession(v string) Option {
+	o["save-session"] = v
+	return o
+}
+
+// SaveSessionInterval sets save-session-interval.
+func (o Option) SaveSessionInterval(v int) Option {
+	o["save-session-interval"] = strconv.Itoa(v)
+	return o
+}
+
+// SeedRatio sets seed-ratio.
+func (o Option) SeedRatio(v float64) Option {
+	o["seed-ratio"] = strconv.FormatFloat(v, 'f', -1, 64)
+	return o
+}
+
+// SeedTime sets seed-time.
+func (o Option) SeedTime(v float64) Option {
+	o["seed-time"] = strconv.FormatFloat(v, 'f', -1, 64)
+	return o
+}
+
+// SelectFile sets select-file.
+func (o Option) SelectFile(v string) Option {
+	o["select-file"] = v
+	return o
+}
+
+// ServerStatIf sets server-stat-if.
+func (o Option) ServerStatIf(v string) Option {
+	o["server-stat-if"] = v
+	return o
+}
+
+// ServerStatOf sets server-stat-of.
+func (o Option) ServerStatOf(v string) Option {
+	o["server-stat-of"] = v
+	return o
+}
+
+// ServerStatTimeout sets server-stat-timeout.
+func (o Option) ServerStatTimeout(v int) Option {
+	o["server-stat-timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// ShowConsoleReadout sets show-console-readout.
+func (o Option) ShowConsoleReadout(v bool) Option {
+	o["show-console-readout"] = strconv.FormatBool(v)
+	return o
+}
+
+// ShowFiles sets show-files.
+func (o Option) ShowFiles(v bool) Option {
+	o["show-files"] = strconv.FormatBool(v)
+	return o
+}
+
+// SocketRecvBufferSize sets socket-recv-buffer-size.
+func (o Option) SocketRecvBufferSize(v Size) Option {
+	o["socket-recv-buffer-size"] = v.String()
+	return o
+}
+
+// Split sets split.
+func (o Option) Split(v int) Option {
+	o["split"] = strconv.Itoa(v)
+	return o
+}
+
+// SSHHostKeyMD sets ssh-host-key-md.
+func (o Option) SSHHostKeyMD(v string) Option {
+	o["ssh-host-key-md"] = v
+	return o
+}
+
+// Stop sets stop.
+func (o Option) Stop(v int) Option {
+	o["stop"] = strconv.Itoa(v)
+	return o
+}
+
+// StopWithProcess sets stop-with-process.
+func (o Option) StopWithProcess(v int) Option {
+	o["stop-with-process"] = strconv.Itoa(v)
+	return o
+}
+
+// StreamPieceSelector sets stream-piece-selector.
+func (o Option) StreamPieceSelector(v string) Option {
+	o["stream-piece-selector"] = v
+	return o
+}
+
+// SummaryInterval sets summary-interval.
+func (o Option) SummaryInterval(v int) Option {
+	o["summary-interval"] = strconv.Itoa(v)
+	return o
+}
+
+// Timeout sets timeout.
+func (o Option) Timeout(v int) Option {
+	o["timeout"] = strconv.Itoa(v)
+	return o
+}
+
+// TorrentFile sets torrent-file.
+func (o Option) TorrentFile(v string) Option {
+	o["torrent-file"] = v
+	return o
+}
+
+// TruncateConsoleReadout sets truncate-console-readout.
+func (o Option) TruncateConsoleReadout(v bool) Option {
+	o["truncate-console-readout"] = strconv.FormatBool(v)
+	return o
+}
+
+// URISelector sets uri-selector.
+func (o Option) URISelector(v string) Option {
+	o["uri-selector"] = v
+	return o
+}
+
+// UseHead sets use-head.
+func (o Option) UseHead(v bool) Option {
+	o["use-head"] = strconv.FormatBool(v)
+	return o
+}
+
+// UserAgent sets user-agent.
+func (o Option) UserAgent(v string) Option {
+	o["user-agent"] = v
+	return o
+}
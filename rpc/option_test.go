@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestOptionValidate(t *testing.T) {
+	for _, tc := range []struct {
+		option Option
+		scope  OptionScope
+		err    error // nil if valid; ErrUnknownOption, ErrOptionScope, or errValue for a bad value
+	}{
+		{Option{}.Dir("/tmp").Split(4).MaxDownloadLimit(512*KiB).Header("A: 1", "B: 2"), ScopeInputFile, nil},
+		{Option{"max-downlaod-limit": "1M"}, ScopeInputFile, ErrUnknownOption},
+		{Option{"max-concurrent-downloads": "2"}, ScopeGlobal, nil},
+		{Option{"max-concurrent-downloads": "2"}, ScopeInputFile, ErrOptionScope},
+		{Option{"pause": "true"}, ScopeInputFile, nil},
+		{Option{"pause": "true"}, ScopeDownload, ErrOptionScope},
+		{Option{"out": "a.iso"}, ScopeGlobal, ErrOptionScope},
+		{Option{"max-upload-limit": "1m"}, ScopeDownload | ScopeActive, nil},
+		{Option{"split": "3"}, ScopeDownload | ScopeActive, ErrOptionScope},
+		{Option{"enable-rpc": "true"}, ScopeGlobal, ErrOptionScope},
+		{Option{"max-download-limit": "1G"}, ScopeInputFile, errValue},
+		{Option{"min-split-size": "512K"}, ScopeInputFile, errValue},
+		{Option{"max-connection-per-server": "17"}, ScopeInputFile, errValue},
+		{Option{"continue": "yes"}, ScopeInputFile, errValue},
+		{Option{"file-allocation": "falloc"}, ScopeInputFile, nil},
+		{Option{"file-allocation": "sparse"}, ScopeInputFile, errValue},
+		{Option{"seed-ratio": "0.5"}, ScopeInputFile, nil},
+		{Option{"seed-ratio": "-1"}, ScopeInputFile, errValue},
+		{Option{"split": 4}, ScopeInputFile, errValue},
+		{Option{"dir": []string{"/a", "/b"}}, ScopeInputFile, errValue},
+		{Option{"index-out": []interface{}{"1=a", "2=b"}}, ScopeInputFile, nil},
+	} {
+		err := tc.option.Validate(tc.scope)
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("%v.Validate(%b) = %v", tc.option, tc.scope, err)
+			}
+			continue
+		}
+		var oerr *OptionError
+		if !errors.As(err, &oerr) {
+			t.Errorf("%v.Validate(%b) = %v, want *OptionError", tc.option, tc.scope, err)
+			continue
+		}
+		if tc.err != errValue && !errors.Is(err, tc.err) {
+			t.Errorf("%v.Validate(%b) = %v, want %v", tc.option, tc.scope, err, tc.err)
+		}
+		if tc.err == errValue && (errors.Is(err, ErrUnknownOption) || errors.Is(err, ErrOptionScope)) {
+			t.Errorf("%v.Validate(%b) = %v, want a bad value", tc.option, tc.scope, err)
+		}
+	}
+}
+
+var errValue = errors.New("bad value")
+
+func TestOptionSetters(t *testing.T) {
+	o := Option{}.Continue(true).Split(8).SeedRatio(1.5).MinSplitSize(MiB).Header("A: 1").AllProxy("http://proxy")
+	want := Option{
+		"continue":       "true",
+		"split":          "8",
+		"seed-ratio":     "1.5",
+		"min-split-size": "1M",
+		"header":         []string{"A: 1"},
+		"all-proxy":      "http://proxy",
+	}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("Option = %v, want %v", o, want)
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		size Size
+	}{
+		{"0", 0},
+		{"1000", 1000},
+		{"1K", KiB},
+		{"3k", 3 * KiB},
+		{"1M", MiB},
+		{"20M", 20 * MiB},
+		{"1536K", 1536 * KiB},
+	} {
+		size, err := ParseSize(tc.s)
+		if err != nil || size != tc.size {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tc.s, size, err, tc.size)
+		}
+	}
+	for _, s := range []string{"", "K", "-1", "1G", "1.5M", "9223372036854775807M"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", s)
+		}
+	}
+	for size, want := range map[Size]string{0: "0", 1000: "1000", 1536 * KiB: "1536K", 2 * MiB: "2M"} {
+		if got := size.String(); got != want {
+			t.Errorf("Size(%d).String() = %q, want %q", int64(size), got, want)
+		}
+	}
+}
+
+func TestOptionCatalog(t *testing.T) {
+	specs := OptionCatalog()
+	if len(specs) != len(optionCatalog) {
+		t.Fatalf("OptionCatalog() has %d options, want %d", len(specs), len(optionCatalog))
+	}
+	for _, spec := range specs {
+		if spec.Default != "" && spec.Type != OptionString {
+			if err := spec.Validate(spec.Default); err != nil {
+				t.Errorf("default of %s: %v", spec.Name, err)
+			}
+		}
+	}
+	if spec, ok := LookupOption("max-download-limit"); !ok || spec.Type != OptionSize || spec.Scope&ScopeActive == 0 {
+		t.Errorf("LookupOption(max-download-limit) = %+v, %v", spec, ok)
+	}
+}
+
+func TestClientOptionValidation(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := New(context.Background(), srv.URL, "", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.AddURI([]string{targetURL}, Option{"max-downlaod-limit": "1M"}); !errors.Is(err, ErrUnknownOption) {
+		t.Errorf("AddURI() = %v, want %v", err, ErrUnknownOption)
+	}
+	gid, err := c.AddURI([]string{targetURL}, Option{}.Pause(true).MaxDownloadLimit(MiB), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ChangeOption(gid, Option{}.Pause(false)); !errors.Is(err, ErrOptionScope) {
+		t.Errorf("ChangeOption() = %v, want %v", err, ErrOptionScope)
+	}
+	if _, err := c.ChangeGlobalOption(Option{}.MaxConcurrentDownloads(0)); err == nil {
+		t.Error("ChangeGlobalOption(max-concurrent-downloads=0) succeeded")
+	}
+	if _, err := c.ChangeGlobalOption(Option{}.MaxConcurrentDownloads(2)); err != nil {
+		t.Error(err)
+	}
+	if o, err := c.GetOption(gid); err != nil {
+		t.Error(err)
+	} else if o["max-download-limit"] != "1M" {
+		t.Errorf("max-download-limit = %v, want 1M", o["max-download-limit"])
+	}
+
+	unchecked, err := New(context.Background(), srv.URL, "", time.Second, nil, WithoutOptionValidation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unchecked.Close()
+	if _, err := unchecked.ChangeGlobalOption(Option{"some-future-option": "1"}); err != nil {
+		t.Errorf("ChangeGlobalOption() without validation = %v", err)
+	}
+}
@@ -2,41 +2,164 @@ package rpc
 
 import "sync"
 
-type ResponseProcFn func(resp clientResponse) error
+// callResult is what a pending call receives: either a response or an error in lieu of it.
+type callResult struct {
+	resp clientResponse
+	err  error
+}
+
+type pendingCall struct {
+	ch   chan callResult // buffered, receives exactly one result
+	sent bool
+}
+
+// pendingCalls correlates requests sent over a websocket with their responses by request id.
+type pendingCalls struct {
+	mu      sync.Mutex
+	calls   map[uint64]*pendingCall
+	batches [][]uint64 // ids of the batch requests sent and not answered yet, in order of sending
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{calls: make(map[uint64]*pendingCall)}
+}
+
+// add registers a call of id and returns the channel its result will be delivered on.
+func (p *pendingCalls) add(id uint64) <-chan callResult {
+	ch := make(chan callResult, 1)
+	p.mu.Lock()
+	p.calls[id] = &pendingCall{ch: ch}
+	p.mu.Unlock()
+	return ch
+}
+
+// markSent records that the request of id was written to the connection;
+// it reports false if the call is no longer pending, e.g. it has timed out.
+func (p *pendingCalls) markSent(id uint64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	call, ok := p.calls[id]
+	if ok {
+		call.sent = true
+	}
+	return ok
+}
+
+// sentBatch records that the requests of ids were written to the connection as a batch, which is answered as a whole.
+func (p *pendingCalls) sentBatch(ids []uint64) {
+	p.mu.Lock()
+	p.batches = append(p.batches, ids)
+	p.mu.Unlock()
+}
+
+// remove forgets the call of id; a response arriving later is dropped.
+func (p *pendingCalls) remove(id uint64) {
+	p.mu.Lock()
+	delete(p.calls, id)
+	p.pruneBatches()
+	p.mu.Unlock()
+}
 
-type ResponseProcessor struct {
-	cbs map[uint64]ResponseProcFn
-	mu  *sync.RWMutex
+// pruneBatches forgets the batches none of whose calls is pending any more, e.g. as they have timed out.
+func (p *pendingCalls) pruneBatches() {
+	batches := p.batches[:0]
+	for _, ids := range p.batches {
+		for _, id := range ids {
+			if _, ok := p.calls[id]; ok {
+				batches = append(batches, ids)
+				break
+			}
+		}
+	}
+	p.batches = batches
 }
 
-func NewResponseProcessor() *ResponseProcessor {
-	return &ResponseProcessor{
-		make(map[uint64]ResponseProcFn),
-		&sync.RWMutex{},
+// resolve delivers resp to the call it answers. Called by recv routine.
+func (p *pendingCalls) resolve(resp clientResponse) {
+	if resp.Id == nil {
+		return
 	}
+	p.deliver(*resp.Id, callResult{resp: resp})
 }
 
-func (r *ResponseProcessor) Add(id uint64, fn ResponseProcFn) {
-	r.mu.Lock()
-	r.cbs[id] = fn
-	r.mu.Unlock()
+func (p *pendingCalls) deliver(id uint64, result callResult) {
+	p.mu.Lock()
+	call, ok := p.calls[id]
+	delete(p.calls, id)
+	p.mu.Unlock()
+	if ok {
+		call.ch <- result
+	}
 }
 
-func (r *ResponseProcessor) remove(id uint64) {
-	r.mu.Lock()
-	delete(r.cbs, id)
-	r.mu.Unlock()
+// resolveBatch delivers resps, the array answering a batch request, to the calls they answer,
+// then errNoBatchResponse to the calls of the batch left unanswered, e.g. as their responses lack an id.
+// The batch is that of the first call answered or, if no response has an id, the earliest one sent,
+// as aria2 answers the messages of a connection in order. Called by recv routine.
+func (p *pendingCalls) resolveBatch(resps []clientResponse) {
+	p.mu.Lock()
+	i := -1
+	if len(p.batches) > 0 {
+		i = 0
+	}
+	for _, resp := range resps {
+		if resp.Id != nil {
+			i = p.batchOf(*resp.Id)
+			break
+		}
+	}
+	var ids []uint64
+	if i >= 0 {
+		ids = p.batches[i]
+		p.batches = append(p.batches[:i], p.batches[i+1:]...)
+	}
+	p.mu.Unlock()
+	for _, resp := range resps {
+		p.resolve(resp)
+	}
+	for _, id := range ids {
+		p.deliver(id, callResult{err: errNoBatchResponse})
+	}
+}
+
+// failBatch delivers err to the calls of the earliest batch sent, answered by a single error object, e.g. as invalid;
+// it reports false if no batch is waiting for an answer. Called by recv routine.
+func (p *pendingCalls) failBatch(err error) bool {
+	p.mu.Lock()
+	if len(p.batches) == 0 {
+		p.mu.Unlock()
+		return false
+	}
+	ids := p.batches[0]
+	p.batches = p.batches[1:]
+	p.mu.Unlock()
+	for _, id := range ids {
+		p.deliver(id, callResult{err: err})
+	}
+	return true
+}
+
+func (p *pendingCalls) batchOf(id uint64) int {
+	for i, ids := range p.batches {
+		for _, bid := range ids {
+			if bid == id {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
-// Process called by recv routine
-func (r *ResponseProcessor) Process(resp clientResponse) error {
-	id := *resp.Id
-	r.mu.RLock()
-	fn, ok := r.cbs[id]
-	r.mu.RUnlock()
-	if ok && fn != nil {
-		defer r.remove(id)
-		return fn(resp)
+// fail delivers err to every pending call, or only to those already sent if sentOnly.
+func (p *pendingCalls) fail(err error, sentOnly bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, call := range p.calls {
+		if sentOnly && !call.sent {
+			continue
+		}
+		call.ch <- callResult{err: err}
+		delete(p.calls, id)
 	}
-	return nil
+	p.batches = nil // every batch is sent, and failed
 }
@@ -1,10 +1,19 @@
 package rpc
 
+import (
+	"context"
+	"io"
+)
+
 // Protocol is a set of rpc methods that aria2 daemon supports
 type Protocol interface {
 	AddURI(uris []string, options ...interface{}) (gid string, err error)
 	AddTorrent(filename string, options ...interface{}) (gid string, err error)
 	AddMetalink(filename string, options ...interface{}) (gid []string, err error)
+	AddTorrentData(data []byte, webSeeds []string, options ...interface{}) (gid string, err error)
+	AddTorrentReader(r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error)
+	AddMetalinkData(data []byte, options ...interface{}) (gid []string, err error)
+	AddMetalinkReader(r io.Reader, options ...interface{}) (gid []string, err error)
 	Remove(gid string) (g string, err error)
 	ForceRemove(gid string) (g string, err error)
 	Pause(gid string) (g string, err error)
@@ -38,3 +47,47 @@ type Protocol interface {
 	Multicall(methods []Method) (r []interface{}, err error)
 	ListMethods() (methods []string, err error)
 }
+
+// ContextProtocol is the set of aria2 rpc methods taking a context.Context;
+// cancellation and deadlines of ctx are honored for the whole round trip.
+type ContextProtocol interface {
+	AddURIContext(ctx context.Context, uris []string, options ...interface{}) (gid string, err error)
+	AddTorrentContext(ctx context.Context, filename string, options ...interface{}) (gid string, err error)
+	AddMetalinkContext(ctx context.Context, filename string, options ...interface{}) (gid []string, err error)
+	AddTorrentDataContext(ctx context.Context, data []byte, webSeeds []string, options ...interface{}) (gid string, err error)
+	AddTorrentReaderContext(ctx context.Context, r io.Reader, webSeeds []string, options ...interface{}) (gid string, err error)
+	AddMetalinkDataContext(ctx context.Context, data []byte, options ...interface{}) (gid []string, err error)
+	AddMetalinkReaderContext(ctx context.Context, r io.Reader, options ...interface{}) (gid []string, err error)
+	RemoveContext(ctx context.Context, gid string) (g string, err error)
+	ForceRemoveContext(ctx context.Context, gid string) (g string, err error)
+	PauseContext(ctx context.Context, gid string) (g string, err error)
+	PauseAllContext(ctx context.Context) (ok string, err error)
+	ForcePauseContext(ctx context.Context, gid string) (g string, err error)
+	ForcePauseAllContext(ctx context.Context) (ok string, err error)
+	UnpauseContext(ctx context.Context, gid string) (g string, err error)
+	UnpauseAllContext(ctx context.Context) (ok string, err error)
+	TellStatusContext(ctx context.Context, gid string, keys ...string) (info StatusInfo, err error)
+	GetURIsContext(ctx context.Context, gid string) (infos []URIInfo, err error)
+	GetFilesContext(ctx context.Context, gid string) (infos []FileInfo, err error)
+	GetPeersContext(ctx context.Context, gid string) (infos []PeerInfo, err error)
+	GetServersContext(ctx context.Context, gid string) (infos []ServerInfo, err error)
+	TellActiveContext(ctx context.Context, keys ...string) (infos []StatusInfo, err error)
+	TellWaitingContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error)
+	TellStoppedContext(ctx context.Context, offset, num int, keys ...string) (infos []StatusInfo, err error)
+	ChangePositionContext(ctx context.Context, gid string, pos int, how string) (p int, err error)
+	ChangeURIContext(ctx context.Context, gid string, fileindex int, delUris []string, addUris []string, position ...int) (p []int, err error)
+	GetOptionContext(ctx context.Context, gid string) (m Option, err error)
+	ChangeOptionContext(ctx context.Context, gid string, option Option) (ok string, err error)
+	GetGlobalOptionContext(ctx context.Context) (m Option, err error)
+	ChangeGlobalOptionContext(ctx context.Context, options Option) (ok string, err error)
+	GetGlobalStatContext(ctx context.Context) (info GlobalStatInfo, err error)
+	PurgeDownloadResultContext(ctx context.Context) (ok string, err error)
+	RemoveDownloadResultContext(ctx context.Context, gid string) (ok string, err error)
+	GetVersionContext(ctx context.Context) (info VersionInfo, err error)
+	GetSessionInfoContext(ctx context.Context) (info SessionInfo, err error)
+	ShutdownContext(ctx context.Context) (ok string, err error)
+	ForceShutdownContext(ctx context.Context) (ok string, err error)
+	SaveSessionContext(ctx context.Context) (ok string, err error)
+	MulticallContext(ctx context.Context, methods []Method) (r []interface{}, err error)
+	ListMethodsContext(ctx context.Context) (methods []string, err error)
+}
@@ -0,0 +1,277 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/zyxar/argo/internal/bencode"
+	"github.com/zyxar/argo/magnet"
+)
+
+// RetryPolicy retries the calls failing with a transient error, e.g. a network one, if they are safe to send again:
+// queries such as aria2.tellStatus and aria2.getGlobalStat, and idempotent controls such as aria2.pause
+// or aria2.changeOption; a system.multicall or a batch is retried if all of its calls are.
+// Adds are not retried, as aria2 would add the download twice if the failed attempt did reach it, unless DedupAdds is set.
+type RetryPolicy struct {
+	// Backoff is the delay between attempts; its MaxAttempts bounds the attempts of a call, the first one included,
+	// 0 meaning until the context of the call is done.
+	Backoff Backoff
+	// Retryable reports whether an attempt failing with err may be retried; IsTransient if nil.
+	Retryable func(err error) bool
+	// DedupAdds retries aria2.addUri and aria2.addTorrent too: the active, waiting and stopped downloads of the same
+	// URIs or info hash are looked up before the first attempt, then before every new one, whose GID is returned
+	// instead if a download not there before the first attempt is found.
+	// The add is not retried if the first lookup fails. A download added by a lost attempt and already purged
+	// from the stopped ones, e.g. past --max-download-result, cannot be found, and is added again.
+	// aria2.addMetalink is never retried.
+	DedupAdds bool
+}
+
+// DefaultRetryPolicy makes up to 4 attempts of a call within about a second.
+var DefaultRetryPolicy = RetryPolicy{
+	Backoff: Backoff{
+		Initial:     100 * time.Millisecond,
+		Max:         time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+		MaxAttempts: 4,
+	},
+}
+
+// WithRetry retries the calls of the client by p, as an Interceptor inner to those of WithInterceptors given before.
+func WithRetry(p RetryPolicy) ClientOption {
+	return WithInterceptors(p.Interceptor())
+}
+
+// retrySafe are the methods which may be sent again as they are: queries and idempotent controls.
+var retrySafe = map[string]bool{
+	aria2TellStatus:          true,
+	aria2GetURIs:             true,
+	aria2GetFiles:            true,
+	aria2GetPeers:            true,
+	aria2GetServers:          true,
+	aria2TellActive:          true,
+	aria2TellWaiting:         true,
+	aria2TellStopped:         true,
+	aria2GetOption:           true,
+	aria2GetGlobalOption:     true,
+	aria2GetGlobalStat:       true,
+	aria2GetVersion:          true,
+	aria2GetSessionInfo:      true,
+	aria2ListMethods:         true,
+	aria2Pause:               true,
+	aria2PauseAll:            true,
+	aria2ForcePause:          true,
+	aria2ForcePauseAll:       true,
+	aria2Unpause:             true,
+	aria2UnpauseAll:          true,
+	aria2ChangeOption:        true,
+	aria2ChangeGlobalOption:  true,
+	aria2SaveSession:         true,
+	aria2PurgeDownloadResult: true,
+}
+
+// IsTransient reports whether err is a failure to reach aria2 daemon, or to hear from it, which may not happen again:
+// a network error, a timeout of the call, or a lost websocket connection. Errors returned by aria2 are not transient.
+func IsTransient(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, ErrConnLost) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Interceptor returns the Interceptor retrying calls by p.
+func (p RetryPolicy) Interceptor() Interceptor {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	return func(ctx context.Context, method string, params, reply interface{}, next Invoker) error {
+		var dedup func(ctx context.Context) (gids []string, err error)
+		var known map[string]bool
+		switch {
+		case p.DedupAdds && (method == aria2AddURI || method == aria2AddTorrent):
+			dedup = newAddLookup(method, params, next)
+			if dedup == nil {
+				return next(ctx, method, params, reply)
+			}
+			gids, err := dedup(ctx)
+			if err != nil {
+				return next(ctx, method, params, reply)
+			}
+			known = make(map[string]bool, len(gids))
+			for _, gid := range gids {
+				known[gid] = true
+			}
+		case !idempotent(method, params):
+			return next(ctx, method, params, reply)
+		}
+		err := next(ctx, method, params, reply)
+		for attempt := 1; err != nil && retryable(err) && ctx.Err() == nil &&
+			(p.Backoff.MaxAttempts <= 0 || attempt < p.Backoff.MaxAttempts); attempt++ {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(p.Backoff.Delay(attempt - 1)):
+			}
+			if dedup != nil {
+				gids, lookupErr := dedup(ctx)
+				if lookupErr != nil {
+					err = lookupErr // look again before resubmitting
+					continue
+				}
+				for _, gid := range gids {
+					if !known[gid] {
+						if s, ok := reply.(*string); ok {
+							*s = gid
+						}
+						return nil
+					}
+				}
+			}
+			err = next(ctx, method, params, reply)
+		}
+		return err
+	}
+}
+
+// idempotent reports whether the call of method may be sent again as it is.
+func idempotent(method string, params interface{}) bool {
+	var calls []Method
+	switch method {
+	case batchMethod:
+		calls, _ = params.([]Method)
+	case aria2Multicall:
+		if p, ok := params.([]interface{}); ok && len(p) == 1 {
+			calls, _ = p[0].([]Method)
+		}
+	default:
+		return retrySafe[method]
+	}
+	if len(calls) == 0 {
+		return false
+	}
+	for _, call := range calls {
+		if !retrySafe[call.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// newAddLookup returns the function listing the GIDs of the active, waiting and stopped downloads
+// of what the call of method with params adds; nil if the download cannot be identified.
+func newAddLookup(method string, params interface{}, next Invoker) func(ctx context.Context) ([]string, error) {
+	p, ok := params.([]interface{})
+	if !ok {
+		return nil
+	}
+	var token []interface{}
+	if len(p) > 0 {
+		if s, ok := p[0].(string); ok && strings.HasPrefix(s, "token:") {
+			token, p = p[:1], p[1:]
+		}
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	var uris map[string]bool
+	var hash string
+	switch method {
+	case aria2AddURI:
+		list, ok := p[0].([]string)
+		if !ok || len(list) == 0 {
+			return nil
+		}
+		if hash = magnetInfoHash(list[0]); hash == "" {
+			uris = make(map[string]bool, len(list))
+			for _, uri := range list {
+				uris[uri] = true
+			}
+		}
+	case aria2AddTorrent:
+		data, ok := p[0].(base64Data)
+		if !ok {
+			return nil
+		}
+		if hash = torrentInfoHash(data); hash == "" {
+			return nil
+		}
+	}
+	keys := []string{"gid", "infoHash", "files"}
+	withToken := func(args ...interface{}) []interface{} {
+		return append(append([]interface{}{}, token...), args...)
+	}
+	return func(ctx context.Context) ([]string, error) {
+		var active []StatusInfo
+		if err := next(ctx, aria2TellActive, withToken(keys), &active); err != nil {
+			return nil, err
+		}
+		infos := active
+		for _, method := range []string{aria2TellWaiting, aria2TellStopped} {
+			for offset := 0; ; offset += addLookupPage {
+				var page []StatusInfo
+				if err := next(ctx, method, withToken(offset, addLookupPage, keys), &page); err != nil {
+					return nil, err
+				}
+				infos = append(infos, page...)
+				if len(page) < addLookupPage {
+					break
+				}
+			}
+		}
+		var gids []string
+	match:
+		for _, info := range infos {
+			if hash != "" {
+				if strings.EqualFold(info.InfoHash, hash) {
+					gids = append(gids, info.Gid)
+				}
+				continue
+			}
+			for _, f := range info.Files {
+				for _, u := range f.URIs {
+					if uris[u.URI] {
+						gids = append(gids, info.Gid)
+						continue match
+					}
+				}
+			}
+		}
+		return gids, nil
+	}
+}
+
+// addLookupPage is the number of waiting or stopped downloads asked for at a time by the lookups of DedupAdds.
+const addLookupPage = 1000
+
+// magnetInfoHash returns the hex-encoded BitTorrent v1 info hash of a magnet URI, or "" if uri is not one.
+func magnetInfoHash(uri string) string {
+	m, err := magnet.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return m.InfoHash
+}
+
+// torrentInfoHash returns the hex-encoded SHA-1 of the bencoded info dictionary of a .torrent file, or "" if data is not one.
+func torrentInfoHash(data []byte) string {
+	v, info, err := bencode.Decode(data)
+	if err != nil {
+		return ""
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if _, ok = root["info"].(map[string]interface{}); !ok {
+		return ""
+	}
+	sum := sha1.Sum(info)
+	return hex.EncodeToString(sum[:])
+}
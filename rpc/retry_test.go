@@ -0,0 +1,245 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// lossyTransport loses the responses to the first n requests of a method, the requests reaching aria2 daemon,
+// or the requests themselves if drop is set.
+type lossyTransport struct {
+	method string
+	n      int32
+	drop   bool
+	sent   int32
+}
+
+var errLost = errors.New("response lost")
+
+func (t *lossyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if t.drop && bytes.Contains(body, []byte(`"`+t.method+`"`)) && atomic.AddInt32(&t.n, -1) >= 0 {
+		atomic.AddInt32(&t.sent, 1)
+		return nil, errLost
+	}
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil || !bytes.Contains(body, []byte(`"`+t.method+`"`)) {
+		return resp, err
+	}
+	atomic.AddInt32(&t.sent, 1)
+	if atomic.AddInt32(&t.n, -1) >= 0 {
+		resp.Body.Close()
+		return nil, errLost
+	}
+	return resp, nil
+}
+
+var testRetryPolicy = RetryPolicy{Backoff: Backoff{Initial: time.Millisecond, Multiplier: 1, MaxAttempts: 3}}
+
+func lossyClient(t *testing.T, uri string, transport *lossyTransport, p RetryPolicy) Client {
+	c, err := NewWithOptions(context.Background(), uri, WithToken("secret"),
+		WithHTTPClient(&http.Client{Transport: transport}), WithRetry(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRetry(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+
+	transport := &lossyTransport{method: aria2GetVersion, n: 2}
+	c := lossyClient(t, srv.URL, transport, testRetryPolicy)
+	defer c.Close()
+	if _, err := c.GetVersion(); err != nil || transport.sent != 3 {
+		t.Errorf("GetVersion() = %v after %d attempts", err, transport.sent)
+	}
+	transport.n, transport.sent = 3, 0
+	if _, err := c.GetVersion(); !errors.Is(err, errLost) || transport.sent != 3 {
+		t.Errorf("GetVersion() = %v after %d attempts, want %v after 3", err, transport.sent, errLost)
+	}
+
+	transport.method, transport.n, transport.sent = aria2AddURI, 1, 0
+	if _, err := c.AddURI([]string{targetURL}); !errors.Is(err, errLost) || transport.sent != 1 {
+		t.Errorf("AddURI() = %v after %d attempts, want %v after 1", err, transport.sent, errLost)
+	}
+
+	transport.method, transport.n, transport.sent = aria2GetVersion, 1, 0
+	b := c.Batch()
+	b.GetVersion()
+	b.TellActive()
+	if err := b.DoArray(context.Background()); err != nil || transport.sent != 2 {
+		t.Errorf("DoArray() = %v after %d attempts", err, transport.sent)
+	}
+	transport.n, transport.sent = 1, 0
+	b.GetVersion()
+	b.Remove("2089b05ecca3d829")
+	if err := b.Do(context.Background()); !errors.Is(err, errLost) || transport.sent != 1 {
+		t.Errorf("Do() = %v after %d attempts, want %v after 1", err, transport.sent, errLost)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	transport.n, transport.sent = 1, 0
+	if _, err := c.GetVersionContext(ctx); err == nil || transport.sent > 1 {
+		t.Errorf("GetVersionContext(canceled) = %v after %d attempts", err, transport.sent)
+	}
+}
+
+func TestRetryDedupAdds(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	p := testRetryPolicy
+	p.DedupAdds = true
+	transport := &lossyTransport{method: aria2AddURI, n: 1}
+	c := lossyClient(t, srv.URL, transport, p)
+	defer c.Close()
+	gid, err := c.AddURI([]string{targetURL})
+	if err != nil || gid == "" || transport.sent != 1 {
+		t.Fatalf("AddURI() = %q, %v after %d attempts", gid, err, transport.sent)
+	}
+	infos, err := c.TellActive("gid")
+	if err != nil || len(infos) != 1 || infos[0].Gid != gid {
+		t.Errorf("TellActive() = %v, %v; want the single download %s", infos, err, gid)
+	}
+
+	magnet := "magnet:?xt=urn:btih:1208F41B4D4DAFD8D993660FF3ABE9E0DFDC7D77&dn=x"
+	transport.n, transport.sent = 1, 0
+	if gid, err = c.AddURI([]string{magnet}); err != nil || transport.sent != 1 {
+		t.Fatalf("AddURI(%q) = %q, %v after %d attempts", magnet, gid, err, transport.sent)
+	}
+	if info, err := c.TellStatus(gid, "infoHash"); err != nil || info.InfoHash != "1208f41b4d4dafd8d993660ff3abe9e0dfdc7d77" {
+		t.Errorf("TellStatus(%q) = %v, %v; want the download of %s", gid, info, err, magnet)
+	}
+
+	// lost before reaching aria2: not found in the queue, the add is resubmitted
+	dropping := &lossyTransport{method: aria2AddURI, n: 1, drop: true}
+	c2 := lossyClient(t, srv.URL, dropping, p)
+	defer c2.Close()
+	if got, err := c2.AddURI([]string{"http://example.com/other"}); err != nil || got == "" || got == gid || dropping.sent != 2 {
+		t.Errorf("AddURI() = %q, %v after %d attempts", got, err, dropping.sent)
+	}
+	if infos, err := c.TellActive("gid"); err != nil || len(infos) != 3 {
+		t.Errorf("TellActive() = %v, %v; want 3 downloads", infos, err)
+	}
+}
+
+func TestRetryDedupAddsStoppedAndPaged(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	p := testRetryPolicy
+	p.DedupAdds = true
+	transport := &lossyTransport{method: aria2AddURI, n: 1}
+	var armed int32 = 1
+	complete := func(ctx context.Context, method string, params, reply interface{}, next Invoker) error {
+		if method == aria2TellActive && atomic.LoadInt32(&transport.sent) > 0 &&
+			atomic.CompareAndSwapInt32(&armed, 1, 0) { // the lost add finishes before it is looked up
+			var active []StatusInfo
+			if err := next(ctx, method, params, &active); err != nil {
+				return err
+			}
+			for _, info := range active {
+				srv.Complete(info.Gid)
+			}
+		}
+		return next(ctx, method, params, reply)
+	}
+	c, err := NewWithOptions(context.Background(), srv.URL, WithToken("secret"),
+		WithHTTPClient(&http.Client{Transport: transport}), WithRetry(p), WithInterceptors(complete))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	gid, err := c.AddURI([]string{"http://example.com/stopped"})
+	if err != nil || transport.sent != 1 {
+		t.Fatalf("AddURI() = %q, %v after %d attempts", gid, err, transport.sent)
+	}
+	if status, _ := srv.Status(gid); status != "complete" {
+		t.Errorf("AddURI() = %s, %s; want the completed download", gid, status)
+	}
+
+	// the lost add is queued past the first page of waiting downloads
+	plain, err := NewWithOptions(context.Background(), srv.URL, WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	b := plain.Batch()
+	for i := 0; i < addLookupPage; i++ {
+		b.AddURI([]string{"http://example.com/" + strconv.Itoa(i)}, Option{}.Pause(true))
+	}
+	if err = b.Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	transport.n, transport.sent = 1, 0
+	if gid, err = c.AddURI([]string{"http://example.com/last"}, Option{}.Pause(true)); err != nil || transport.sent != 1 {
+		t.Fatalf("AddURI() = %q, %v after %d attempts", gid, err, transport.sent)
+	}
+	if waiting, err := plain.TellWaiting(addLookupPage, addLookupPage, "gid"); err != nil || len(waiting) != 1 || waiting[0].Gid != gid {
+		t.Errorf("TellWaiting() = %v, %v; want the single download %s", waiting, err, gid)
+	}
+}
+
+func TestRetryDedupAddsExisting(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	p := testRetryPolicy
+	p.DedupAdds = true
+	transport := &lossyTransport{method: aria2AddURI, n: 1}
+	c := lossyClient(t, srv.URL, transport, p)
+	defer c.Close()
+	uri := "http://example.com/again"
+	transport.n = 0
+	old, err := c.AddURI([]string{uri})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Complete(old)
+
+	transport.n, transport.sent = 1, 0
+	gid, err := c.AddURI([]string{uri})
+	if err != nil || gid == "" || gid == old || transport.sent != 1 {
+		t.Fatalf("AddURI() = %q, %v after %d attempts; want a download other than %s", gid, err, transport.sent, old)
+	}
+	if infos, err := c.TellActive("gid"); err != nil || len(infos) != 1 || infos[0].Gid != gid {
+		t.Errorf("TellActive() = %v, %v; want the single download %s", infos, err, gid)
+	}
+}
+
+func TestInfoHash(t *testing.T) {
+	info := "d6:lengthi3e4:name1:a12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	data := "d8:announce3:url4:info" + info + "e"
+	sum := sha1.Sum([]byte(info))
+	if got := torrentInfoHash([]byte(data)); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("torrentInfoHash() = %q, want %x", got, sum)
+	}
+	deep := "d4:info" + strings.Repeat("l", 1<<20) + strings.Repeat("e", 1<<20) + "e" // bounded, not recursed into
+	for _, data := range []string{"", "d4:info", "le", "d3:foo3:bare", "d4:info3:abc", deep} {
+		if got := torrentInfoHash([]byte(data)); got != "" {
+			t.Errorf("torrentInfoHash(%q) = %q", data, got)
+		}
+	}
+	for uri, want := range map[string]string{
+		"magnet:?xt=urn:btih:1208F41B4D4DAFD8D993660FF3ABE9E0DFDC7D77":       "1208f41b4d4dafd8d993660ff3abe9e0dfdc7d77",
+		"magnet:?dn=x&xt=urn:btih:CIEPIG2NJWX5RWMTMYH7HK7J4DP5Y7LX":          "1208f41b4d4dafd8d993660ff3abe9e0dfdc7d77",
+		"http://example.com/magnet?xt=urn:btih:CIEPIG2NJWX5RWMTMYH7HK7J4DP5": "",
+	} {
+		if got := magnetInfoHash(uri); got != want {
+			t.Errorf("magnetInfoHash(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
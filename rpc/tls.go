@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+
+	"github.com/gorilla/websocket"
+)
+
+// WithTLSConfig sets the base TLS configuration of https and wss connections, i.e. to aria2 daemon run with --rpc-secure;
+// the other TLS options apply on top of a clone of it.
+func WithTLSConfig(c *tls.Config) ClientOption {
+	return func(cfg *clientConfig) { cfg.tls = c }
+}
+
+// WithRootCAs sets the pool of CAs the certificate of aria2 daemon is verified against, e.g. a private or self-signed CA.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(cfg *clientConfig) { cfg.rootCAs = pool }
+}
+
+// WithClientCertificate presents cert to the server, e.g. to a TLS-terminating proxy requiring mutual TLS.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(cfg *clientConfig) { cfg.certs = append(cfg.certs, cert) }
+}
+
+// WithServerName overrides the name the certificate of aria2 daemon is verified for, which defaults to the host of the uri.
+func WithServerName(name string) ClientOption {
+	return func(cfg *clientConfig) { cfg.serverName = name }
+}
+
+// WithPinnedPublicKeys trusts only the certificates whose SubjectPublicKeyInfo has one of the SHA-256 sums,
+// see PublicKeySum. A pinned certificate is trusted on its own, even if it is self-signed,
+// so the usual verification against root CAs and server name is skipped.
+func WithPinnedPublicKeys(sums ...[sha256.Size]byte) ClientOption {
+	return func(cfg *clientConfig) { cfg.pins = append(cfg.pins, sums...) }
+}
+
+// PublicKeySum returns the SHA-256 sum of the SubjectPublicKeyInfo of cert, as pinned by WithPinnedPublicKeys.
+func PublicKeySum(cert *x509.Certificate) [sha256.Size]byte {
+	return sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+}
+
+var errPinMismatch = errors.New("certificate of aria2 daemon matches no pinned public key")
+
+// tlsConfig returns the TLS configuration of https and wss connections; nil if no TLS option is set.
+func (cfg *clientConfig) tlsConfig() *tls.Config {
+	if cfg.tls == nil && cfg.rootCAs == nil && cfg.certs == nil && cfg.serverName == "" && cfg.pins == nil {
+		return nil
+	}
+	c := &tls.Config{}
+	if cfg.tls != nil {
+		c = cfg.tls.Clone()
+	}
+	if cfg.rootCAs != nil {
+		c.RootCAs = cfg.rootCAs
+	}
+	if cfg.certs != nil {
+		c.Certificates = append(c.Certificates, cfg.certs...)
+	}
+	if cfg.serverName != "" {
+		c.ServerName = cfg.serverName
+	}
+	if pins := cfg.pins; pins != nil {
+		// VerifyConnection runs on resumed sessions as well, unlike VerifyPeerCertificate
+		c.InsecureSkipVerify = true
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errPinMismatch
+			}
+			sum := PublicKeySum(cs.PeerCertificates[0])
+			for _, pin := range pins {
+				if bytes.Equal(sum[:], pin[:]) {
+					return nil
+				}
+			}
+			return errPinMismatch
+		}
+	}
+	return c
+}
+
+// dialer returns the websocket dialer of the rpc connection of ws/wss clients, and the notification stream of http/https clients.
+func (cfg *clientConfig) dialer() *websocket.Dialer {
+	d := *websocket.DefaultDialer
+	d.TLSClientConfig = cfg.tlsConfig()
+	d.NetDialContext = cfg.dial
+	if cfg.proxy != nil {
+		d.Proxy = cfg.proxy
+	}
+	return &d
+}
@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// tlsServer serves srv over TLS; it requires a client certificate signed by clientCA, if any.
+func tlsServer(srv *ariatest.Server, clientCA *x509.Certificate) (ts *httptest.Server, https, wss string) {
+	ts = httptest.NewUnstartedServer(srv)
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA)
+		ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	}
+	ts.StartTLS()
+	return ts, ts.URL + "/jsonrpc", "wss" + strings.TrimPrefix(ts.URL, "https") + "/jsonrpc"
+}
+
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "argo"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, cert
+}
+
+func TestTLS(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	ts, https, wss := tlsServer(srv, nil)
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	_, other := selfSignedCertificate(t)
+
+	for _, tc := range []struct {
+		name    string
+		options []ClientOption
+		ok      bool
+	}{
+		{"default", nil, false},
+		{"root CAs", []ClientOption{WithRootCAs(pool)}, true},
+		{"server name", []ClientOption{WithRootCAs(pool), WithServerName("example.com")}, true},
+		{"wrong server name", []ClientOption{WithRootCAs(pool), WithServerName("example.net")}, false},
+		{"base config", []ClientOption{WithTLSConfig(&tls.Config{RootCAs: pool})}, true},
+		{"pinned", []ClientOption{WithPinnedPublicKeys(PublicKeySum(other), PublicKeySum(ts.Certificate()))}, true},
+		{"wrong pin", []ClientOption{WithRootCAs(pool), WithPinnedPublicKeys(PublicKeySum(other))}, false},
+	} {
+		c, err := New(context.Background(), https, "", time.Second, nil, tc.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.GetVersion(); (err == nil) != tc.ok {
+			t.Errorf("%s: https GetVersion() = %v", tc.name, err)
+		}
+		c.Close()
+		c, err = New(context.Background(), wss, "", time.Second, nil, tc.options...)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: wss New() = %v", tc.name, err)
+		}
+		if err == nil {
+			if _, err = c.GetVersion(); err != nil {
+				t.Errorf("%s: wss GetVersion() = %v", tc.name, err)
+			}
+			c.Close()
+		}
+	}
+}
+
+func TestTLSPinnedResumed(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	ts, https, _ := tlsServer(srv, nil)
+	defer ts.Close()
+	_, other := selfSignedCertificate(t)
+	cache := tls.NewLRUClientSessionCache(4)
+	for _, tc := range []struct {
+		name string
+		pin  [32]byte
+		ok   bool
+	}{
+		{"pinned", PublicKeySum(ts.Certificate()), true},
+		{"wrong pin on a resumed session", PublicKeySum(other), false},
+	} {
+		c, err := New(context.Background(), https, "", time.Second, nil,
+			WithTLSConfig(&tls.Config{ClientSessionCache: cache}), WithPinnedPublicKeys(tc.pin))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.GetVersion(); (err == nil) != tc.ok {
+			t.Errorf("%s: GetVersion() = %v", tc.name, err)
+		}
+		c.Close()
+	}
+}
+
+func TestTLSNotifications(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	ts, https, _ := tlsServer(srv, nil)
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	notifier := make(chanNotifier, 16)
+	states := make(chan ConnState, 16)
+	c, err := New(context.Background(), https, "", time.Second, notifier, WithRootCAs(pool), WithConnStateHandler(func(s ConnState) { states <- s }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	waitState(t, states, StateConnected)
+	deadline := time.After(time.Second)
+	for { // the server may register the connection after the handshake completes
+		srv.Notify("aria2.onDownloadStart", "0000000000000001")
+		select {
+		case got := <-notifier:
+			if got != "start:0000000000000001" {
+				t.Errorf("notification = %q", got)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no notification over wss")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	cert, ca := selfSignedCertificate(t)
+	ts, https, wss := tlsServer(srv, ca)
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	for _, uri := range []string{https, wss} {
+		c, err := New(context.Background(), uri, "", time.Second, nil, WithRootCAs(pool), WithClientCertificate(cert))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.GetVersion(); err != nil {
+			t.Errorf("%s: GetVersion() with client certificate = %v", uri, err)
+		}
+		c.Close()
+	}
+	c, err := New(context.Background(), https, "", time.Second, nil, WithRootCAs(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err = c.GetVersion(); err == nil {
+		t.Error("GetVersion() without client certificate succeeded")
+	}
+}
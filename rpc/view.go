@@ -0,0 +1,302 @@
+package rpc
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is the state of a download, as reported by aria2.tellStatus.
+type Status int
+
+const (
+	StatusUnknown  Status = iota
+	StatusActive          // currently downloading/seeding
+	StatusWaiting         // in the queue; download is not started
+	StatusPaused          // paused
+	StatusError           // stopped because of error
+	StatusComplete        // stopped and completed
+	StatusRemoved         // removed by user
+)
+
+var statusNames = [...]string{"unknown", "active", "waiting", "paused", "error", "complete", "removed"}
+
+// ParseStatus returns the Status named s, or StatusUnknown.
+func ParseStatus(s string) Status {
+	for i, name := range statusNames {
+		if name == s {
+			return Status(i)
+		}
+	}
+	return StatusUnknown
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return statusNames[StatusUnknown]
+	}
+	return statusNames[s]
+}
+
+// Stopped reports whether s is a final state: error, complete or removed.
+func (s Status) Stopped() bool {
+	return s == StatusError || s == StatusComplete || s == StatusRemoved
+}
+
+// DownloadStatus is the typed view of StatusInfo. Keys not included in the response are zero.
+type DownloadStatus struct {
+	Gid             string
+	Status          Status
+	TotalLength     int64 // bytes
+	CompletedLength int64 // bytes
+	UploadLength    int64 // bytes
+	BitField        string
+	DownloadSpeed   int64 // bytes/sec
+	UploadSpeed     int64 // bytes/sec
+	InfoHash        string
+	NumSeeders      int
+	Seeder          bool
+	PieceLength     int64 // bytes
+	NumPieces       int
+	Connections     int
+	ErrorCode       DownloadErrorCode
+	ErrorMessage    string
+	FollowedBy      []string
+	BelongsTo       string
+	Dir             string
+	Files           []File
+	BitTorrent      *BitTorrentInfo // nil unless a BitTorrent download
+}
+
+// BitTorrentInfo is the information retrieved from the .torrent file of a download.
+type BitTorrentInfo struct {
+	AnnounceList [][]string
+	Comment      string
+	CreationDate time.Time // zero if unknown
+	Mode         string    // single or multi
+	Name         string
+}
+
+// File is the typed view of FileInfo.
+type File struct {
+	Index           int // starting at 1
+	Path            string
+	Length          int64 // bytes
+	CompletedLength int64 // bytes, of completed pieces only
+	Selected        bool
+	URIs            []URIInfo
+}
+
+// Peer is the typed view of PeerInfo.
+type Peer struct {
+	PeerId        string
+	IP            string
+	Port          int
+	BitField      string
+	AmChoking     bool
+	PeerChoking   bool
+	DownloadSpeed int64 // bytes/sec
+	UploadSpeed   int64 // bytes/sec
+	Seeder        bool
+}
+
+// GlobalStat is the typed view of GlobalStatInfo.
+type GlobalStat struct {
+	DownloadSpeed   int64 // bytes/sec
+	UploadSpeed     int64 // bytes/sec
+	NumActive       int
+	NumWaiting      int
+	NumStopped      int
+	NumStoppedTotal int
+}
+
+// fieldParser parses string fields of responses, keeping the first error; empty strings parse as zero.
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) int64(name, v string) int64 {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %q is not an integer", name, v)
+	}
+	return n
+}
+
+func (p *fieldParser) int(name, v string) int {
+	return int(p.int64(name, v))
+}
+
+func (p *fieldParser) bool(name, v string) bool {
+	switch v {
+	case "", "false":
+		return false
+	case "true":
+		return true
+	}
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %q is not true or false", name, v)
+	}
+	return false
+}
+
+// Typed parses the string fields of s.
+func (s StatusInfo) Typed() (d DownloadStatus, err error) {
+	var p fieldParser
+	d = DownloadStatus{
+		Gid:             s.Gid,
+		Status:          ParseStatus(s.Status),
+		TotalLength:     p.int64("totalLength", s.TotalLength),
+		CompletedLength: p.int64("completedLength", s.CompletedLength),
+		UploadLength:    p.int64("uploadLength", s.UploadLength),
+		BitField:        s.BitField,
+		DownloadSpeed:   p.int64("downloadSpeed", s.DownloadSpeed),
+		UploadSpeed:     p.int64("uploadSpeed", s.UploadSpeed),
+		InfoHash:        s.InfoHash,
+		NumSeeders:      p.int("numSeeders", s.NumSeeders),
+		Seeder:          p.bool("seeder", s.Seeder),
+		PieceLength:     p.int64("pieceLength", s.PieceLength),
+		NumPieces:       p.int("numPieces", s.NumPieces),
+		Connections:     p.int("connections", s.Connections),
+		ErrorCode:       DownloadErrorCode(p.int("errorCode", s.ErrorCode)),
+		ErrorMessage:    s.ErrorMessage,
+		FollowedBy:      s.FollowedBy,
+		BelongsTo:       s.BelongsTo,
+		Dir:             s.Dir,
+	}
+	if s.Files != nil {
+		d.Files = make([]File, len(s.Files))
+		for i, f := range s.Files {
+			if d.Files[i], err = f.Typed(); err != nil {
+				return
+			}
+		}
+	}
+	if bt := s.BitTorrent; bt.Info.Name != "" || bt.Mode != "" || len(bt.AnnounceList) != 0 {
+		d.BitTorrent = &BitTorrentInfo{
+			AnnounceList: bt.AnnounceList,
+			Comment:      bt.Comment,
+			Mode:         bt.Mode,
+			Name:         bt.Info.Name,
+		}
+		if bt.CreationDate != 0 {
+			d.BitTorrent.CreationDate = time.Unix(bt.CreationDate, 0)
+		}
+	}
+	err = p.err
+	return
+}
+
+// Progress returns the completed ratio of the download, in [0, 1].
+func (d DownloadStatus) Progress() float64 {
+	if d.TotalLength <= 0 {
+		if d.Status == StatusComplete {
+			return 1
+		}
+		return 0
+	}
+	return float64(d.CompletedLength) / float64(d.TotalLength)
+}
+
+// Remaining returns the bytes left to download.
+func (d DownloadStatus) Remaining() int64 {
+	if d.TotalLength <= d.CompletedLength {
+		return 0
+	}
+	return d.TotalLength - d.CompletedLength
+}
+
+// ETA estimates the time left at the current download speed;
+// ok is false if the download does not progress, or its length is still unknown.
+func (d DownloadStatus) ETA() (eta time.Duration, ok bool) {
+	if d.DownloadSpeed <= 0 || d.TotalLength <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(d.Remaining()) / float64(d.DownloadSpeed) * float64(time.Second)), true
+}
+
+// Name returns a name to display the download with: the torrent name,
+// else the path of its first file relative to Dir, else the last element of its first URI, else its GID.
+func (d DownloadStatus) Name() string {
+	if d.BitTorrent != nil && d.BitTorrent.Name != "" {
+		return d.BitTorrent.Name
+	}
+	if len(d.Files) == 0 {
+		return d.Gid
+	}
+	f := d.Files[0]
+	if f.Path != "" {
+		if d.Dir != "" && strings.HasPrefix(f.Path, strings.TrimSuffix(d.Dir, "/")+"/") {
+			return f.Path[len(strings.TrimSuffix(d.Dir, "/"))+1:]
+		}
+		return path.Base(f.Path)
+	}
+	for _, u := range f.URIs {
+		uri := u.URI
+		if i := strings.IndexAny(uri, "?#"); i >= 0 {
+			uri = uri[:i]
+		}
+		if name := path.Base(uri); name != "" && name != "." && name != "/" && !strings.HasSuffix(name, ":") {
+			return name
+		}
+	}
+	return d.Gid
+}
+
+// Typed parses the string fields of f.
+func (f FileInfo) Typed() (File, error) {
+	var p fieldParser
+	file := File{
+		Index:           p.int("index", f.Index),
+		Path:            f.Path,
+		Length:          p.int64("length", f.Length),
+		CompletedLength: p.int64("completedLength", f.CompletedLength),
+		Selected:        p.bool("selected", f.Selected),
+		URIs:            f.URIs,
+	}
+	return file, p.err
+}
+
+// Progress returns the completed ratio of the file, in [0, 1].
+func (f File) Progress() float64 {
+	if f.Length <= 0 {
+		return 0
+	}
+	return float64(f.CompletedLength) / float64(f.Length)
+}
+
+// Typed parses the string fields of pi.
+func (pi PeerInfo) Typed() (Peer, error) {
+	var p fieldParser
+	peer := Peer{
+		PeerId:        pi.PeerId,
+		IP:            pi.IP,
+		Port:          p.int("port", pi.Port),
+		BitField:      pi.BitField,
+		AmChoking:     p.bool("amChoking", pi.AmChoking),
+		PeerChoking:   p.bool("peerChoking", pi.PeerChoking),
+		DownloadSpeed: p.int64("downloadSpeed", pi.DownloadSpeed),
+		UploadSpeed:   p.int64("uploadSpeed", pi.UploadSpeed),
+		Seeder:        p.bool("seeder", pi.Seeder),
+	}
+	return peer, p.err
+}
+
+// Typed parses the string fields of g.
+func (g GlobalStatInfo) Typed() (GlobalStat, error) {
+	var p fieldParser
+	stat := GlobalStat{
+		DownloadSpeed:   p.int64("downloadSpeed", g.DownloadSpeed),
+		UploadSpeed:     p.int64("uploadSpeed", g.UploadSpeed),
+		NumActive:       p.int("numActive", g.NumActive),
+		NumWaiting:      p.int("numWaiting", g.NumWaiting),
+		NumStopped:      p.int("numStopped", g.NumStopped),
+		NumStoppedTotal: p.int("numStoppedTotal", g.NumStoppedTotal),
+	}
+	return stat, p.err
+}
@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStatusInfoTyped(t *testing.T) {
+	var info StatusInfo
+	err := json.Unmarshal([]byte(`{
+		"gid": "2089b05ecca3d829", "status": "active",
+		"totalLength": "34896136", "completedLength": "8724034", "uploadLength": "0",
+		"downloadSpeed": "1000000", "uploadSpeed": "0", "seeder": "false", "numSeeders": "3",
+		"connections": "5", "errorCode": "0", "dir": "/downloads",
+		"files": [{"index": "1", "path": "/downloads/ubuntu/ubuntu.iso", "length": "34896138", "completedLength": "0", "selected": "true", "uris": []}],
+		"bittorrent": {"creationDate": 1600000000, "mode": "single", "info": {"name": "ubuntu"}}
+	}`), &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := info.Typed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != StatusActive || d.TotalLength != 34896136 || d.NumSeeders != 3 || d.Seeder || d.Connections != 5 {
+		t.Errorf("Typed() = %+v", d)
+	}
+	if len(d.Files) != 1 || d.Files[0].Index != 1 || !d.Files[0].Selected {
+		t.Errorf("Typed().Files = %+v", d.Files)
+	}
+	if d.BitTorrent == nil || !d.BitTorrent.CreationDate.Equal(time.Unix(1600000000, 0)) || d.BitTorrent.Mode != "single" {
+		t.Errorf("Typed().BitTorrent = %+v", d.BitTorrent)
+	}
+	if p := d.Progress(); p != 0.25 {
+		t.Errorf("Progress() = %v, want 0.25", p)
+	}
+	if eta, ok := d.ETA(); !ok || eta != 26172102*time.Microsecond {
+		t.Errorf("ETA() = %v, %v", eta, ok)
+	}
+	if name := d.Name(); name != "ubuntu" {
+		t.Errorf("Name() = %q, want ubuntu", name)
+	}
+
+	info.BitTorrent.Info.Name, info.BitTorrent.Mode, info.BitTorrent.CreationDate = "", "", 0
+	info.Seeder = "yes"
+	if _, err := info.Typed(); err == nil {
+		t.Error("Typed() of seeder=yes succeeded")
+	}
+}
+
+func TestDownloadStatusName(t *testing.T) {
+	for _, tc := range []struct {
+		d    DownloadStatus
+		want string
+	}{
+		{DownloadStatus{Gid: "1"}, "1"},
+		{DownloadStatus{Gid: "1", Dir: "/downloads/", Files: []File{{Path: "/downloads/a/b.iso"}}}, "a/b.iso"},
+		{DownloadStatus{Gid: "1", Dir: "/tmp", Files: []File{{Path: "/downloads/b.iso"}}}, "b.iso"},
+		{DownloadStatus{Gid: "1", Files: []File{{URIs: []URIInfo{{URI: "http://example.org/dist/c.tar.gz?x=1"}}}}}, "c.tar.gz"},
+		{DownloadStatus{Gid: "1", Files: []File{{URIs: []URIInfo{{URI: "http://example.org/"}}}}}, "example.org"},
+		{DownloadStatus{Gid: "1", Files: []File{{}}}, "1"},
+	} {
+		if got := tc.d.Name(); got != tc.want {
+			t.Errorf("%+v.Name() = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for _, s := range []Status{StatusActive, StatusWaiting, StatusPaused, StatusError, StatusComplete, StatusRemoved} {
+		if got := ParseStatus(s.String()); got != s {
+			t.Errorf("ParseStatus(%q) = %v", s.String(), got)
+		}
+	}
+	if ParseStatus("bogus") != StatusUnknown || !StatusRemoved.Stopped() || StatusPaused.Stopped() {
+		t.Error("unexpected Status behavior")
+	}
+}
+
+func TestTypedViews(t *testing.T) {
+	peer, err := PeerInfo{Port: "6881", AmChoking: "true", PeerChoking: "false", DownloadSpeed: "10", UploadSpeed: "20", Seeder: "true"}.Typed()
+	if err != nil || peer.Port != 6881 || !peer.AmChoking || peer.PeerChoking || peer.UploadSpeed != 20 || !peer.Seeder {
+		t.Errorf("PeerInfo.Typed() = %+v, %v", peer, err)
+	}
+	stat, err := GlobalStatInfo{DownloadSpeed: "1", NumActive: "2", NumWaiting: "3", NumStopped: "4", NumStoppedTotal: "5"}.Typed()
+	if err != nil || stat != (GlobalStat{DownloadSpeed: 1, NumActive: 2, NumWaiting: 3, NumStopped: 4, NumStoppedTotal: 5}) {
+		t.Errorf("GlobalStatInfo.Typed() = %+v, %v", stat, err)
+	}
+	if _, err := (GlobalStatInfo{NumActive: "x"}).Typed(); err == nil {
+		t.Error("GlobalStatInfo.Typed() of numActive=x succeeded")
+	}
+	if f, _ := (FileInfo{Length: "4", CompletedLength: "1"}).Typed(); f.Progress() != 0.25 {
+		t.Errorf("File.Progress() = %v", f.Progress())
+	}
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultWaitPollInterval is how often Wait polls the status of a download, in case a notification is missed.
+const DefaultWaitPollInterval = time.Second
+
+// DownloadError is returned by Wait when a download ends in error, or is removed.
+type DownloadError struct {
+	Gid     string
+	Status  string            // error or removed
+	Code    DownloadErrorCode // errorCode of the download
+	Message string            // errorMessage of the download
+}
+
+func (e *DownloadError) Error() string {
+	if e.Status == "removed" {
+		return "download " + e.Gid + " removed"
+	}
+	msg := "download " + e.Gid + " failed with error code " + strconv.Itoa(int(e.Code))
+	if e.Message != "" {
+		return msg + ": " + e.Message
+	}
+	return msg + ": " + e.Code.Description()
+}
+
+// Unwrap returns the DownloadErrorCode of a failed download, for errors.Is(err, ErrDiskFull) and the like.
+func (e *DownloadError) Unwrap() error {
+	if e.Code == 0 {
+		return nil
+	}
+	return e.Code
+}
+
+// Wait blocks until the download of gid is complete, failed or removed, and returns its final status.
+// Notifications wake it up early; the status is polled anyway, so that no missed notification makes it hang.
+// A download followed by others (see StatusInfo.FollowedBy), e.g. the metadata of a magnet link,
+// is waited for through them, and the status of the first of them is returned.
+// A download which ends in error or is removed yields a *DownloadError.
+func (c *client) Wait(ctx context.Context, gid string) (info StatusInfo, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := c.Subscribe(ctx, EventFilter{
+		Types:    []EventType{EventDownloadStop, EventDownloadComplete, EventDownloadError},
+		Gids:     []string{gid},
+		Overflow: DropOldest,
+	})
+	interval := c.pollInterval
+	if interval <= 0 {
+		interval = DefaultWaitPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if info, err = c.TellStatusContext(ctx, gid); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return
+		}
+		switch info.Status {
+		case "complete":
+			if len(info.FollowedBy) == 0 {
+				return
+			}
+			infos, err := c.WaitAll(ctx, info.FollowedBy...)
+			return infos[0], err
+		case "error", "removed":
+			code, _ := strconv.Atoi(info.ErrorCode)
+			err = &DownloadError{Gid: gid, Status: info.Status, Code: DownloadErrorCode(code), Message: info.ErrorMessage}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return info, ctx.Err()
+		case _, ok := <-events:
+			if !ok { // client closed; keep polling
+				events = nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitAll waits for every download of gids as Wait does, concurrently,
+// and returns their final status in the same order; err is the first error in that order.
+func (c *client) WaitAll(ctx context.Context, gids ...string) (infos []StatusInfo, err error) {
+	if len(gids) == 0 {
+		return nil, errInvalidParameter
+	}
+	infos = make([]StatusInfo, len(gids))
+	errs := make([]error, len(gids))
+	var wg sync.WaitGroup
+	for i, gid := range gids {
+		wg.Add(1)
+		go func(i int, gid string) {
+			defer wg.Done()
+			infos[i], errs[i] = c.Wait(ctx, gid)
+		}(i, gid)
+	}
+	wg.Wait()
+	for _, e := range errs {
+		if e != nil {
+			return infos, e
+		}
+	}
+	return
+}
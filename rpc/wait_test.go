@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// waitAsync runs c.Wait(ctx, gid) in background.
+func waitAsync(c Client, ctx context.Context, gid string) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		info, err := c.Wait(ctx, gid)
+		if err == nil && info.Status != "complete" {
+			err = errors.New("status " + info.Status)
+		}
+		ch <- err
+	}()
+	return ch
+}
+
+func recvErr(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Wait to return")
+	}
+	return nil
+}
+
+func TestWait(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		// with polling out of the picture, notifications alone must wake Wait up
+		c, err := New(context.Background(), uri, "", time.Second, nil, WithWaitPollInterval(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gid, err := c.AddURI([]string{targetURL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := waitAsync(c, context.Background(), gid)
+		time.Sleep(50 * time.Millisecond)
+		if err := srv.Complete(gid); err != nil {
+			t.Fatal(err)
+		}
+		if err := recvErr(t, done); err != nil {
+			t.Errorf("%s: Wait() = %v", uri, err)
+		}
+
+		gid, _ = c.AddURI([]string{targetURL})
+		done = waitAsync(c, context.Background(), gid)
+		time.Sleep(50 * time.Millisecond)
+		srv.Fail(gid, 3, "Resource not found")
+		var derr *DownloadError
+		if err := recvErr(t, done); !errors.As(err, &derr) || derr.Gid != gid || derr.Status != "error" || derr.Code != 3 || derr.Message != "Resource not found" {
+			t.Errorf("%s: Wait() of failed download = %v", uri, err)
+		}
+
+		gid, _ = c.AddURI([]string{targetURL})
+		done = waitAsync(c, context.Background(), gid)
+		time.Sleep(50 * time.Millisecond)
+		c.Remove(gid)
+		if err := recvErr(t, done); !errors.As(err, &derr) || derr.Status != "removed" {
+			t.Errorf("%s: Wait() of removed download = %v", uri, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		gid, _ = c.AddURI([]string{targetURL})
+		done = waitAsync(c, ctx, gid)
+		cancel()
+		if err := recvErr(t, done); err != context.Canceled {
+			t.Errorf("%s: Wait() canceled = %v", uri, err)
+		}
+		srv.Complete(gid)
+		c.Close()
+	}
+}
+
+func TestWaitFollowedBy(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := New(context.Background(), srv.WebsocketURL, "", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	meta, _ := c.AddURI([]string{"magnet:?xt=urn:btih:248d0a1cd08284299de78d5c1ed359bb46717d8c"})
+	real, _ := c.AddURI([]string{targetURL})
+	srv.Update(meta, func(d *ariatest.Download) { d.FollowedBy = []string{real} })
+	srv.Complete(meta)
+
+	type result struct {
+		info StatusInfo
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		info, err := c.Wait(context.Background(), meta)
+		ch <- result{info, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	srv.Complete(real)
+	select {
+	case r := <-ch:
+		if r.err != nil || r.info.Gid != real || r.info.Status != "complete" {
+			t.Errorf("Wait() = %s/%s, %v; want %s/complete", r.info.Gid, r.info.Status, r.err, real)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestWaitPolling(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	states := make(chan ConnState, 16)
+	c, err := New(context.Background(), srv.URL, "", time.Second, nil,
+		WithoutReconnect(), WithWaitPollInterval(20*time.Millisecond), WithConnStateHandler(func(s ConnState) { states <- s }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Subscribe(context.Background(), EventFilter{})
+	waitState(t, states, StateConnected)
+	srv.CloseClientConnections() // no more notifications
+	waitState(t, states, StateClosed)
+
+	a, _ := c.AddURI([]string{targetURL})
+	b, _ := c.AddURI([]string{targetURL})
+	ch := make(chan error, 1)
+	go func() {
+		infos, err := c.WaitAll(context.Background(), a, b)
+		if err == nil && (len(infos) != 2 || infos[0].Gid != a || infos[1].Gid != b) {
+			err = errors.New("unexpected statuses")
+		}
+		ch <- err
+	}()
+	srv.Complete(a)
+	srv.Fail(b, 1, "unknown error")
+	var derr *DownloadError
+	if err := recvErr(t, ch); !errors.As(err, &derr) || derr.Gid != b {
+		t.Errorf("WaitAll() = %v, want *DownloadError of %s", err, b)
+	}
+}
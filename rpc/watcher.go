@@ -0,0 +1,192 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// WatchEventType is the kind of change a Watcher found between two snapshots of the downloads.
+type WatchEventType int
+
+const (
+	WatchDiscovered    WatchEventType = iota + 1 // a download appeared
+	WatchDisappeared                             // a download is gone, e.g. its result was purged
+	WatchStatusChanged                           // status changed, e.g. from active to complete
+	WatchProgress                                // totalLength or completedLength changed
+	WatchSpeed                                   // downloadSpeed or uploadSpeed changed
+	WatchError                                   // a snapshot could not be taken; see WatchEvent.Err
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchDiscovered:
+		return "discovered"
+	case WatchDisappeared:
+		return "disappeared"
+	case WatchStatusChanged:
+		return "status"
+	case WatchProgress:
+		return "progress"
+	case WatchSpeed:
+		return "speed"
+	case WatchError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// WatchEvent is a change of a download between two snapshots.
+type WatchEvent struct {
+	Type WatchEventType
+	Gid  string
+	Old  StatusInfo // status in the previous snapshot; zero for WatchDiscovered
+	New  StatusInfo // status in the current snapshot; zero for WatchDisappeared
+	Time time.Time  // when the current snapshot was taken
+	Err  error      // why the snapshot failed, for WatchError
+}
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	Interval time.Duration // between snapshots; DefaultWatchInterval if not positive
+	Keys     []string      // keys of StatusInfo to pull; all keys if empty, otherwise the keys the events derive from are added
+	Waiting  int           // number of waiting downloads to pull; DefaultWatchLimit if not positive
+	Stopped  int           // number of stopped downloads to pull; DefaultWatchLimit if not positive
+	Buffer   int           // capacity of the event channel
+}
+
+const (
+	// DefaultWatchInterval is the interval between snapshots of a Watcher.
+	DefaultWatchInterval = time.Second
+	// DefaultWatchLimit is the number of waiting, and of stopped, downloads a Watcher pulls.
+	DefaultWatchLimit = 1000
+)
+
+// watchKeys are the keys the events of a Watcher derive from.
+var watchKeys = []string{"gid", "status", "totalLength", "completedLength", "downloadSpeed", "uploadSpeed"}
+
+// Watcher periodically pulls the active, waiting and stopped downloads, in a single batch,
+// and diffs consecutive snapshots into events; aria2 itself never notifies about progress.
+type Watcher struct {
+	c    Client
+	opts WatchOptions
+	keys []string
+}
+
+// NewWatcher returns a Watcher of the downloads of c.
+func NewWatcher(c Client, opts WatchOptions) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.Waiting <= 0 {
+		opts.Waiting = DefaultWatchLimit
+	}
+	if opts.Stopped <= 0 {
+		opts.Stopped = DefaultWatchLimit
+	}
+	w := &Watcher{c: c, opts: opts}
+	if len(opts.Keys) != 0 {
+		seen := make(map[string]bool)
+		for _, key := range append(append([]string{}, watchKeys...), opts.Keys...) {
+			if !seen[key] {
+				seen[key] = true
+				w.keys = append(w.keys, key)
+			}
+		}
+	}
+	return w
+}
+
+// Watch takes a snapshot right away, then every interval, until ctx is done, when the returned channel is closed.
+// Every download of the first snapshot is discovered. Events are delivered in order;
+// the next snapshot is not taken until the events of the previous one are received.
+func (w *Watcher) Watch(ctx context.Context) <-chan WatchEvent {
+	ch := make(chan WatchEvent, w.opts.Buffer)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+		var last []StatusInfo
+		for {
+			snapshot, err := w.snapshot(ctx)
+			now := time.Now()
+			var events []WatchEvent
+			if err != nil {
+				events = []WatchEvent{{Type: WatchError, Time: now, Err: err}}
+			} else {
+				events = diffSnapshots(last, snapshot, now)
+				last = snapshot
+			}
+			for _, e := range events {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (w *Watcher) snapshot(ctx context.Context) ([]StatusInfo, error) {
+	b := w.c.Batch()
+	active := b.TellActive(w.keys...)
+	waiting := b.TellWaiting(0, w.opts.Waiting, w.keys...)
+	stopped := b.TellStopped(0, w.opts.Stopped, w.keys...)
+	if err := b.Do(ctx); err != nil {
+		return nil, err
+	}
+	for _, err := range []error{active.Err, waiting.Err, stopped.Err} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	snapshot := make([]StatusInfo, 0, len(active.Result)+len(waiting.Result)+len(stopped.Result))
+	snapshot = append(snapshot, active.Result...)
+	snapshot = append(snapshot, waiting.Result...)
+	return append(snapshot, stopped.Result...), nil
+}
+
+// diffSnapshots returns the events turning last into current, in the order of current, then disappeared downloads by GID.
+func diffSnapshots(last, current []StatusInfo, now time.Time) (events []WatchEvent) {
+	old := make(map[string]StatusInfo, len(last))
+	for _, info := range last {
+		old[info.Gid] = info
+	}
+	for _, info := range current {
+		prev, ok := old[info.Gid]
+		if !ok {
+			events = append(events, WatchEvent{Type: WatchDiscovered, Gid: info.Gid, New: info, Time: now})
+			continue
+		}
+		delete(old, info.Gid)
+		e := WatchEvent{Gid: info.Gid, Old: prev, New: info, Time: now}
+		if prev.Status != info.Status {
+			e.Type = WatchStatusChanged
+			events = append(events, e)
+		}
+		if prev.TotalLength != info.TotalLength || prev.CompletedLength != info.CompletedLength {
+			e.Type = WatchProgress
+			events = append(events, e)
+		}
+		if prev.DownloadSpeed != info.DownloadSpeed || prev.UploadSpeed != info.UploadSpeed {
+			e.Type = WatchSpeed
+			events = append(events, e)
+		}
+	}
+	gone := make([]string, 0, len(old))
+	for gid := range old {
+		gone = append(gone, gid)
+	}
+	sort.Strings(gone)
+	for _, gid := range gone {
+		events = append(events, WatchEvent{Type: WatchDisappeared, Gid: gid, Old: old[gid], Time: now})
+	}
+	return
+}
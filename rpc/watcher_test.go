@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	last := []StatusInfo{
+		{Gid: "1", Status: "active", CompletedLength: "10", DownloadSpeed: "5"},
+		{Gid: "2", Status: "waiting"},
+		{Gid: "3", Status: "complete"},
+		{Gid: "4", Status: "error"},
+	}
+	current := []StatusInfo{
+		{Gid: "1", Status: "active", CompletedLength: "20", DownloadSpeed: "6"},
+		{Gid: "5", Status: "active"},
+		{Gid: "2", Status: "active"},
+	}
+	var got []string
+	for _, e := range diffSnapshots(last, current, time.Now()) {
+		got = append(got, e.Type.String()+":"+e.Gid)
+	}
+	want := []string{"progress:1", "speed:1", "discovered:5", "status:2", "disappeared:3", "disappeared:4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSnapshots() = %v, want %v", got, want)
+	}
+	if events := diffSnapshots(current, current, time.Now()); len(events) != 0 {
+		t.Errorf("diffSnapshots() of identical snapshots = %v", events)
+	}
+}
+
+func recvWatchEvent(t *testing.T, ch <-chan WatchEvent, typ WatchEventType) WatchEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-ch:
+			if e.Type == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s event", typ)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	c, err := New(context.Background(), srv.URL, "secret", time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	gid, err := c.AddURI([]string{targetURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := NewWatcher(c, WatchOptions{Interval: 10 * time.Millisecond, Keys: []string{"dir"}}).Watch(ctx)
+
+	e := recvWatchEvent(t, events, WatchDiscovered)
+	if e.Gid != gid || e.New.Status != "active" || e.New.Dir == "" || e.New.Files != nil {
+		t.Errorf("discovered = %+v", e)
+	}
+	srv.Update(gid, func(d *ariatest.Download) { d.TotalLength, d.CompletedLength, d.DownloadSpeed = 100, 40, 10 })
+	if e = recvWatchEvent(t, events, WatchProgress); e.Old.CompletedLength != "0" || e.New.CompletedLength != "40" {
+		t.Errorf("progress = %+v", e)
+	}
+	srv.Complete(gid)
+	if e = recvWatchEvent(t, events, WatchStatusChanged); e.Old.Status != "active" || e.New.Status != "complete" {
+		t.Errorf("status = %+v", e)
+	}
+	c.PurgeDownloadResult()
+	if e = recvWatchEvent(t, events, WatchDisappeared); e.Gid != gid {
+		t.Errorf("disappeared = %+v", e)
+	}
+	cancel()
+	for range events { // drained and closed
+	}
+}
@@ -0,0 +1,282 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithXMLRPC makes http/https clients speak XML-RPC, as aria2 daemon does at /rpc; it is implied by a uri of path /rpc.
+// Notifications are still received over the JSON-RPC websocket at /jsonrpc. WithHTTPGet has no effect on XML-RPC.
+func WithXMLRPC() ClientOption {
+	return func(cfg *clientConfig) { cfg.xml = true }
+}
+
+// base64Data is binary data, e.g. the contents of a ".torrent" file, sent as a base64-encoded string
+// in JSON-RPC, and as a base64 value in XML-RPC.
+type base64Data []byte
+
+func (b base64Data) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString(b))
+}
+
+// xmlCaller sends calls to aria2 daemon in XML-RPC over http.
+type xmlCaller struct {
+	*httpCaller
+}
+
+func (x xmlCaller) Call(ctx context.Context, method string, params, reply interface{}) (err error) {
+	defer logCall(x.cfg.logger, method, 0, time.Now(), &err)
+	payload, err := encodeXMLRequest(method, params)
+	if err != nil {
+		return
+	}
+	return x.roundTrip(ctx, payload, func(body io.Reader) error {
+		return decodeXMLResponse(body, reply)
+	})
+}
+
+// CallBatch sends calls with system.multicall, XML-RPC having no batch request of its own.
+func (x xmlCaller) CallBatch(ctx context.Context, calls []batchCall) (err error) {
+	methods := make([]Method, len(calls))
+	for i, call := range calls {
+		methods[i] = call.method
+	}
+	var results []json.RawMessage
+	if err = x.Call(ctx, aria2Multicall, []interface{}{methods}, &results); err != nil {
+		return
+	}
+	if len(results) != len(calls) {
+		return fmt.Errorf("system.multicall returned %d results for %d calls", len(results), len(calls))
+	}
+	for i, call := range calls {
+		*call.err = decodeMulticallResult(results[i], call.reply)
+	}
+	return
+}
+
+// encodeXMLRequest encodes a methodCall of method; params is a slice of parameters, or nil.
+func encodeXMLRequest(method string, params interface{}) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + "<methodCall><methodName>")
+	xml.EscapeText(&buf, []byte(method))
+	buf.WriteString("</methodName><params>")
+	if params != nil {
+		v := reflect.ValueOf(params)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, errInvalidParameter
+		}
+		for i := 0; i < v.Len(); i++ {
+			buf.WriteString("<param>")
+			if err := encodeXMLValue(&buf, v.Index(i)); err != nil {
+				return nil, err
+			}
+			buf.WriteString("</param>")
+		}
+	}
+	buf.WriteString("</params></methodCall>\n")
+	return &buf, nil
+}
+
+// encodeXMLValue encodes v as a value element: maps as structs, slices as arrays, []byte as base64;
+// other structs, but Method, are encoded as their JSON objects are.
+func encodeXMLValue(buf *bytes.Buffer, v reflect.Value) error {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return errors.New("xmlrpc: nil value")
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(Method); ok { // keep params of system.multicall, e.g. base64Data, as they are
+		v = reflect.ValueOf(map[string]interface{}{"methodName": m.Name, "params": m.Params})
+	} else if v.Kind() == reflect.Struct {
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		var m map[string]interface{}
+		if err = json.Unmarshal(b, &m); err != nil {
+			return err
+		}
+		v = reflect.ValueOf(m)
+	}
+	buf.WriteString("<value>")
+	switch v.Kind() {
+	case reflect.String:
+		buf.WriteString("<string>")
+		xml.EscapeText(buf, []byte(v.String()))
+		buf.WriteString("</string>")
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteString("<boolean>1</boolean>")
+		} else {
+			buf.WriteString("<boolean>0</boolean>")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < math.MinInt32 || v.Int() > math.MaxInt32 { // beyond i4, as aria2 takes large numbers: as strings
+			buf.WriteString("<string>" + strconv.FormatInt(v.Int(), 10) + "</string>")
+		} else {
+			buf.WriteString("<int>" + strconv.FormatInt(v.Int(), 10) + "</int>")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt32 {
+			buf.WriteString("<string>" + strconv.FormatUint(v.Uint(), 10) + "</string>")
+		} else {
+			buf.WriteString("<int>" + strconv.FormatUint(v.Uint(), 10) + "</int>")
+		}
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString("<double>" + strconv.FormatFloat(v.Float(), 'f', -1, 64) + "</double>")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			buf.WriteString("<base64>" + base64.StdEncoding.EncodeToString(v.Bytes()) + "</base64>")
+			break
+		}
+		buf.WriteString("<array><data>")
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXMLValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</data></array>")
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("xmlrpc: unsupported map key type %s", v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		buf.WriteString("<struct>")
+		for _, key := range keys {
+			buf.WriteString("<member><name>")
+			xml.EscapeText(buf, []byte(key))
+			buf.WriteString("</name>")
+			if err := encodeXMLValue(buf, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))); err != nil {
+				return err
+			}
+			buf.WriteString("</member>")
+		}
+		buf.WriteString("</struct>")
+	default:
+		return fmt.Errorf("xmlrpc: unsupported type %s", v.Type())
+	}
+	buf.WriteString("</value>")
+	return nil
+}
+
+// xmlValue is a value element of XML-RPC; one of its fields is set, or else Text is a string.
+type xmlValue struct {
+	String  *string    `xml:"string"`
+	Int     *string    `xml:"int"`
+	I4      *string    `xml:"i4"`
+	Boolean *string    `xml:"boolean"`
+	Double  *string    `xml:"double"`
+	Base64  *string    `xml:"base64"`
+	Struct  *xmlStruct `xml:"struct"`
+	Array   *xmlArray  `xml:"array"`
+	Text    string     `xml:",chardata"`
+}
+
+type xmlStruct struct {
+	Members []struct {
+		Name  string   `xml:"name"`
+		Value xmlValue `xml:"value"`
+	} `xml:"member"`
+}
+
+type xmlArray struct {
+	Values []xmlValue `xml:"data>value"`
+}
+
+type xmlResponse struct {
+	Params []xmlValue `xml:"params>param>value"`
+	Fault  *xmlValue  `xml:"fault>value"`
+}
+
+// decodeXMLResponse decodes a methodResponse into reply, by way of the JSON encoding of its value,
+// so that reply is decoded as it is from JSON-RPC. A fault is returned as an *Error.
+func decodeXMLResponse(r io.Reader, reply interface{}) error {
+	var resp xmlResponse
+	if err := xml.NewDecoder(r).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Fault != nil {
+		v, err := resp.Fault.value()
+		if err != nil {
+			return err
+		}
+		fault, _ := v.(map[string]interface{})
+		e := &Error{Message: fmt.Sprint(fault["faultString"])}
+		if code, ok := fault["faultCode"].(int64); ok {
+			e.Code = ErrorCode(code)
+		}
+		return e
+	}
+	if len(resp.Params) != 1 {
+		return ErrNullResult
+	}
+	v, err := resp.Params[0].value()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &reply)
+}
+
+// value returns v as a string, int64, bool, float64, map[string]interface{} or []interface{};
+// base64 is returned as the base64-encoded string, as JSON-RPC has it.
+func (v *xmlValue) value() (interface{}, error) {
+	switch {
+	case v.String != nil:
+		return *v.String, nil
+	case v.Int != nil, v.I4 != nil:
+		s := v.Int
+		if s == nil {
+			s = v.I4
+		}
+		return strconv.ParseInt(strings.TrimSpace(*s), 10, 64)
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean) == "1", nil
+	case v.Double != nil:
+		return strconv.ParseFloat(strings.TrimSpace(*v.Double), 64)
+	case v.Base64 != nil:
+		return strings.Join(strings.Fields(*v.Base64), ""), nil
+	case v.Struct != nil:
+		m := make(map[string]interface{}, len(v.Struct.Members))
+		for i := range v.Struct.Members {
+			member := &v.Struct.Members[i]
+			value, err := member.Value.value()
+			if err != nil {
+				return nil, err
+			}
+			m[member.Name] = value
+		}
+		return m, nil
+	case v.Array != nil:
+		a := make([]interface{}, len(v.Array.Values))
+		for i := range v.Array.Values {
+			value, err := v.Array.Values[i].value()
+			if err != nil {
+				return nil, err
+			}
+			a[i] = value
+		}
+		return a, nil
+	}
+	return v.Text, nil
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+func TestEncodeXMLRequest(t *testing.T) {
+	params := []interface{}{
+		"token:a<b", []string{"x"}, base64Data("d8:announce"), Option{"split": "4", "pause": "true"}, 3, int64(1 << 40), true,
+		Method{Name: aria2AddTorrent, Params: []interface{}{base64Data("t")}},
+	}
+	buf, err := encodeXMLRequest(aria2AddTorrent, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<methodCall><methodName>aria2.addTorrent</methodName><params>" +
+		"<param><value><string>token:a&lt;b</string></value></param>" +
+		"<param><value><array><data><value><string>x</string></value></data></array></value></param>" +
+		"<param><value><base64>ZDg6YW5ub3VuY2U=</base64></value></param>" +
+		"<param><value><struct><member><name>pause</name><value><string>true</string></value></member>" +
+		"<member><name>split</name><value><string>4</string></value></member></struct></value></param>" +
+		"<param><value><int>3</int></value></param>" +
+		"<param><value><string>1099511627776</string></value></param>" +
+		"<param><value><boolean>1</boolean></value></param>" +
+		"<param><value><struct><member><name>methodName</name><value><string>aria2.addTorrent</string></value></member>" +
+		"<member><name>params</name><value><array><data><value><base64>dA==</base64></value></data></array></value></member></struct></value></param>" +
+		"</params></methodCall>"
+	if got := strings.TrimSpace(strings.TrimPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n")); got != want {
+		t.Errorf("encodeXMLRequest() =\n%s\nwant\n%s", got, want)
+	}
+	if _, err = encodeXMLRequest(aria2AddURI, []interface{}{nil}); err == nil {
+		t.Error("encodeXMLRequest() of nil succeeded")
+	}
+}
+
+func TestDecodeXMLResponse(t *testing.T) {
+	var info StatusInfo
+	body := `<?xml version="1.0"?><methodResponse><params><param><value><struct>` +
+		`<member><name>gid</name><value><string>2089b05ecca3d829</string></value></member>` +
+		`<member><name>status</name><value>active</value></member>` +
+		`<member><name>files</name><value><array><data><value><struct><member><name>index</name><value><string>1</string></value></member></struct></value></data></array></value></member>` +
+		`</struct></value></param></params></methodResponse>`
+	if err := decodeXMLResponse(strings.NewReader(body), &info); err != nil || info.Gid != "2089b05ecca3d829" || info.Status != "active" || len(info.Files) != 1 || info.Files[0].Index != "1" {
+		t.Errorf("decodeXMLResponse() = %+v, %v", info, err)
+	}
+	var pos int
+	body = `<methodResponse><params><param><value><int> 2 </int></value></param></params></methodResponse>`
+	if err := decodeXMLResponse(strings.NewReader(body), &pos); err != nil || pos != 2 {
+		t.Errorf("decodeXMLResponse() = %d, %v", pos, err)
+	}
+	body = `<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>1</int></value></member>` +
+		`<member><name>faultString</name><value><string>GID 2089b05ecca3d829 is not found</string></value></member></struct></value></fault></methodResponse>`
+	if err := decodeXMLResponse(strings.NewReader(body), &pos); err == nil || err.(*Error).Code != 1 || err.Error() != "GID 2089b05ecca3d829 is not found" {
+		t.Errorf("decodeXMLResponse() of fault = %v", err)
+	}
+}
+
+func TestAddTorrentMetalink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "argo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	torrent, metalink := filepath.Join(dir, "a.torrent"), filepath.Join(dir, "a.metalink")
+	ioutil.WriteFile(torrent, []byte("d8:announce0:4:infod4:name1:aee"), 0644)
+	ioutil.WriteFile(metalink, []byte(`<?xml version="1.0" encoding="UTF-8"?><metalink xmlns="urn:ietf:params:xml:ns:metalink">`+
+		`<file name="a"><url>http://example.org/a</url></file><file name="b"><url>http://example.org/b</url></file></metalink>`), 0644)
+	srv := ariatest.NewServer("secret")
+	defer srv.Close()
+	for _, uri := range []string{srv.URL, srv.WebsocketURL, srv.XMLRPCURL} {
+		c, err := New(context.Background(), uri, "secret", time.Second, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gid, err := c.AddTorrent(torrent, Option{}.Pause(true))
+		if err != nil {
+			t.Fatalf("%s: AddTorrent() = %v", uri, err)
+		}
+		if info, err := c.TellStatus(gid, "infoHash"); err != nil || info.InfoHash == "" {
+			t.Errorf("%s: TellStatus() = %+v, %v", uri, info, err)
+		}
+		if gids, err := c.AddMetalink(metalink); err != nil || len(gids) != 2 {
+			t.Errorf("%s: AddMetalink() = %v, %v", uri, gids, err)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,392 @@
+// Package torrent decodes the metainfo of ".torrent" files, i.e. BitTorrent v1, v2 and hybrid torrents,
+// to show their files, sizes, trackers and info hashes before adding them to aria2,
+// and to select their files by path glob as aria2 indexes them.
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zyxar/argo/internal/bencode"
+	"github.com/zyxar/argo/magnet"
+	"github.com/zyxar/argo/rpc"
+)
+
+var (
+	// ErrBencode is wrapped by the errors of data which is not valid bencode.
+	ErrBencode = bencode.ErrInvalid
+	// ErrNoInfo is returned for metainfo without an info dictionary.
+	ErrNoInfo = errors.New("torrent: no info dictionary")
+	// ErrNoFileSelected is returned by SelectFile when no file matches the patterns.
+	ErrNoFileSelected = errors.New("torrent: no file selected")
+)
+
+// MetaInfo is the decoded metainfo of a ".torrent" file.
+type MetaInfo struct {
+	Announce     string     // URL of the tracker
+	AnnounceList [][]string // tiers of tracker URLs, BEP 12
+	URLList      []string   // web-seed URLs, BEP 19
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time // zero if absent
+	Info         Info
+
+	data []byte // the metainfo as decoded
+	info []byte // raw bencoded info dictionary
+}
+
+// Info is the info dictionary of MetaInfo.
+type Info struct {
+	Name        string
+	PieceLength int64
+	Pieces      []byte // concatenated SHA-1 hashes of pieces; v1 and hybrid only
+	Private     bool
+	MetaVersion int // 2 for v2 and hybrid torrents, else 1
+	Source      string
+
+	files []File
+}
+
+// File is a file of a torrent.
+type File struct {
+	Index   int      // 1-based index of the file in aria2, i.e. FileInfo.Index, as in select-file
+	Path    []string // path elements relative to the download directory, Info.Name first for a multi-file torrent
+	Length  int64    // bytes
+	Padding bool     // a padding file of BEP 47, which aria2 lists yet no one wants
+}
+
+// String returns the path of f joined by "/".
+func (f File) String() string { return strings.Join(f.Path, "/") }
+
+// Decode decodes the metainfo of a ".torrent" file.
+func Decode(data []byte) (*MetaInfo, error) {
+	v, rawInfo, err := bencode.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metainfo is not a dictionary", ErrBencode)
+	}
+	info, ok := root["info"].(map[string]interface{})
+	if !ok {
+		return nil, ErrNoInfo
+	}
+	m := &MetaInfo{
+		Announce:  str(root["announce"]),
+		Comment:   str(root["comment"]),
+		CreatedBy: str(root["created by"]),
+		data:      data,
+		info:      rawInfo,
+	}
+	if n, ok := root["creation date"].(int64); ok {
+		m.CreationDate = time.Unix(n, 0)
+	}
+	if tiers, ok := root["announce-list"].([]interface{}); ok {
+		for _, tier := range tiers {
+			if urls := strs(tier); len(urls) != 0 {
+				m.AnnounceList = append(m.AnnounceList, urls)
+			}
+		}
+	}
+	switch l := root["url-list"].(type) {
+	case string:
+		if l != "" {
+			m.URLList = []string{l}
+		}
+	case []interface{}:
+		m.URLList = strs(l)
+	}
+	if m.Info, err = decodeInfo(info); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Read decodes the metainfo read from r until EOF.
+func Read(r io.Reader) (*MetaInfo, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Load decodes the metainfo of the ".torrent" file of filename.
+func Load(filename string) (*MetaInfo, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+func decodeInfo(dict map[string]interface{}) (info Info, err error) {
+	info = Info{
+		Name:        str(dict["name.utf-8"]),
+		Source:      str(dict["source"]),
+		MetaVersion: 1,
+	}
+	if info.Name == "" {
+		info.Name = str(dict["name"])
+	}
+	if pieces := str(dict["pieces"]); pieces != "" {
+		info.Pieces = []byte(pieces)
+	}
+	info.PieceLength, _ = dict["piece length"].(int64)
+	if n, ok := dict["private"].(int64); ok && n == 1 {
+		info.Private = true
+	}
+	if n, ok := dict["meta version"].(int64); ok {
+		info.MetaVersion = int(n)
+	}
+	switch {
+	case dict["files"] != nil: // v1 multi-file, as aria2 reads hybrid torrents too
+		files, ok := dict["files"].([]interface{})
+		if !ok {
+			return info, errors.New("torrent: invalid files")
+		}
+		for _, f := range files {
+			f, ok := f.(map[string]interface{})
+			if !ok {
+				return info, errors.New("torrent: invalid file")
+			}
+			elems := strs(f["path.utf-8"])
+			if len(elems) == 0 {
+				elems = strs(f["path"])
+			}
+			if len(elems) == 0 {
+				return info, errors.New("torrent: file without path")
+			}
+			length, _ := f["length"].(int64)
+			info.files = append(info.files, File{
+				Index:   len(info.files) + 1,
+				Path:    append([]string{info.Name}, elems...),
+				Length:  length,
+				Padding: strings.Contains(str(f["attr"]), "p"),
+			})
+		}
+	case dict["length"] != nil: // v1 single-file
+		length, _ := dict["length"].(int64)
+		info.files = []File{{Index: 1, Path: []string{info.Name}, Length: length}}
+	case dict["file tree"] != nil: // v2 only
+		tree, ok := dict["file tree"].(map[string]interface{})
+		if !ok {
+			return info, errors.New("torrent: invalid file tree")
+		}
+		info.walkTree(tree, nil)
+		if len(info.files) == 1 && len(info.files[0].Path) == 1 {
+			info.files[0].Path = []string{info.Name}
+		} else {
+			for i := range info.files {
+				info.files[i].Path = append([]string{info.Name}, info.files[i].Path...)
+			}
+		}
+	default:
+		return info, errors.New("torrent: info without files")
+	}
+	return info, nil
+}
+
+// walkTree adds the files of a v2 file tree, in the order of their paths.
+func (info *Info) walkTree(tree map[string]interface{}, dir []string) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		node, ok := tree[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		elems := append(append([]string{}, dir...), name)
+		if leaf, ok := node[""].(map[string]interface{}); ok {
+			length, _ := leaf["length"].(int64)
+			info.files = append(info.files, File{Index: len(info.files) + 1, Path: elems, Length: length})
+			continue
+		}
+		info.walkTree(node, elems)
+	}
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func strs(v interface{}) []string {
+	l, _ := v.([]interface{})
+	var s []string
+	for _, e := range l {
+		if e, ok := e.(string); ok {
+			s = append(s, e)
+		}
+	}
+	return s
+}
+
+// Files returns the files of the torrent in the order aria2 indexes them.
+func (m *MetaInfo) Files() []File {
+	return append([]File(nil), m.Info.files...)
+}
+
+// TotalLength returns the sum of the lengths of the files of the torrent, padding files included.
+func (m *MetaInfo) TotalLength() (n int64) {
+	for _, f := range m.Info.files {
+		n += f.Length
+	}
+	return
+}
+
+// Trackers returns the tracker URLs of the torrent, announce-list first, without duplicates.
+func (m *MetaInfo) Trackers() []string {
+	seen := map[string]bool{"": true}
+	var trackers []string
+	for _, tier := range m.AnnounceList {
+		for _, t := range tier {
+			if !seen[t] {
+				seen[t] = true
+				trackers = append(trackers, t)
+			}
+		}
+	}
+	if !seen[m.Announce] {
+		trackers = append(trackers, m.Announce)
+	}
+	return trackers
+}
+
+// InfoHash returns the hex-encoded v1 info hash, the SHA-1 of the info dictionary, which aria2 reports as infoHash;
+// it is also the truncated v2 info hash for v2-only torrents.
+func (m *MetaInfo) InfoHash() string {
+	if m.Info.Pieces == nil && m.Info.MetaVersion == 2 {
+		return m.InfoHashV2()[:40]
+	}
+	sum := sha1.Sum(m.info)
+	return hex.EncodeToString(sum[:])
+}
+
+// InfoHashV2 returns the hex-encoded v2 info hash, the SHA-256 of the info dictionary, of v2 and hybrid torrents;
+// "" for v1 ones.
+func (m *MetaInfo) InfoHashV2() string {
+	if m.Info.MetaVersion != 2 {
+		return ""
+	}
+	sum := sha256.Sum256(m.info)
+	return hex.EncodeToString(sum[:])
+}
+
+// Magnet returns the magnet URI of the torrent, with its info hashes, name, total length, trackers and web seeds.
+func (m *MetaInfo) Magnet() *magnet.Magnet {
+	mg := &magnet.Magnet{
+		InfoHashV2:  m.InfoHashV2(),
+		DisplayName: m.Info.Name,
+		Length:      m.TotalLength(),
+		Trackers:    m.Trackers(),
+		WebSeeds:    m.URLList,
+	}
+	if m.Info.Pieces != nil || m.Info.MetaVersion != 2 {
+		mg.InfoHash = m.InfoHash()
+	}
+	return mg
+}
+
+// Bytes returns the metainfo as decoded, e.g. to upload it with AddTorrentData.
+func (m *MetaInfo) Bytes() []byte { return m.data }
+
+// SelectFile returns the select-file option of aria2 selecting the files matching any of patterns,
+// e.g. "1,3-5". A pattern is matched by path.Match against the path of a file joined by "/",
+// or, if it has no "/", against its base name; padding files are never selected.
+func (m *MetaInfo) SelectFile(patterns ...string) (string, error) {
+	var indexes []int
+	for _, f := range m.Info.files {
+		if f.Padding {
+			continue
+		}
+		name := f.String()
+		for _, pattern := range patterns {
+			target := name
+			if !strings.Contains(pattern, "/") {
+				target = path.Base(name)
+			}
+			ok, err := path.Match(pattern, target)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				indexes = append(indexes, f.Index)
+				break
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return "", ErrNoFileSelected
+	}
+	return formatRanges(indexes), nil
+}
+
+// formatRanges formats ascending indexes as ranges, e.g. "1,3-5".
+func formatRanges(indexes []int) string {
+	var b strings.Builder
+	for i := 0; i < len(indexes); {
+		j := i
+		for j+1 < len(indexes) && indexes[j+1] == indexes[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(indexes[i]))
+		if j > i {
+			b.WriteString("-" + strconv.Itoa(indexes[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// Add adds the torrent to aria2 with c, downloading only the files matching patterns, as selected by SelectFile,
+// or every file if no pattern is given. options are those of AddTorrentData, i.e. an rpc.Option or a map of options,
+// then a position; the select-file of the patterns takes the place of the one of the options, which are left as they are.
+func (m *MetaInfo) Add(ctx context.Context, c rpc.ContextProtocol, webSeeds []string, patterns []string, options ...interface{}) (gid string, err error) {
+	if len(patterns) != 0 {
+		selected, err := m.SelectFile(patterns...)
+		if err != nil {
+			return "", err
+		}
+		opt := rpc.Option{}
+		if len(options) != 0 {
+			switch o := options[0].(type) {
+			case rpc.Option:
+				for k, v := range o {
+					opt[k] = v
+				}
+				options = options[1:]
+			case map[string]interface{}:
+				for k, v := range o {
+					opt[k] = v
+				}
+				options = options[1:]
+			case map[string]string:
+				for k, v := range o {
+					opt[k] = v
+				}
+				options = options[1:]
+			}
+		}
+		options = append([]interface{}{opt.SelectFile(selected)}, options...)
+	}
+	return c.AddTorrentDataContext(ctx, m.data, webSeeds, options...)
+}
@@ -0,0 +1,222 @@
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zyxar/argo/magnet"
+	"github.com/zyxar/argo/rpc"
+	"github.com/zyxar/argo/rpc/ariatest"
+)
+
+// encode bencodes v, made of int, string, []interface{} and map[string]interface{}.
+func encode(v interface{}) string {
+	switch v := v.(type) {
+	case int:
+		return "i" + strconv.Itoa(v) + "e"
+	case string:
+		return strconv.Itoa(len(v)) + ":" + v
+	case []interface{}:
+		s := "l"
+		for _, e := range v {
+			s += encode(e)
+		}
+		return s + "e"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		s := "d"
+		for _, k := range keys {
+			s += encode(k) + encode(v[k])
+		}
+		return s + "e"
+	}
+	panic("unsupported type")
+}
+
+type dict = map[string]interface{}
+type list = []interface{}
+
+func file(length int, path ...interface{}) dict { return dict{"length": length, "path": list(path)} }
+
+var multiInfo = dict{
+	"name":         "album",
+	"piece length": 16384,
+	"pieces":       strings.Repeat("x", 20),
+	"files": list{
+		file(100, "cd1", "01.flac"),
+		dict{"length": 16284, "path": list{".pad", "16284"}, "attr": "p"},
+		file(200, "cd1", "cover.jpg"),
+		file(300, "cd2", "01.flac"),
+		file(400, "cd2", "02.flac"),
+		file(10, "readme.txt"),
+	},
+}
+
+func TestDecode(t *testing.T) {
+	data := encode(dict{
+		"announce":      "http://t1/announce",
+		"announce-list": list{list{"http://t2/announce", "http://t1/announce"}, list{"udp://t3:80"}},
+		"url-list":      "http://seed/",
+		"comment":       "test",
+		"creation date": 1600000000,
+		"info":          multiInfo,
+	})
+	m, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(encode(multiInfo)))
+	if m.InfoHash() != hex.EncodeToString(sum[:]) || m.InfoHashV2() != "" {
+		t.Errorf("InfoHash() = %s, %q; want %x", m.InfoHash(), m.InfoHashV2(), sum)
+	}
+	if want := []string{"http://t2/announce", "http://t1/announce", "udp://t3:80"}; !reflect.DeepEqual(m.Trackers(), want) {
+		t.Errorf("Trackers() = %q, want %q", m.Trackers(), want)
+	}
+	if m.Comment != "test" || !m.CreationDate.Equal(time.Unix(1600000000, 0)) || !reflect.DeepEqual(m.URLList, []string{"http://seed/"}) {
+		t.Errorf("Decode() = %+v", m)
+	}
+	files := m.Files()
+	if len(files) != 6 || m.TotalLength() != 17294 {
+		t.Fatalf("Files() = %+v, TotalLength() = %d", files, m.TotalLength())
+	}
+	if f := files[3]; f.Index != 4 || f.String() != "album/cd2/01.flac" || f.Length != 300 || !files[1].Padding {
+		t.Errorf("Files()[3] = %+v", f)
+	}
+	for _, c := range []struct {
+		patterns []string
+		want     string
+	}{
+		{[]string{"*.flac"}, "1,4-5"},
+		{[]string{"album/cd2/*"}, "4-5"},
+		{[]string{"cover.jpg", "readme.txt"}, "3,6"},
+		{[]string{"*"}, "1,3-6"},
+	} {
+		if got, err := m.SelectFile(c.patterns...); err != nil || got != c.want {
+			t.Errorf("SelectFile(%q) = %q, %v; want %q", c.patterns, got, err, c.want)
+		}
+	}
+	mg, err := magnet.Parse(m.Magnet().String())
+	if err != nil || mg.InfoHash != m.InfoHash() || mg.DisplayName != "album" || mg.Length != 17294 ||
+		len(mg.Trackers) != 3 || !reflect.DeepEqual(mg.WebSeeds, m.URLList) {
+		t.Errorf("Magnet() = %+v, %v", mg, err)
+	}
+	if _, err = m.SelectFile("*.mp3"); err != ErrNoFileSelected {
+		t.Errorf("SelectFile(*.mp3) = %v, want %v", err, ErrNoFileSelected)
+	}
+	if _, err = m.SelectFile("["); err == nil {
+		t.Error("SelectFile([) succeeded")
+	}
+}
+
+func TestDecodeSingleFile(t *testing.T) {
+	m, err := Decode([]byte(encode(dict{"info": dict{"name": "a.iso", "name.utf-8": "ä.iso", "length": 42, "piece length": 16384, "pieces": "", "private": 1}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := m.Files(); len(files) != 1 || files[0].String() != "ä.iso" || files[0].Index != 1 || files[0].Length != 42 || !m.Info.Private {
+		t.Errorf("Files() = %+v, %+v", files, m.Info)
+	}
+}
+
+func TestDecodeV2(t *testing.T) {
+	leaf := func(n int) dict { return dict{"": dict{"length": n, "pieces root": strings.Repeat("r", 32)}} }
+	tree := dict{"b": dict{"c.txt": leaf(3)}, "a.txt": leaf(1)}
+	v2 := dict{"name": "dir", "meta version": 2, "piece length": 16384, "file tree": tree}
+	m, err := Decode([]byte(encode(dict{"info": v2})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(encode(v2)))
+	if m.InfoHashV2() != hex.EncodeToString(sum[:]) || m.InfoHash() != hex.EncodeToString(sum[:20]) {
+		t.Errorf("InfoHash() = %s, %s; want %x", m.InfoHash(), m.InfoHashV2(), sum)
+	}
+	if mg := m.Magnet(); mg.InfoHash != "" || mg.InfoHashV2 != m.InfoHashV2() || mg.Validate() != nil {
+		t.Errorf("Magnet() = %+v, want only the v2 info hash", mg)
+	}
+	var paths []string
+	for _, f := range m.Files() {
+		paths = append(paths, strconv.Itoa(f.Index)+" "+f.String())
+	}
+	if want := []string{"1 dir/a.txt", "2 dir/b/c.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Files() = %q, want %q", paths, want)
+	}
+
+	hybrid := dict{"name": "dir", "meta version": 2, "piece length": 16384, "file tree": tree, "pieces": strings.Repeat("x", 20),
+		"files": list{file(1, "a.txt"), dict{"length": 16383, "path": list{".pad", "16383"}, "attr": "p"}, file(3, "b", "c.txt")}}
+	if m, err = Decode([]byte(encode(dict{"info": hybrid}))); err != nil {
+		t.Fatal(err)
+	}
+	sha := sha1.Sum([]byte(encode(hybrid)))
+	if m.InfoHash() != hex.EncodeToString(sha[:]) || m.InfoHashV2() == "" {
+		t.Errorf("InfoHash() = %s, %s", m.InfoHash(), m.InfoHashV2())
+	}
+	if s, err := m.SelectFile("c.txt"); err != nil || s != "3" {
+		t.Errorf("SelectFile(c.txt) = %q, %v; want the index of aria2, after the padding file", s, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, data := range []string{"", "i01e", "i-0e", "ie", "d", "l", "4:ab", "d4:infoi1e", "de", "le", "d4:infode",
+		"d4:infod4:name1:aee", "d4:infod5:filesi1eee", "d4:infod4:name1:a6:lengthi1eeeX", strings.Repeat("l", 100) + strings.Repeat("e", 100)} {
+		if _, err := Decode([]byte(data)); err == nil {
+			t.Errorf("Decode(%q) succeeded", data)
+		}
+	}
+	if _, err := Decode([]byte("d4:infoi1ee")); !errors.Is(err, ErrNoInfo) {
+		t.Errorf("Decode() = %v, want %v", err, ErrNoInfo)
+	}
+	if _, err := Decode([]byte("i1e")); !errors.Is(err, ErrBencode) {
+		t.Errorf("Decode() = %v, want %v", err, ErrBencode)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	srv := ariatest.NewServer("")
+	defer srv.Close()
+	c, err := rpc.NewWithOptions(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m, err := Decode([]byte(encode(dict{"info": multiInfo})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := rpc.Option{}.Pause(true)
+	gid, err := m.Add(context.Background(), c, nil, []string{"*.flac"}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, err := c.GetOption(gid); err != nil || o["select-file"] != "1,4-5" || o["pause"] != "true" {
+		t.Errorf("GetOption() = %v, %v", o, err)
+	}
+	if _, ok := opt["select-file"]; ok {
+		t.Error("Add() changed the options given")
+	}
+	strOpt := map[string]string{"pause": "true", "select-file": "2"}
+	if gid, err = m.Add(context.Background(), c, nil, []string{"*.flac"}, strOpt); err != nil {
+		t.Fatal(err)
+	}
+	if o, err := c.GetOption(gid); err != nil || o["select-file"] != "1,4-5" || o["pause"] != "true" {
+		t.Errorf("GetOption() = %v, %v", o, err)
+	}
+	if strOpt["select-file"] != "2" {
+		t.Error("Add() changed the options given")
+	}
+	if _, err = m.Add(context.Background(), c, nil, []string{"*.mp3"}); err != ErrNoFileSelected {
+		t.Errorf("Add(*.mp3) = %v, want %v", err, ErrNoFileSelected)
+	}
+}